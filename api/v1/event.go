@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"time"
+)
+
+// Event reports a operation which changed a Todo, as streamed by the change feed
+type Event struct {
+	// Seq is the position of the event in the feed, monotonically increasing
+	Seq uint64 `json:"seq"`
+	// Time is when the operation was performed
+	Time time.Time `json:"time"`
+	// Operation is what was performed
+	Operation Operation `json:"operation"`
+	// Item is the todo as changed by the operation
+	Item Item `json:"item"`
+	// MergedFrom lists the todos merged into Item, which are gone
+	MergedFrom []ID `json:"mergedFrom,omitempty"`
+}
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"time"
+)
+
+// ActorHeader is the HTTP header which identifies who is performing a operation.
+// The actor is recorded in the history of the todos changed by the operation.
+const ActorHeader = "X-Actor"
+
+// Operation represent a state-changing operation performed on a Todo
+type Operation string
+
+const (
+	OpCreate   Operation = "create"
+	OpDescribe Operation = "describe"
+	OpAssign   Operation = "assign"
+	OpUnassign Operation = "unassign"
+	OpReassign Operation = "reassign"
+	OpReopen   Operation = "reopen"
+	OpLabel    Operation = "label"
+	OpUnlabel  Operation = "unlabel"
+	OpComplete Operation = "complete"
+	OpDelete   Operation = "delete"
+	OpMerge    Operation = "merge"
+	OpImport   Operation = "import"
+)
+
+// Change reports the value of a Todo field before and after a operation.
+// Empty values mean the field was not set.
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// HistoryEntry records a operation which changed a Todo
+type HistoryEntry struct {
+	// Time is when the operation was performed
+	Time time.Time `json:"time"`
+	// Actor identifies who performed the operation, if known
+	Actor string `json:"actor,omitempty"`
+	// Operation is what was performed
+	Operation Operation `json:"operation"`
+	// Changes lists the fields changed by the operation
+	Changes []Change `json:"changes,omitempty"`
+	// MergedFrom links the histories of the todos merged into this one
+	MergedFrom []ID `json:"mergedFrom,omitempty"`
+	// MergedInto links the history of the todo this one was merged into
+	MergedInto ID `json:"mergedInto,omitempty"`
+}
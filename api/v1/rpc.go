@@ -0,0 +1,94 @@
+package v1
+
+import "encoding/json"
+
+// RPCVersion is the only supported JSON-RPC protocol version
+const RPCVersion = "2.0"
+
+// JSON-RPC 2.0 standard error codes
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	// RPCServerError reports a failure processing a valid request, e.g. a unknown todo.
+	// The error data is a Error object whose code is the equivalent HTTP status code.
+	RPCServerError = -32000
+)
+
+// RPCRequest is a JSON-RPC 2.0 request. A request without ID is a notification,
+// and gets no response.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// ID is a string, a number or null. Kept raw to be echoed back verbatim.
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification returns true if the request expects no response
+func (req RPCRequest) IsNotification() bool {
+	return req.ID == nil
+}
+
+// RPCResponse is a JSON-RPC 2.0 response. Exactly one of Result and Error is set.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  *Result         `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError describes a JSON-RPC 2.0 processing error
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *Error `json:"data,omitempty"`
+}
+
+// RPCTodoParams are the parameters of the methods processing a single todo.
+type RPCTodoParams struct {
+	// ID identifies the todo to process. Ignored on creation.
+	ID ID `json:"id,omitempty"`
+	// Todo holds the todo fields to set, on creation and on update.
+	Todo *Todo `json:"todo,omitempty"`
+	// Revision, if given, must match the current revision of the todo to process.
+	Revision *uint64 `json:"revision,omitempty"`
+}
+
+// RPCMergeParams are the parameters of the todo.merge method
+type RPCMergeParams struct {
+	ID1 ID `json:"id1"`
+	ID2 ID `json:"id2"`
+}
+
+// RPCSearchParams are the parameters of the todo.search method
+type RPCSearchParams struct {
+	// Text is the words to look for in the title and description of the todos
+	Text string `json:"text"`
+	// Statuses, if given, restricts the search to the todos having any of the given statuses
+	Statuses []Status `json:"statuses,omitempty"`
+	// Assignee, if given, restricts the search to the todos of the given assignee
+	Assignee string `json:"assignee,omitempty"`
+	// Limit, if given, caps the number of todos returned
+	Limit int `json:"limit,omitempty"`
+}
+
+// RPCListParams are the parameters of the methods listing todos
+type RPCListParams struct {
+	// Assignee, if given, restricts the listing to the todos of the given assignee
+	Assignee string `json:"assignee,omitempty"`
+	// Sort, if given, is the sorting criteria of the todos: "priority", "due", "updated", "title"
+	// or "status", optionally prefixed by "-" to reverse it. By default the todos are sorted by ID.
+	Sort string `json:"sort,omitempty"`
+	// Limit, if given, caps the number of todos returned
+	Limit int `json:"limit,omitempty"`
+	// Cursor, if given, is the NextCursor returned by the previous page
+	Cursor string `json:"cursor,omitempty"`
+	// Labels, if given, restricts the listing to the todos having all the given labels
+	Labels []string `json:"labels,omitempty"`
+	// Query, if given, restricts the listing to the todos matching the filter expression,
+	// see the filter package for the syntax
+	Query string `json:"query,omitempty"`
+}
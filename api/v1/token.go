@@ -0,0 +1,18 @@
+package v1
+
+import (
+	"time"
+)
+
+// Token is a API token. Its holder authenticates sending the secret
+// in the Authorization header, as "Bearer <secret>".
+type Token struct {
+	// ID identifies the token. Set by the server.
+	ID ID `json:"id,omitempty"`
+	// Name describes the holder of the token, and is recorded as the actor of its operations
+	Name string `json:"name"`
+	// Secret is generated by the server, and only returned when the token is created
+	Secret string `json:"secret,omitempty"`
+	// Created is when the token was created. Set by the server.
+	Created time.Time `json:"created,omitempty"`
+}
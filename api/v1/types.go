@@ -22,6 +22,20 @@ const (
 	Deleted Status = "deleted"
 )
 
+// Priority represent the urgency of a Todo
+type Priority string
+
+const (
+	// Low means a Todo is nice to have
+	Low Priority = "low"
+	// Normal is the default Priority of a Todo
+	Normal Priority = "normal"
+	// High means a Todo should be processed before the others
+	High Priority = "high"
+	// Urgent means a Todo should be processed as soon as possible
+	Urgent Priority = "urgent"
+)
+
 // ID is an opaque value which uniquely identifies a Todo. Can only be compared for equality
 type ID string
 
@@ -31,12 +45,22 @@ type Todo struct {
 	Title string `json:"title"`
 	// Assignee is the identifier of the agent working on the Todo
 	Assignee string `json:"assignee,omitempty"`
+	// PreviousAssignees lists the agents which worked on the Todo before the current one, oldest first
+	PreviousAssignees []string `json:"previousAssignees,omitempty"`
 	// Description is a longer description of the todo
 	Description string `json:"description,omitempty"`
 	// Status is the current processing status of the todo
 	Status Status `json:"status"`
+	// Priority is the urgency of the todo
+	Priority Priority `json:"priority,omitempty"`
+	// DueTime is the optional deadline of the todo
+	DueTime *time.Time `json:"due,omitempty"`
+	// Labels is the set of tags attached to the todo
+	Labels []string `json:"labels,omitempty"`
 	// LastUpdateTime records the last time a todo was modified in any way in the system
 	LastUpdateTime time.Time `json:"updated"`
+	// Revision is increased every time the todo is updated. Can be used to detect concurrent updates.
+	Revision uint64 `json:"revision,omitempty"`
 }
 
 // ToJSON returns a bytestream JSON encoding of the Todo; if succesfull, err is nil;
@@ -82,6 +106,24 @@ type Error struct {
 	Code int `json:"code"`
 	// Optional human friendly description of the error
 	Text string `json:"text,omitempty"`
+	// Location, if given, points to the part of a request parameter causing the error
+	Location *ErrorLocation `json:"location,omitempty"`
+}
+
+// ErrorLocation points to the offending part of a request parameter, like a filter expression
+type ErrorLocation struct {
+	// Parameter is the name of the request parameter
+	Parameter string `json:"parameter"`
+	// Offset is the position of the offending text in the parameter value, in characters from 0
+	Offset int `json:"offset"`
+	// Length is the length of the offending text, in characters
+	Length int `json:"length"`
+}
+
+// LabelCount reports how many todos have a label
+type LabelCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
 }
 
 // Result represent the status of a succesfull processing.
@@ -89,10 +131,36 @@ type Result struct {
 	// Items includes the updated objects as returned by the operation.
 	// Can be empty in succesfull operations (e.g. a query produced no values)
 	Items []Item `json:"items,omitempty"`
+	// NextCursor, if not empty, is the cursor to request the next page of Items
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Labels includes the labels in use, when requested by the operation
+	Labels []LabelCount `json:"labels,omitempty"`
+	// History includes the audit history of a todo, oldest entry first, when requested by the operation
+	History []HistoryEntry `json:"history,omitempty"`
+	// Webhooks includes the webhook subscriptions processed by the operation
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// DeadLetters includes the abandoned webhook deliveries, when requested by the operation
+	DeadLetters []DeadLetter `json:"deadLetters,omitempty"`
+	// Tokens includes the API tokens processed by the operation
+	Tokens []Token `json:"tokens,omitempty"`
+	// Import summarizes the import of a backup, when requested by the operation
+	Import *ImportReport `json:"import,omitempty"`
 	// Optional human friendly description of the operation
 	Text string `json:"text,omitempty"`
 }
 
+// ImportReport summarizes the import of a backup
+type ImportReport struct {
+	// Total is the number of todos in the backup
+	Total int `json:"total"`
+	// Created is the number of todos imported with a new ID
+	Created int `json:"created"`
+	// Overwritten is the number of existing todos replaced
+	Overwritten int `json:"overwritten"`
+	// Skipped is the number of existing todos kept
+	Skipped int `json:"skipped"`
+}
+
 // ResponseStatus represent the overlal status (e.g. success/error) of a operation
 type ResponseStatus string
 
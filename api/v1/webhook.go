@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"time"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the webhook payloads,
+	// as "sha256=" followed by the hex encoded signature of the request body
+	SignatureHeader = "X-Todo-Signature"
+	// EventHeader carries the operation which triggered a webhook delivery
+	EventHeader = "X-Todo-Event"
+	// DeliveryHeader carries the sequence number of the event delivered by a webhook
+	DeliveryHeader = "X-Todo-Delivery"
+)
+
+// Webhook is a subscription to the changes of the todos, delivered to a URL
+type Webhook struct {
+	// ID identifies the subscription. Set by the server.
+	ID ID `json:"id,omitempty"`
+	// URL is where the events are POSTed
+	URL string `json:"url"`
+	// Secret is the key used to sign the payloads. If not given, the server generates one.
+	// It is only returned when the subscription is created.
+	Secret string `json:"secret,omitempty"`
+	// Events restricts the deliveries to the given operations. Empty means all the operations.
+	Events []Operation `json:"events,omitempty"`
+	// Assignee restricts the deliveries to the todos with the given assignee
+	Assignee string `json:"assignee,omitempty"`
+}
+
+// WebhookPayload is the body of a webhook delivery
+type WebhookPayload struct {
+	// Webhook is the ID of the subscription which triggered the delivery
+	Webhook ID `json:"webhook"`
+	// Event is what happened
+	Event Event `json:"event"`
+}
+
+// DeadLetter reports a webhook delivery abandoned after exhausting all the attempts
+type DeadLetter struct {
+	Payload WebhookPayload `json:"payload"`
+	// Attempts is how many times the delivery was tried
+	Attempts int `json:"attempts"`
+	// LastError describes the failure of the last attempt
+	LastError string `json:"lastError"`
+	// Time is when the delivery was abandoned
+	Time time.Time `json:"time"`
+}
@@ -0,0 +1,79 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+func TestTokens(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokens(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tokens.Create(ctx, "1", ""); !errors.Is(err, auth.ErrInvalidName) {
+		t.Fatalf("expected %v got %v", auth.ErrInvalidName, err)
+	}
+	_, secret, err := tokens.Create(ctx, "1", "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tokens.Create(ctx, "1", "ci"); !errors.As(err, &store.ErrAlreadyExists{}) {
+		t.Fatalf("duplicate id: unexpected error %v", err)
+	}
+
+	// the tokens are persisted, and shared with the other users of the store
+	reloaded, err := auth.NewTokens(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := reloaded.Verify(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := auth.Principal{Name: "ci", Method: auth.MethodToken, TokenID: "1"}
+	if p != expected {
+		t.Errorf("got %+v expected %+v", p, expected)
+	}
+	if _, err := reloaded.Verify(secret + "0"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("wrong secret: expected %v got %v", auth.ErrInvalidCredentials, err)
+	}
+
+	if err := reloaded.Revoke(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.Verify(secret); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("revoked token: expected %v got %v", auth.ErrInvalidCredentials, err)
+	}
+	if err := reloaded.Revoke(ctx, "1"); !errors.As(err, &store.ErrNotFound{}) {
+		t.Errorf("revoked twice: unexpected error %v", err)
+	}
+	if len(st.Blobs) != 0 {
+		t.Errorf("revoked token still stored: %v", st.Blobs)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	users := auth.NewUsers(map[string]string{"admin": "p4ssw0rd"})
+	p, err := users.Verify("admin", "p4ssw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != (auth.Principal{Name: "admin", Method: auth.MethodBasic}) {
+		t.Errorf("unexpected principal %+v", p)
+	}
+	for _, creds := range [][2]string{{"admin", "wrong"}, {"root", "p4ssw0rd"}, {"", ""}} {
+		if _, err := users.Verify(creds[0], creds[1]); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Errorf("%v: expected %v got %v", creds, auth.ErrInvalidCredentials, err)
+		}
+	}
+}
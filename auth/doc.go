@@ -0,0 +1,4 @@
+// Package auth verifies who is calling the API: the holders of the API tokens, which
+// are persisted hashed in the store, and the static users of the configuration, which
+// authenticate with basic auth. The HTTP side lives in the middleware package.
+package auth
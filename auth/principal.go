@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidCredentials is returned when the credentials match no token or user
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Method is how a principal authenticated
+type Method string
+
+const (
+	MethodToken Method = "token"
+	MethodBasic Method = "basic"
+)
+
+// Principal is who performs a request
+type Principal struct {
+	// Name is the name of the user, or of the token
+	Name string
+	// Method is how the principal authenticated
+	Method Method
+	// TokenID identifies the token used, if any
+	TokenID string
+}
+
+// IsAdmin tells if the principal is a administrator: the static users of the configuration
+// are, the holders of the API tokens are not.
+func (p Principal) IsAdmin() bool {
+	return p.Method == MethodBasic
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of the context carrying the given principal
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal carried by the context, if any
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
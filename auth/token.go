@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// namespace is the store namespace holding the tokens
+const namespace = "token"
+
+// ErrInvalidName is returned when creating a token without a name
+var ErrInvalidName = errors.New("invalid token name")
+
+// Token is a API token. The secret presented by its holder is never stored, only its hash.
+type Token struct {
+	// Name describes the holder of the token; it is the name of its principal
+	Name string
+	// Hash is the hex encoded SHA-256 hash of the secret
+	Hash string
+	// Created is when the token was created
+	Created time.Time
+}
+
+// ToAPIv1 converts the object into the corresponding API layer object.
+// The secret, known only on creation, is included if not empty.
+func (tok Token) ToAPIv1(id, secret string) apiv1.Token {
+	return apiv1.Token{
+		ID:      apiv1.ID(id),
+		Name:    tok.Name,
+		Secret:  secret,
+		Created: tok.Created,
+	}
+}
+
+// Serialize encodes the object in its canonical bytestream representation.
+// If succesfull, returns the representation; otherwise the representation
+// must be ignored, and the error will describe the failure.
+func (tok Token) Serialize() ([]byte, error) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(tok)
+	return buf.Bytes(), err
+}
+
+// DeserializeToken decodes the object from its canonical bytestream representation.
+// If succesfull, returns the decode object; otherwise returns a zero valued
+// object, and the error will describe the failure.
+func DeserializeToken(data []byte) (Token, error) {
+	var tok Token
+	err := json.NewDecoder(bytes.NewBuffer(data)).Decode(&tok)
+	return tok, err
+}
+
+// Tokens manages the API tokens, persisted in the store.
+// Tokens is safe for concurrent use.
+type Tokens struct {
+	lock   sync.RWMutex
+	storer store.Storage
+	tokens map[string]Token
+	// byHash maps the hashes of the secrets to the ids of their tokens
+	byHash map[string]string
+}
+
+// NewTokens creates a new Tokens, loading the tokens persisted in the given store,
+// which can be shared with other users, like the Ledger.
+// Returns error if the initialization fails; in this case, the returned object must be ignored.
+func NewTokens(ctx context.Context, storer store.Storage) (*Tokens, error) {
+	items, err := storer.LoadAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ts := Tokens{
+		storer: storer,
+		tokens: make(map[string]Token),
+		byHash: make(map[string]string),
+	}
+	prefix := string(store.Namespaced(namespace, ""))
+	for _, item := range items {
+		if item.ID.Namespace() != namespace {
+			continue
+		}
+		tok, err := DeserializeToken(item.Blob)
+		if err != nil {
+			return nil, err
+		}
+		id := string(item.ID)[len(prefix):]
+		ts.tokens[id] = tok
+		ts.byHash[tok.Hash] = id
+	}
+	log.Printf("auth: loaded %d tokens", len(ts.tokens))
+	return &ts, nil
+}
+
+// Create persists a new token with the given id and name, generating its secret.
+// Returns the token and its secret, which can't be recovered later.
+func (ts *Tokens) Create(ctx context.Context, id, name string) (Token, string, error) {
+	if name == "" {
+		return Token{}, "", ErrInvalidName
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return Token{}, "", err
+	}
+	secret := hex.EncodeToString(key)
+	tok := Token{
+		Name:    name,
+		Hash:    hashSecret(secret),
+		Created: time.Now().UTC(),
+	}
+	blob, err := tok.Serialize()
+	if err != nil {
+		return Token{}, "", err
+	}
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	if err := ts.storer.Create(ctx, store.Namespaced(namespace, id), blob); err != nil {
+		return Token{}, "", err
+	}
+	ts.tokens[id] = tok
+	ts.byHash[tok.Hash] = id
+	return tok, secret, nil
+}
+
+// Revoke removes a token: its secret is not accepted anymore.
+// Returns store.ErrNotFound if the id is unknown.
+func (ts *Tokens) Revoke(ctx context.Context, id string) error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	tok, ok := ts.tokens[id]
+	if !ok {
+		return store.ErrNotFound{ID: store.ID(id)}
+	}
+	if err := ts.storer.Delete(ctx, store.Namespaced(namespace, id)); err != nil {
+		return err
+	}
+	delete(ts.tokens, id)
+	delete(ts.byHash, tok.Hash)
+	return nil
+}
+
+// Get returns a token from its id. Returns store.ErrNotFound if the id is unknown.
+func (ts *Tokens) Get(id string) (Token, error) {
+	ts.lock.RLock()
+	defer ts.lock.RUnlock()
+	tok, ok := ts.tokens[id]
+	if !ok {
+		return Token{}, store.ErrNotFound{ID: store.ID(id)}
+	}
+	return tok, nil
+}
+
+// List returns the ids of all the tokens, sorted
+func (ts *Tokens) List() []string {
+	ts.lock.RLock()
+	defer ts.lock.RUnlock()
+	ids := make([]string, 0, len(ts.tokens))
+	for id := range ts.tokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Verify returns the principal of the token with the given secret.
+// Returns ErrInvalidCredentials if no token has it.
+func (ts *Tokens) Verify(secret string) (Principal, error) {
+	ts.lock.RLock()
+	defer ts.lock.RUnlock()
+	// looking up the hash doesn't leak the secrets by the timing
+	id, ok := ts.byHash[hashSecret(secret)]
+	if !ok {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Name: ts.tokens[id].Name, Method: MethodToken, TokenID: id}, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,284 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Policy tells what to do importing a todo whose ID already exists
+type Policy string
+
+const (
+	// PolicySkip keeps the existing todo
+	PolicySkip Policy = "skip"
+	// PolicyOverwrite replaces the existing todo with the imported one
+	PolicyOverwrite Policy = "overwrite"
+	// PolicyFail aborts the import, before any todo is imported
+	PolicyFail Policy = "fail"
+
+	// maxLineSize is the longest line accepted importing
+	maxLineSize = 1024 * 1024
+	// maxReportedErrors caps the invalid lines reported by a ValidationError
+	maxReportedErrors = 100
+)
+
+var (
+	ErrInvalidPolicy = errors.New("invalid conflict policy")
+	ErrConflict      = errors.New("todo already exists")
+)
+
+// ParsePolicy returns the conflict policy with the given name
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case PolicySkip, PolicyOverwrite, PolicyFail:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w %q, expected one of skip, overwrite, fail", ErrInvalidPolicy, name)
+	}
+}
+
+// LineError describes a invalid line of a backup
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists the invalid lines of a backup, at most maxReportedErrors
+type ValidationError struct {
+	Errors []LineError
+	// Total is the number of invalid lines, including the ones not reported
+	Total int
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, lineErr := range e.Errors {
+		msgs = append(msgs, lineErr.Error())
+	}
+	more := ""
+	if e.Total > len(e.Errors) {
+		more = fmt.Sprintf("; and %d more", e.Total-len(e.Errors))
+	}
+	return fmt.Sprintf("invalid backup: %d invalid lines: %s%s", e.Total, strings.Join(msgs, "; "), more)
+}
+
+// Unwrap makes errors.Is match the errors of the reported lines, e.g. ErrConflict
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, lineErr := range e.Errors {
+		errs = append(errs, lineErr)
+	}
+	return errs
+}
+
+func (e *ValidationError) add(line int, err error) {
+	e.Total++
+	if len(e.Errors) < maxReportedErrors {
+		e.Errors = append(e.Errors, LineError{Line: line, Err: err})
+	}
+}
+
+// Report summarizes a import
+type Report struct {
+	// Total is the number of todos in the backup
+	Total int
+	// Created is the number of todos imported with a new ID
+	Created int
+	// Overwritten is the number of existing todos replaced
+	Overwritten int
+	// Skipped is the number of existing todos kept
+	Skipped int
+}
+
+// ToAPIv1 converts the report on its API layer corresponding object
+func (rep Report) ToAPIv1() apiv1.ImportReport {
+	return apiv1.ImportReport{
+		Total:       rep.Total,
+		Created:     rep.Created,
+		Overwritten: rep.Overwritten,
+		Skipped:     rep.Skipped,
+	}
+}
+
+// Export writes all the todos of the ledger, in order of ID, as a snapshot taken at once.
+// Returns the number of todos written; on failure, the error is not nil.
+func Export(w io.Writer, ld *ledger.Ledger) (int, error) {
+	items, _, err := ld.Query(ledger.Query{})
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for idx, item := range items {
+		if err := enc.Encode(item.ToAPIv1()); err != nil {
+			return idx, err
+		}
+	}
+	log.Printf("backup: exported %d todos", len(items))
+	return len(items), bw.Flush()
+}
+
+// entry is a todo to import
+type entry struct {
+	line int
+	id   store.ID
+	todo model.Todo
+}
+
+// Notify is called with each imported todo, once committed and before any other write
+// to the ledger, e.g. to publish it. It must not block, nor use the ledger.
+type Notify func(id store.ID, todo model.Todo)
+
+// Import reads a backup and stores its todos in the ledger, resolving the conflicts with
+// the existing ones according to the policy. The whole backup is validated first: if any
+// line is invalid, or conflicts under PolicyFail, returns a *ValidationError and imports nothing.
+// With dryRun, returns the report of what would be imported, without importing anything.
+// Each todo is stored along with a history entry on behalf of actor, and then passed to notify,
+// if not nil. The import is not atomic: on failure, including a todo created under PolicyFail
+// after the validation, it returns the report of the todos already imported, and importing
+// again the same backup with PolicySkip completes it.
+// The revisions of the imported todos are managed by the ledger, as for any other update.
+func Import(ctx context.Context, r io.Reader, ld *ledger.Ledger, policy Policy, dryRun bool, actor string, notify Notify) (Report, error) {
+	if _, err := ParsePolicy(string(policy)); err != nil {
+		return Report{}, err
+	}
+	entries, err := validate(r, ld, policy)
+	if err != nil {
+		return Report{}, err
+	}
+
+	var report Report
+	report.Total = len(entries)
+	for _, ent := range entries {
+		var exists bool
+		if dryRun {
+			_, err := ld.Get(ent.id)
+			exists = err == nil
+		} else if exists, err = importEntry(ctx, ld, ent, policy, actor, notify); err != nil {
+			return report, LineError{Line: ent.line, Err: err}
+		}
+		switch {
+		case exists && policy == PolicySkip:
+			report.Skipped++
+		case exists:
+			report.Overwritten++
+		default:
+			report.Created++
+		}
+	}
+	log.Printf("backup: imported %d todos: created=%d overwritten=%d skipped=%d dryRun=%v",
+		report.Total, report.Created, report.Overwritten, report.Skipped, dryRun)
+	return report, nil
+}
+
+// importEntry stores a todo along with its history entry, unless the policy keeps the existing one.
+// Returns whether the todo already existed.
+func importEntry(ctx context.Context, ld *ledger.Ledger, ent entry, policy Policy, actor string, notify Notify) (bool, error) {
+	var exists bool
+	err := ld.Update(ctx, func(tx *ledger.Tx) error {
+		before, err := tx.Get(ent.id)
+		if err != nil && !errors.Is(err, store.ErrNotFound{ID: ent.id}) {
+			return err
+		}
+		exists = err == nil
+		switch {
+		case exists && policy == PolicyFail:
+			// created after the validation
+			return fmt.Errorf("%w: %q", ErrConflict, ent.id)
+		case exists && policy == PolicySkip:
+			return nil
+		}
+		todo, err := tx.Set(ent.id, ent.todo)
+		if err != nil {
+			return err
+		}
+		if notify != nil {
+			tx.OnCommit(func() { notify(ent.id, todo) })
+		}
+		return tx.Record(ent.id, model.NewHistoryEntry(actor, apiv1.OpImport, before, todo))
+	})
+	return exists, err
+}
+
+// validate decodes and checks all the lines of a backup, skipping the blank ones
+func validate(r io.Reader, ld *ledger.Ledger, policy Policy) ([]entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	var entries []entry
+	verr := &ValidationError{}
+	seen := make(map[store.ID]int)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		ent, err := decode(data)
+		if err != nil {
+			verr.add(line, err)
+			continue
+		}
+		if prev, ok := seen[ent.id]; ok {
+			verr.add(line, fmt.Errorf("duplicated id %q, first seen at line %d", ent.id, prev))
+			continue
+		}
+		seen[ent.id] = line
+		ent.line = line
+		if policy == PolicyFail {
+			if _, err := ld.Get(ent.id); err == nil {
+				verr.add(line, fmt.Errorf("%w: %q", ErrConflict, ent.id))
+				continue
+			}
+		}
+		entries = append(entries, ent)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading line %d: %w", line+1, err)
+	}
+	if verr.Total > 0 {
+		return nil, verr
+	}
+	return entries, nil
+}
+
+func decode(data []byte) (entry, error) {
+	var item apiv1.Item
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&item); err != nil {
+		return entry{}, err
+	}
+	id := store.ID(item.ID)
+	if id == store.NullID || id.Namespace() != "" {
+		return entry{}, fmt.Errorf("invalid id %q", item.ID)
+	}
+	if item.Todo == nil {
+		return entry{}, errors.New("missing todo")
+	}
+	todo, err := model.RestoreFromAPIv1(*item.Todo)
+	if err != nil {
+		return entry{}, err
+	}
+	return entry{id: id, todo: todo}, nil
+}
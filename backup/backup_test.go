@@ -0,0 +1,339 @@
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/backup"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func newLedger(t *testing.T) *ledger.Ledger {
+	t.Helper()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ldg
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := newLedger(t)
+	due := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	todos := map[store.ID]model.Todo{
+		"a": {Title: "deploy", Status: apiv1.Pending, Priority: apiv1.Urgent, DueTime: &due, Labels: []string{"ops", "web"}},
+		"b": {Title: "docs", Description: "write them", Status: apiv1.Assigned, Assignee: "ana", PreviousAssignees: []string{"bob"}},
+		"c": {Title: "done", Status: apiv1.Completed, Assignee: "bob"},
+	}
+	for id, todo := range todos {
+		todo.LastUpdateTime = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+		if err := src.Set(ctx, id, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// histories are not todos, and are not exported
+	if err := src.Record(ctx, "a", model.NewHistoryEntry("ana", apiv1.OpCreate, model.Todo{}, todos["a"])); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	count, err := backup.Export(&buf, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || strings.Count(buf.String(), "\n") != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", count, buf.String())
+	}
+
+	dst := newLedger(t)
+	report, err := backup.Import(ctx, bytes.NewReader(buf.Bytes()), dst, backup.PolicyFail, false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != (backup.Report{Total: 3, Created: 3}) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for id := range todos {
+		expected, err := src.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := dst.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected.Priority == "" {
+			// exported as the default priority
+			expected.Priority = apiv1.Normal
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("todo %v: expected %#v got %#v", id, expected, got)
+		}
+	}
+}
+
+func TestImportPolicies(t *testing.T) {
+	ctx := context.Background()
+	backupData := `{"id":"a","todo":{"title":"imported a","status":"pending","updated":"2026-03-10T12:00:00Z"}}
+
+{"id":"b","todo":{"title":"imported b","status":"pending","updated":"2026-03-10T12:00:00Z"}}
+`
+	tests := []struct {
+		policy   backup.Policy
+		dryRun   bool
+		report   backup.Report
+		expected string
+		err      error
+	}{
+		{backup.PolicySkip, false, backup.Report{Total: 2, Created: 1, Skipped: 1}, "existing a", nil},
+		{backup.PolicyOverwrite, false, backup.Report{Total: 2, Created: 1, Overwritten: 1}, "imported a", nil},
+		{backup.PolicyOverwrite, true, backup.Report{Total: 2, Created: 1, Overwritten: 1}, "existing a", nil},
+		{backup.PolicyFail, false, backup.Report{}, "existing a", backup.ErrConflict},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.policy)+map[bool]string{true: " dry run"}[tc.dryRun], func(t *testing.T) {
+			ldg := newLedger(t)
+			if err := ldg.Set(ctx, "a", model.New("existing a")); err != nil {
+				t.Fatal(err)
+			}
+			report, err := backup.Import(ctx, strings.NewReader(backupData), ldg, tc.policy, tc.dryRun, "", nil)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v got %v", tc.err, err)
+			}
+			if report != tc.report {
+				t.Fatalf("expected report %+v got %+v", tc.report, report)
+			}
+			todo, err := ldg.Get("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if todo.Title != tc.expected {
+				t.Fatalf("expected title %q got %q", tc.expected, todo.Title)
+			}
+			_, err = ldg.Get("b")
+			if imported := err == nil; imported != (tc.err == nil && !tc.dryRun) {
+				t.Fatalf("unexpected import of b: %v", err)
+			}
+		})
+	}
+}
+
+// racingReader creates a todo in the ledger once the backup is read, i.e. validated
+type racingReader struct {
+	*strings.Reader
+	t   *testing.T
+	ldg *ledger.Ledger
+}
+
+func (rr racingReader) Read(p []byte) (int, error) {
+	n, err := rr.Reader.Read(p)
+	if err == io.EOF {
+		if err := rr.ldg.Set(context.Background(), "a", model.New("created meanwhile")); err != nil {
+			rr.t.Fatal(err)
+		}
+	}
+	return n, err
+}
+
+func TestImportConflictAfterValidation(t *testing.T) {
+	ctx := context.Background()
+	backupData := `{"id":"a","todo":{"title":"imported a","status":"pending","updated":"2026-03-10T12:00:00Z"}}
+`
+	for policy, expected := range map[backup.Policy]error{
+		backup.PolicySkip: nil,
+		backup.PolicyFail: backup.ErrConflict,
+	} {
+		t.Run(string(policy), func(t *testing.T) {
+			ldg := newLedger(t)
+			r := racingReader{Reader: strings.NewReader(backupData), t: t, ldg: ldg}
+			if _, err := backup.Import(ctx, r, ldg, policy, false, "", nil); !errors.Is(err, expected) {
+				t.Fatalf("expected error %v got %v", expected, err)
+			}
+			todo, err := ldg.Get("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if todo.Title != "created meanwhile" {
+				t.Fatalf("overwritten todo created meanwhile: %v", todo)
+			}
+		})
+	}
+}
+
+func TestImportHistoryAndNotify(t *testing.T) {
+	ctx := context.Background()
+	backupData := `{"id":"a","todo":{"title":"imported a","status":"pending","updated":"2026-03-10T12:00:00Z"}}
+{"id":"b","todo":{"title":"imported b","status":"pending","updated":"2026-03-10T12:00:00Z"}}
+`
+	ldg := newLedger(t)
+	if err := ldg.Set(ctx, "a", model.New("existing a")); err != nil {
+		t.Fatal(err)
+	}
+	notified := make(map[store.ID]string)
+	notify := func(id store.ID, todo model.Todo) { notified[id] = todo.Title }
+	if _, err := backup.Import(ctx, strings.NewReader(backupData), ldg, backup.PolicyOverwrite, false, "admin", notify); err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[store.ID]string{"a": "imported a", "b": "imported b"}; !reflect.DeepEqual(notified, expected) {
+		t.Fatalf("expected notifications %v got %v", expected, notified)
+	}
+	for id, before := range map[store.ID]string{"a": "existing a", "b": ""} {
+		history, err := ldg.History(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].Actor != "admin" || history[0].Operation != apiv1.OpImport {
+			t.Fatalf("unexpected history of %v: %+v", id, history)
+		}
+		if change := history[0].Changes[0]; change.Field != "title" || change.Before != before || change.After != notified[id] {
+			t.Fatalf("unexpected title change of %v: %+v", id, change)
+		}
+	}
+}
+
+func TestImportValidation(t *testing.T) {
+	backupData := strings.Join([]string{
+		`{"id":"a","todo":{"title":"ok","status":"pending"}}`,
+		`not json`,
+		`{"id":"","todo":{"title":"no id","status":"pending"}}`,
+		`{"id":"history/a","todo":{"title":"namespaced","status":"pending"}}`,
+		`{"id":"b"}`,
+		`{"id":"c","todo":{"title":"bad status","status":"sleeping"}}`,
+		`{"id":"d","todo":{"title":"assigned to nobody","status":"assigned"}}`,
+		`{"id":"e","todo":{"title":"bad label","status":"pending","labels":["Not A Label"]}}`,
+		`{"id":"a","todo":{"title":"duplicated","status":"pending"}}`,
+		`{"id":"f","todo":{"title":"unknown field","status":"pending","mood":"happy"}}`,
+	}, "\n")
+	ldg := newLedger(t)
+	_, err := backup.Import(context.Background(), strings.NewReader(backupData), ldg, backup.PolicyOverwrite, false, "", nil)
+	var validationErr *backup.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	var lines []int
+	for _, lineErr := range validationErr.Errors {
+		lines = append(lines, lineErr.Line)
+	}
+	if expected := []int{2, 3, 4, 5, 6, 7, 8, 9, 10}; !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expected invalid lines %v got %v: %v", expected, lines, err)
+	}
+	if _, err := ldg.Get("a"); err == nil {
+		t.Fatal("invalid backup partially imported")
+	}
+}
+
+// TestRoundTripThroughAPI exports the todos as the API leaves them, not as hand-built fixtures,
+// and imports them back.
+func TestRoundTripThroughAPI(t *testing.T) {
+	src := newLedger(t)
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokens(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the export is reserved to the admins
+	users := auth.NewUsers(map[string]string{"admin": "p4ssw0rd"})
+	handler := controller.New(src, uuid.NewSequence(1), controller.WithAuth(tokens, users))
+	request := func(method, target, body string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.SetBasicAuth("admin", "p4ssw0rd")
+		return r
+	}
+	do := func(method, target, body string) apiv1.Response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request(method, target, body))
+		if w.Code >= http.StatusBadRequest {
+			t.Fatalf("%s %s: unexpected code %d: %s", method, target, w.Code, w.Body.String())
+		}
+		var resp apiv1.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	create := func(body string) string {
+		t.Helper()
+		return string(do(http.MethodPost, "/todos", body).Result.Items[0].ID)
+	}
+
+	due := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	pending := create(`{"title":"pending"}`)
+	do(http.MethodPut, "/todos/"+pending, `{"priority":"high","due":"`+due+`"}`)
+	do(http.MethodPost, "/todos/"+pending+"/label", `{"labels":["ops"]}`)
+	assigned := create(`{"title":"assigned"}`)
+	do(http.MethodPut, "/todos/"+assigned, `{"assignee":"ana"}`)
+	do(http.MethodPut, "/todos/"+assigned, `{"assignee":"ana","priority":"urgent"}`)
+	do(http.MethodPost, "/todos/"+assigned+"/reassign", `{"assignee":"bob"}`)
+	completed := create(`{"title":"completed"}`)
+	do(http.MethodPut, "/todos/"+completed, `{"assignee":"ana"}`)
+	do(http.MethodPost, "/todos/"+completed+"/complete", `{}`)
+	create(`{"title":"merged 1"}`)
+	do(http.MethodPut, "/todos/4", `{"assignee":"ana"}`)
+	create(`{"title":"merged 2"}`)
+	do(http.MethodPost, "/todomerge/4/5", `{}`)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodGet, "/admin/export", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: unexpected code %d: %s", w.Code, w.Body.String())
+	}
+
+	dst := newLedger(t)
+	report, err := backup.Import(context.Background(), w.Body, dst, backup.PolicyFail, false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != (backup.Report{Total: 4, Created: 4}) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	items, err := src.Filter(func(model.Todo) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		got, err := dst.Get(item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(apiTodo(got), apiTodo(*item.Todo)) {
+			t.Errorf("todo %v: expected %+v got %+v", item.ID, apiTodo(*item.Todo), apiTodo(got))
+		}
+	}
+}
+
+// apiTodo returns the fields of a todo the backups must restore
+func apiTodo(todo model.Todo) apiv1.Todo {
+	api := todo.ToAPIv1()
+	api.Revision = 0
+	api.LastUpdateTime = api.LastUpdateTime.UTC().Truncate(time.Second)
+	if api.DueTime != nil {
+		due := api.DueTime.UTC().Truncate(time.Second)
+		api.DueTime = &due
+	}
+	return api
+}
@@ -0,0 +1,5 @@
+// Package backup exports the todos of a ledger, and imports them back, as JSON Lines:
+// one apiv1.Item, holding the ID and all the fields of a todo, per line.
+// Backups are a snapshot of the ledger taken while it keeps serving, and can move the
+// todos between any store backends.
+package backup
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/gotestbootcamp/go-todo-app/backup"
+	"github.com/gotestbootcamp/go-todo-app/config"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+)
+
+// exportTodos writes all the todos of the configured store as JSON Lines. Returns the exit code.
+// Test with this command:
+//
+//	go run ./cmd export -data-dir /tmp/todos -file /tmp/todos.jsonl
+func exportTodos(args []string) int {
+	cfg, ldg, code := openLedger(args)
+	if ldg == nil {
+		return code
+	}
+	defer ldg.Close()
+
+	var w io.Writer = os.Stdout
+	if cfg.BackupFile != "-" {
+		f, err := os.Create(cfg.BackupFile)
+		if err != nil {
+			log.Printf("error creating the backup file: %v", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	count, err := backup.Export(w, ldg)
+	if err != nil {
+		log.Printf("error exporting the todos: %v", err)
+		return 1
+	}
+	log.Printf("export: exported %d todos", count)
+	return 0
+}
+
+// importTodos reads the todos to store in the configured store as JSON Lines. Returns the exit code.
+// Test with this command:
+//
+//	go run ./cmd import -data-dir /tmp/todos -file /tmp/todos.jsonl -on-conflict skip -dry-run
+func importTodos(args []string) int {
+	cfg, ldg, code := openLedger(args)
+	if ldg == nil {
+		return code
+	}
+	defer ldg.Close()
+
+	policy, err := backup.ParsePolicy(cfg.OnConflict)
+	if err != nil {
+		log.Printf("error parsing flags: %v", err)
+		return 2
+	}
+	var r io.Reader = os.Stdin
+	if cfg.BackupFile != "-" {
+		f, err := os.Open(cfg.BackupFile)
+		if err != nil {
+			log.Printf("error opening the backup file: %v", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	// no server is running along, so there is no one to notify the imported todos to
+	report, err := backup.Import(context.Background(), r, ldg, policy, cfg.DryRun, "", nil)
+	log.Printf("import: %d todos: created %d, overwritten %d, skipped %d", report.Total, report.Created, report.Overwritten, report.Skipped)
+	if err != nil {
+		log.Printf("error importing the todos: %v", err)
+		return 1
+	}
+	if cfg.DryRun {
+		log.Printf("import: dry run, nothing changed")
+	}
+	return 0
+}
+
+// openLedger creates the ledger over the configured store. On failure, the ledger is nil
+// and the exit code is returned.
+func openLedger(args []string) (config.Config, *ledger.Ledger, int) {
+	cfg, err := config.Load(args...)
+	if err != nil {
+		return cfg, nil, configError(err)
+	}
+	st, err := openStore(cfg)
+	if err != nil {
+		log.Printf("error creating store backend: %v", err)
+		return cfg, nil, 1
+	}
+	ldg, err := ledger.New(context.Background(), st, ledger.WithTimeout(cfg.StoreTimeout))
+	if err != nil {
+		log.Printf("error creating the ledger: %v", err)
+		st.Close()
+		return cfg, nil, 1
+	}
+	return cfg, ldg, 0
+}
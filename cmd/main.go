@@ -1,47 +1,164 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/gotestbootcamp/go-todo-app/auth"
 	"github.com/gotestbootcamp/go-todo-app/config"
 	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/feed"
 	"github.com/gotestbootcamp/go-todo-app/ledger"
 	"github.com/gotestbootcamp/go-todo-app/store"
 	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
 )
 
 func main() {
-	cfg, err := config.FromFlags(os.Args[1:]...)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(migrate(os.Args[2:]))
+		case "export":
+			os.Exit(exportTodos(os.Args[2:]))
+		case "import":
+			os.Exit(importTodos(os.Args[2:]))
+		}
+	}
+
+	os.Exit(serve(os.Args[1:]))
+}
+
+// serve runs the server until SIGINT or SIGTERM, then shuts it down gracefully:
+// the in-flight requests have the configured grace period to complete, then
+// the ledger and the store are closed. Returns the exit code.
+func serve(args []string) int {
+	cfg, err := config.Load(args...)
 	if err != nil {
-		log.Printf("error parsing flags: %v", err)
-		os.Exit(0)
+		return configError(err)
 	}
 	log.Printf("ready: configuration:\n%s", cfg.String())
 
-	var st store.Storage
-	if cfg.Redis.URL != "" {
-		log.Printf("store: using backend \"redis\"")
-		st, err = store.NewRedis(cfg.Redis.URL, cfg.Redis.Password, cfg.Redis.Database)
-	} else {
-		log.Printf("store: using backend \"fake\"")
-		st, err = fake.NewMem()
-	}
+	// a signal also aborts the startup, e.g. while loading a big store
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	st, err := openStore(cfg)
 	if err != nil {
 		log.Printf("error creating store backend: %v", err)
+		return 1
 	}
 	log.Printf("ready: store backend")
 
-	ldg, err := ledger.New(st)
+	ldg, err := ledger.New(ctx, st, ledger.WithTimeout(cfg.StoreTimeout))
 	if err != nil {
-		log.Printf("error parsing flags: %v", err)
+		log.Printf("error creating the ledger: %v", err)
+		st.Close()
+		return 1
 	}
+	// closing the ledger closes the store too
+	defer func() {
+		if err := ldg.Close(); err != nil {
+			log.Printf("shutdown: error closing the ledger: %v", err)
+		}
+		log.Printf("shutdown: closed the ledger")
+	}()
 	log.Printf("ready: data ledger")
 
-	ctrl := controller.New(ldg)
+	uuidGen, err := uuid.FromKind(cfg.IDGenerator)
+	if err != nil {
+		log.Printf("error creating the id generator: %v", err)
+		return 1
+	}
+	log.Printf("ready: id generator %q", cfg.IDGenerator)
+
+	webhookOpts := webhook.DefaultOptions()
+	webhookOpts.AllowedHosts = cfg.Webhooks.AllowedHostList()
+	dispatcher, err := webhook.NewDispatcher(ctx, st, webhookOpts)
+	if err != nil {
+		log.Printf("error creating the webhook dispatcher: %v", err)
+		return 1
+	}
+	// deferred after the ledger, so closed before it: the deliveries don't outlive the store
+	defer dispatcher.Close()
+	log.Printf("ready: webhook dispatcher")
+
+	events := feed.NewBroker(feed.DefaultBufferSize)
+	opts := []controller.Option{controller.WithWebhooks(dispatcher), controller.WithEvents(events)}
+	if cfg.Auth.Enabled {
+		tokens, err := auth.NewTokens(ctx, st)
+		if err != nil {
+			log.Printf("error loading the API tokens: %v", err)
+			return 1
+		}
+		// already validated
+		passwords, _ := config.ParseUsers(cfg.Auth.Users)
+		opts = append(opts, controller.WithAuth(tokens, auth.NewUsers(passwords)))
+		log.Printf("ready: authentication, %d users, %d tokens", len(passwords), len(tokens.List()))
+	}
+	ctrl := controller.New(ldg, uuidGen, opts...)
 	log.Printf("ready: controller")
 
+	srv := &http.Server{
+		Addr:    cfg.Address,
+		Handler: ctrl,
+	}
+	// the event streams never complete on their own
+	srv.RegisterOnShutdown(events.Close)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
 	log.Printf("start serving on address %q", cfg.Address)
-	log.Fatal(http.ListenAndServe(cfg.Address, ctrl))
+
+	select {
+	case err := <-serveErr:
+		log.Printf("error serving: %v", err)
+		return 1
+	case <-ctx.Done():
+	}
+	// from now on, a second signal kills the process
+	stop()
+	log.Printf("shutdown: waiting up to %v for the in-flight requests", cfg.ShutdownGrace)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGrace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: closing the requests still in flight: %v", err)
+		srv.Close()
+		return 1
+	}
+	log.Printf("shutdown: all requests completed")
+	return 0
+}
+
+// configError returns the exit code of a failure loading the configuration: success if the usage
+// was requested, since it was printed already
+func configError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	log.Printf("error loading the configuration:\n%v", err)
+	return 2
+}
+
+// openStore creates the store backend selected by the configuration
+func openStore(cfg config.Config) (store.Storage, error) {
+	if cfg.Redis.URL != "" {
+		log.Printf("store: using backend \"redis\"")
+		return store.NewRedis(cfg.Redis.URL, cfg.Redis.Password, cfg.Redis.Database, cfg.Redis.Prefix)
+	}
+	if cfg.DataDir != "" {
+		log.Printf("store: using backend \"filelog\" on %q", cfg.DataDir)
+		return store.NewFileLog(cfg.DataDir, store.DefaultCompactThreshold)
+	}
+	log.Printf("store: using backend \"fake\"")
+	return fake.NewMem()
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/gotestbootcamp/go-todo-app/config"
+	migrations "github.com/gotestbootcamp/go-todo-app/migrate"
+)
+
+// migrate applies the registered migrations to the configured store, printing the diff
+// of every record changed on the standard output. Returns the exit code.
+// Test with this command:
+//
+//	go run ./cmd migrate -data-dir /tmp/todos -dry-run
+func migrate(args []string) int {
+	cfg, err := config.Load(args...)
+	if err != nil {
+		return configError(err)
+	}
+	log.Printf("migrate: configuration:\n%s", cfg.String())
+
+	st, err := openStore(cfg)
+	if err != nil {
+		log.Printf("error creating store backend: %v", err)
+		return 1
+	}
+	defer st.Close()
+
+	reports, err := migrations.Run(context.Background(), st, migrations.Registered(), migrations.Options{
+		DryRun: cfg.DryRun,
+		Out:    os.Stdout,
+	})
+	for _, report := range reports {
+		log.Printf("migrate: migration %d %q: scanned %d records, changed %d", report.Version, report.Name, report.Scanned, report.Changed)
+	}
+	if err != nil {
+		log.Printf("error migrating the store: %v", err)
+		return 1
+	}
+	if cfg.DryRun {
+		log.Printf("migrate: dry run, nothing changed")
+	}
+	return 0
+}
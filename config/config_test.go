@@ -0,0 +1,203 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/config"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+url: localhost:9000
+redis-url: redis:6379
+redis-database: 1
+store-timeout: 1s
+dry-run: true
+`)
+	t.Setenv("TODO_REDIS_DATABASE", "2")
+	t.Setenv("TODO_STORE_TIMEOUT", "2s")
+
+	cfg, err := config.Load("-config", path, "-redis-database", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := config.Defaults()
+	expected.ConfigFile = path
+	expected.Address = "localhost:9000"     // file
+	expected.Redis.URL = "redis:6379"       // file
+	expected.DryRun = true                  // file
+	expected.StoreTimeout = 2 * time.Second // env over file
+	expected.Redis.Database = 3             // flag over env over file
+	if cfg != expected {
+		t.Fatalf("expected\n%+v\ngot\n%+v", expected, cfg)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("TODO_CONFIG", writeFile(t, "data-dir: /var/lib/todos\n"))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DataDir != "/var/lib/todos" {
+		t.Fatalf("configuration file not loaded: %+v", cfg)
+	}
+
+	// the flag wins over the environment
+	cfg, err = config.Load("-config", writeFile(t, "data-dir: /tmp/todos\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DataDir != "/tmp/todos" {
+		t.Fatalf("configuration file not loaded: %+v", cfg)
+	}
+}
+
+func TestLoadIgnoresUnknownEnv(t *testing.T) {
+	// the prefix is generic, other software may use it too
+	t.Setenv("TODO_LIST", "milk,eggs")
+	t.Setenv("TODO_REDIS_PASWORD", "secret")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Redis.Password != "" {
+		t.Errorf("unexpected password %q", cfg.Redis.Password)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := writeFile(t, `
+url: localhost:9000
+redis-host: redis
+store-timeout: forever
+data-dir: [a, b]
+`)
+	t.Setenv("TODO_REDIS_DATABASE", "first")
+
+	_, err := config.Load("-config", path)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, expected := range []string{
+		`config.yaml:3: unknown key "redis-host"`,
+		`config.yaml:4: "store-timeout": invalid value`,
+		`config.yaml:5: "data-dir": expected a scalar value`,
+		`TODO_REDIS_DATABASE: invalid value`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in:\n%v", expected, err)
+		}
+	}
+
+	if _, err := config.Load("-config", filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %v got %v", os.ErrNotExist, err)
+	}
+	if _, err := config.Load("-h"); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("expected %v got %v", flag.ErrHelp, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := config.Defaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults not valid: %v", err)
+	}
+
+	cfg.Address = "localhost"
+	cfg.StoreTimeout = -time.Second
+	cfg.IDGenerator = "uuidv5"
+	cfg.OnConflict = "merge"
+	cfg.Auth.Users = "admin"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, expected := range []string{"url:", "store-timeout:", "id-generator:", "on-conflict:", "auth-users:"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in:\n%v", expected, err)
+		}
+	}
+
+	if _, err := config.Load("-id-generator", "uuidv5"); err == nil || !strings.Contains(err.Error(), "id-generator:") {
+		t.Errorf("invalid configuration loaded: %v", err)
+	}
+	for _, kind := range uuid.Kinds() {
+		if _, err := config.Load("-id-generator", kind); err != nil {
+			t.Errorf("id generator %q: %v", kind, err)
+		}
+	}
+
+	// no one could create the API tokens
+	if _, err := config.Load("-auth"); err == nil || !strings.Contains(err.Error(), "auth-users:") {
+		t.Errorf("configuration locking everyone out loaded: %v", err)
+	}
+	if _, err := config.Load("-auth", "-auth-users", "admin:p4ssw0rd"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Redis.Password = "s3cr3t"
+	cfg.Auth.Users = "admin:p4ssw0rd"
+	out := cfg.String()
+	for _, secret := range []string{"s3cr3t", "p4ssw0rd"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret %q not redacted:\n%s", secret, out)
+		}
+	}
+}
+
+func TestParseUsers(t *testing.T) {
+	users, err := config.ParseUsers("admin:p4ss:w0rd,ci:secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"admin": "p4ss:w0rd", "ci": "secret"}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("got %v expected %v", users, expected)
+	}
+
+	for _, bad := range []string{"admin", "admin:", ":secret", "admin:a,admin:b"} {
+		_, err := config.ParseUsers(bad)
+		if err == nil {
+			t.Errorf("%q: expected error", bad)
+			continue
+		}
+		if strings.Contains(err.Error(), "secret") {
+			t.Errorf("%q: error discloses the password: %v", bad, err)
+		}
+	}
+}
+
+func TestWebhookAllowedHostList(t *testing.T) {
+	cfg, err := config.Load("-webhook-allowed-hosts", "hooks.example.com, ci.example.com,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"hooks.example.com", "ci.example.com"}
+	if got := cfg.Webhooks.AllowedHostList(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v got %v", expected, got)
+	}
+	if got := config.Defaults().Webhooks.AllowedHostList(); got != nil {
+		t.Errorf("expected no restriction got %v", got)
+	}
+}
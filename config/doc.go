@@ -1,3 +1,5 @@
 // Package config holds all the tunables and knows how
-// to set them and merge the configuration sources
+// to set them and merge the configuration sources:
+// the compiled-in defaults, a YAML configuration file, the TODO_* environment
+// variables and the command line flags, each overriding the previous ones.
 package config
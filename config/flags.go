@@ -1,30 +1,101 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/uuid"
 )
 
-// FromFlags creates a Config object out of the command line args
-// If succesfull, returns the resulting Config; otherwise returns
-// a zero-valued Config and the error describing the failure.
-func FromFlags(args ...string) (Config, error) {
+// configFlag is the flag, and the key, naming the configuration file
+const configFlag = "config"
+
+// Load creates a Config object merging, in order of precedence, the compiled-in defaults,
+// the configuration file, the environment variables and the command line args: each source
+// overrides the previous ones. The configuration file is named by the -config flag or the
+// TODO_CONFIG environment variable. The keys of the file and the environment variables
+// are named after the flags, see EnvName.
+// If succesfull, returns the resulting, validated Config; otherwise returns
+// a zero-valued Config and the error describing the failure, which is flag.ErrHelp
+// if the usage was requested and printed.
+func Load(args ...string) (Config, error) {
+	// the first pass reports the bad flags, and finds the configuration file
+	var scratch Config
+	flags := newFlagSet(&scratch, os.Stderr)
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	path := scratch.ConfigFile
+	if !isSet(flags, configFlag) {
+		path = os.Getenv(EnvName(configFlag))
+	}
+
 	conf := Defaults()
+	conf.ConfigFile = path
+	flags = newFlagSet(&conf, io.Discard)
+	var errs []error
+	if path != "" {
+		if err := fromFile(flags, path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := fromEnv(flags, os.Environ()); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+	// the flags were already checked by the first pass
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if err := conf.Validate(); err != nil {
+		return Config{}, err
+	}
+	return conf, nil
+}
 
+// newFlagSet creates the flags setting the fields of the given Config, keeping their current values as default
+func newFlagSet(conf *Config, output io.Writer) *flag.FlagSet {
 	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&conf.ConfigFile, configFlag, conf.ConfigFile, "YAML configuration file, whose keys are the flag names")
 	flags.StringVar(&conf.Address, "url", conf.Address, "url to listen to")
+	flags.DurationVar(&conf.ShutdownGrace, "shutdown-grace", conf.ShutdownGrace, "how long the in-flight requests have to complete on shutdown")
 	flags.StringVar(&conf.Redis.URL, "redis-url", conf.Redis.URL, "redis URL")
 	flags.StringVar(&conf.Redis.Password, "redis-password", conf.Redis.Password, "redis password")
 	flags.IntVar(&conf.Redis.Database, "redis-database", conf.Redis.Database, "redis database index")
+	flags.StringVar(&conf.Redis.Prefix, "redis-prefix", conf.Redis.Prefix, "prefix of the redis keys of the objects, e.g. todo: to share the database; changing it hides the existing objects")
+	flags.BoolVar(&conf.Auth.Enabled, "auth", conf.Auth.Enabled, "require authentication, by API token or basic auth, on the routes changing data")
+	flags.StringVar(&conf.Auth.Users, "auth-users", conf.Auth.Users, "static basic auth users, as comma separated name:password pairs")
+	flags.StringVar(&conf.Webhooks.AllowedHosts, "webhook-allowed-hosts", conf.Webhooks.AllowedHosts, "comma separated hosts the webhooks can target; empty means any")
+	flags.DurationVar(&conf.StoreTimeout, "store-timeout", conf.StoreTimeout, "bound of each operation on the store, 0 means no bound")
+	flags.StringVar(&conf.IDGenerator, "id-generator", conf.IDGenerator, "kind of generator of the IDs of new objects: "+strings.Join(uuid.Kinds(), ", "))
+	flags.StringVar(&conf.DataDir, "data-dir", conf.DataDir, "directory of the file-backed store (ignored if redis-url is set)")
+	flags.BoolVar(&conf.DryRun, "dry-run", conf.DryRun, "migrate and import subcommands only: report the changes without applying them")
+	flags.StringVar(&conf.BackupFile, "file", conf.BackupFile, "export and import subcommands only: JSON Lines backup file, - for stdout/stdin")
+	flags.StringVar(&conf.OnConflict, "on-conflict", conf.OnConflict, "import subcommand only: what to do with the todos already existing: skip, overwrite or fail")
 
 	flags.Usage = func() {
 		w := flags.Output()
-		fmt.Fprintf(w, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(w, "Usage of %s [migrate|export|import]:\n", os.Args[0])
 		flags.PrintDefaults()
-		os.Exit(0)
+		fmt.Fprintf(w, "Every flag can be set also by the environment variable %s<FLAG_NAME>, e.g. %s.\n", EnvPrefix, EnvName("redis-url"))
 	}
+	return flags
+}
 
-	err := flags.Parse(args)
-	return conf, err
+// isSet tells if the named flag was set on the command line
+func isSet(flags *flag.FlagSet, name string) bool {
+	found := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
 }
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables setting the configuration
+const EnvPrefix = "TODO_"
+
+// EnvName returns the environment variable corresponding to a flag, e.g. TODO_REDIS_URL for -redis-url
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// fromFile sets the given flags from a YAML file, whose keys are the flag names
+// and whose values are scalars, written as on the command line, e.g.
+//
+//	redis-url: localhost:6379
+//	store-timeout: 2s
+//
+// Unknown keys are errors. Returns all the errors found, joined.
+func fromFile(flags *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		// empty file
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s:%d: expected a mapping of flag names to values", path, root.Line)
+	}
+	var errs []error
+	for idx := 0; idx+1 < len(root.Content); idx += 2 {
+		key, value := root.Content[idx], root.Content[idx+1]
+		switch {
+		case key.Value == configFlag:
+			errs = append(errs, fmt.Errorf("%s:%d: %q can't be set by the configuration file", path, key.Line, key.Value))
+		case flags.Lookup(key.Value) == nil:
+			errs = append(errs, fmt.Errorf("%s:%d: unknown key %q", path, key.Line, key.Value))
+		case value.Kind != yaml.ScalarNode:
+			errs = append(errs, fmt.Errorf("%s:%d: %q: expected a scalar value", path, value.Line, key.Value))
+		default:
+			if err := flags.Set(key.Value, value.Value); err != nil {
+				errs = append(errs, fmt.Errorf("%s:%d: %q: invalid value %q: %w", path, value.Line, key.Value, value.Value, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fromEnv sets the given flags from the environment variables named after them, see EnvName.
+// environ is in the format of os.Environ. Unknown variables having EnvPrefix are logged
+// and ignored, since the prefix may be shared with unrelated software.
+// Returns all the errors found, joined.
+func fromEnv(flags *flag.FlagSet, environ []string) error {
+	names := make(map[string]string)
+	flags.VisitAll(func(f *flag.Flag) {
+		names[EnvName(f.Name)] = f.Name
+	})
+	var errs []error
+	environ = append([]string(nil), environ...)
+	sort.Strings(environ)
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		name, ok := names[key]
+		if !ok {
+			log.Printf("config: ignoring unknown environment variable %s", key)
+			continue
+		}
+		if name == configFlag {
+			// already used to find the configuration file
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", key, value, err))
+		}
+	}
+	return errors.Join(errs...)
+}
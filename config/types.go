@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/uuid"
 )
 
 // RedisConfig holds all the redis-related tunables
@@ -10,29 +13,126 @@ type RedisConfig struct {
 	URL      string
 	Password string
 	Database int
+	// Prefix is prepended to the keys of the objects, so the database can be shared.
+	// Empty by default, as the keys written before it was introduced.
+	Prefix string
+}
+
+// AuthConfig holds the authentication tunables
+type AuthConfig struct {
+	// Enabled rejects the anonymous requests to the non public routes
+	Enabled bool
+	// Users are the static basic auth users, as comma separated name:password pairs, see ParseUsers
+	Users string
+}
+
+// WebhookConfig holds the webhook-related tunables
+type WebhookConfig struct {
+	// AllowedHosts, if not empty, are the only hosts the webhooks can target, comma separated
+	AllowedHosts string
 }
 
 // Config holds all the tunables
 type Config struct {
+	// ConfigFile is the configuration file the Config was loaded from, if any
+	ConfigFile string
 	// Address is in the format `[host]:port`
 	Address string
-	Redis   RedisConfig
+	// ShutdownGrace is how long the in-flight requests have to complete on shutdown
+	ShutdownGrace time.Duration
+	Redis         RedisConfig
+	Auth          AuthConfig
+	Webhooks      WebhookConfig
+	// DataDir is the directory holding the durable file-backed store, if enabled
+	DataDir string
+	// StoreTimeout bounds each operation on the store; zero means no bound
+	StoreTimeout time.Duration
+	// IDGenerator is the kind of generator of the IDs of new objects
+	IDGenerator string
+	// DryRun makes the migrate and import subcommands report the changes without applying them
+	DryRun bool
+	// BackupFile is the file written by the export subcommand and read by the import one; "-" means stdout/stdin
+	BackupFile string
+	// OnConflict is the import policy for the todos already existing: skip, overwrite or fail
+	OnConflict string
 }
 
+// redacted replaces the secrets when printing the configuration
+const redacted = "<redacted>"
+
+// String returns the configuration in a human friendly format, with the secrets redacted.
 func (cfg Config) String() string {
 	var sb strings.Builder
+	fmt.Fprintf(&sb, "- config file: %q\n", cfg.ConfigFile)
 	fmt.Fprintf(&sb, "- address: %s\n", cfg.Address)
+	fmt.Fprintf(&sb, "- shutdown grace: %v\n", cfg.ShutdownGrace)
 	fmt.Fprintf(&sb, "- redis:\n")
 	fmt.Fprintf(&sb, "  - url:  %q\n", cfg.Redis.URL)
-	fmt.Fprintf(&sb, "  - pass: %q\n", cfg.Redis.Password)
+	fmt.Fprintf(&sb, "  - pass: %q\n", redact(cfg.Redis.Password))
 	fmt.Fprintf(&sb, "  - db:   %d\n", cfg.Redis.Database)
+	fmt.Fprintf(&sb, "  - prefix: %q\n", cfg.Redis.Prefix)
+	fmt.Fprintf(&sb, "- auth:\n")
+	fmt.Fprintf(&sb, "  - enabled: %v\n", cfg.Auth.Enabled)
+	fmt.Fprintf(&sb, "  - users:   %q\n", redact(cfg.Auth.Users))
+	fmt.Fprintf(&sb, "- webhooks:\n")
+	fmt.Fprintf(&sb, "  - allowed hosts: %q\n", cfg.Webhooks.AllowedHosts)
+	fmt.Fprintf(&sb, "- data dir: %q\n", cfg.DataDir)
+	fmt.Fprintf(&sb, "- store timeout: %v\n", cfg.StoreTimeout)
+	fmt.Fprintf(&sb, "- id generator: %s\n", cfg.IDGenerator)
+	fmt.Fprintf(&sb, "- dry run: %v\n", cfg.DryRun)
+	fmt.Fprintf(&sb, "- backup file: %q\n", cfg.BackupFile)
+	fmt.Fprintf(&sb, "- on conflict: %s\n", cfg.OnConflict)
 	return sb.String()
 }
 
 // Defaults return a Config initialized with the compiled-in defaults
 func Defaults() Config {
 	return Config{
-		Address: "localhost:8181",
-		Redis:   RedisConfig{},
+		Address:       "localhost:8181",
+		ShutdownGrace: 10 * time.Second,
+		StoreTimeout:  5 * time.Second,
+		IDGenerator:   uuid.KindUUIDv4,
+		BackupFile:    "-",
+		OnConflict:    "fail",
+	}
+}
+
+// ParseUsers parses the static basic auth users, given as comma separated name:password pairs,
+// returning their passwords keyed by name.
+func ParseUsers(users string) (map[string]string, error) {
+	passwords := make(map[string]string)
+	if users == "" {
+		return passwords, nil
+	}
+	for i, pair := range strings.Split(users, ",") {
+		name, password, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || password == "" {
+			// not to disclose the password, reports the position only
+			return nil, fmt.Errorf("user #%d: expected name:password", i+1)
+		}
+		if _, ok := passwords[name]; ok {
+			return nil, fmt.Errorf("user #%d: duplicate name %q", i+1, name)
+		}
+		passwords[name] = password
+	}
+	return passwords, nil
+}
+
+// AllowedHostList returns the hosts the webhooks can target, empty if any
+func (wc WebhookConfig) AllowedHostList() []string {
+	var hosts []string
+	for _, host := range strings.Split(wc.AllowedHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// redact hides a secret, telling only if it is set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
 	}
+	return redacted
 }
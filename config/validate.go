@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+var onConflicts = []string{"skip", "overwrite", "fail"}
+
+// Validate checks the configuration, returning all the problems found, joined.
+func (cfg Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		errs = append(errs, fmt.Errorf("url: %w", err))
+	}
+	if cfg.ShutdownGrace < 0 {
+		errs = append(errs, fmt.Errorf("shutdown-grace: must not be negative, got %v", cfg.ShutdownGrace))
+	}
+	if cfg.Redis.Database < 0 {
+		errs = append(errs, fmt.Errorf("redis-database: must not be negative, got %d", cfg.Redis.Database))
+	}
+	if _, err := ParseUsers(cfg.Auth.Users); err != nil {
+		errs = append(errs, fmt.Errorf("auth-users: %w", err))
+	} else if cfg.Auth.Enabled && cfg.Auth.Users == "" {
+		// only they can create the API tokens
+		errs = append(errs, errors.New("auth-users: must not be empty when auth is enabled"))
+	}
+	if cfg.StoreTimeout < 0 {
+		errs = append(errs, fmt.Errorf("store-timeout: must not be negative, got %v", cfg.StoreTimeout))
+	}
+	if !oneOf(cfg.IDGenerator, uuid.Kinds()) {
+		errs = append(errs, fmt.Errorf("id-generator: unknown kind %q, expected one of %v", cfg.IDGenerator, uuid.Kinds()))
+	}
+	if cfg.BackupFile == "" {
+		errs = append(errs, errors.New("file: must not be empty, use - for stdout/stdin"))
+	}
+	if !oneOf(cfg.OnConflict, onConflicts) {
+		errs = append(errs, fmt.Errorf("on-conflict: unknown policy %q, expected one of %v", cfg.OnConflict, onConflicts))
+	}
+	return errors.Join(errs...)
+}
+
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/backup"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// maxImportSize is the largest backup accepted by AdminImport
+const maxImportSize = 64 * 1024 * 1024
+
+/*
+AdminExport streams a snapshot of all the todos as JSON Lines, see the backup package,
+while the server keeps serving. Test with this curl command:
+
+curl -u admin:password -o todos.jsonl http://localhost:8080/admin/export
+*/
+func (ctrl *Controller) AdminExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.jsonl"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := backup.Export(w, ctrl.ld); err != nil {
+		// too late to report it to the client, which gets a truncated backup
+		log.Printf("API: export failed: %v", err)
+	}
+}
+
+/*
+AdminImport imports the todos of a JSON Lines backup, see the backup package.
+The onConflict parameter tells what to do with the todos already existing: skip,
+overwrite or fail (the default); dryRun=true reports what would be imported.
+Imported todos are recorded in the history and published as import events. Test with this curl command:
+
+curl -u admin:password --data-binary @todos.jsonl "http://localhost:8080/admin/import?onConflict=skip&dryRun=true"
+*/
+func (ctrl *Controller) AdminImport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	policy := backup.PolicyFail
+	if value := query.Get("onConflict"); value != "" {
+		var err error
+		if policy, err = backup.ParsePolicy(value); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	dryRun := false
+	if value := query.Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			sendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	report, err := backup.Import(r.Context(), http.MaxBytesReader(w, r.Body, maxImportSize), ctrl.ld, policy, dryRun,
+		actorFromRequest(r), func(todoID store.ID, todo model.Todo) { ctrl.publish(apiv1.OpImport, todoID, todo) })
+	if err != nil {
+		code := http.StatusInternalServerError
+		var validationErr *backup.ValidationError
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			code = http.StatusRequestEntityTooLarge
+		case errors.Is(err, backup.ErrConflict):
+			code = http.StatusConflict
+		case errors.As(err, &validationErr):
+			code = http.StatusUnprocessableEntity
+		}
+		sendError(w, code, err)
+		return
+	}
+
+	apiReport := report.ToAPIv1()
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Import: &apiReport,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
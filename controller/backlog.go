@@ -4,26 +4,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
-	"github.com/gotestbootcamp/go-todo-app/model"
 )
 
 func (ctrl *Controller) BacklogIndex(w http.ResponseWriter, r *http.Request) {
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
-		return todo.IsOngoing()
-	})
+	pg, err := pageFromRequest(r)
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, next, code, err := ctrl.listTodos(withLabels(backlogQuery(""), r.URL.Query()["label"]), pg)
+	if err != nil {
+		sendError(w, code, err)
 		return
 	}
 
 	resp := apiv1.Response{
 		Status: apiv1.ResponseSuccess,
 		Result: &apiv1.Result{
-			Items: items.ToAPIv1(),
+			Items:      items.ToAPIv1(),
+			NextCursor: next,
 		},
 	}
 
@@ -41,18 +45,75 @@ func (ctrl *Controller) BacklogAssigned(w http.ResponseWriter, r *http.Request)
 		sendError(w, http.StatusInternalServerError, fmt.Errorf("missing assignee"))
 		return
 	}
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
-		return todo.IsOngoing() && todo.Assignee == assignee
-	})
+	pg, err := pageFromRequest(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, next, code, err := ctrl.listTodos(withLabels(backlogQuery(assignee), r.URL.Query()["label"]), pg)
+	if err != nil {
+		sendError(w, code, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items:      items.ToAPIv1(),
+			NextCursor: next,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// OverdueIndex lists the ongoing todos whose due time is past, the ones due earlier first
+func (ctrl *Controller) OverdueIndex(w http.ResponseWriter, r *http.Request) {
+	pg, err := pageFromRequest(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if pg.Sort == "" {
+		pg.Sort = sortByDue
+	}
+	items, next, code, err := ctrl.listTodos(overdueQuery(time.Now(), ""), pg)
+	if err != nil {
+		sendError(w, code, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items:      items.ToAPIv1(),
+			NextCursor: next,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// LabelIndex lists all the labels in use, with the number of todos having them
+func (ctrl *Controller) LabelIndex(w http.ResponseWriter, r *http.Request) {
+	labels, code, err := ctrl.listLabels()
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
 	resp := apiv1.Response{
 		Status: apiv1.ResponseSuccess,
 		Result: &apiv1.Result{
-			Items: items.ToAPIv1(),
+			Labels: labels,
 		},
 	}
 
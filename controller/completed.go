@@ -8,22 +8,25 @@ import (
 	"github.com/gorilla/mux"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
-	"github.com/gotestbootcamp/go-todo-app/model"
 )
 
 func (ctrl *Controller) CompletedIndex(w http.ResponseWriter, r *http.Request) {
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
-		return todo.Status == apiv1.Completed
-	})
+	pg, err := pageFromRequest(r)
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, next, code, err := ctrl.listTodos(completedQuery(""), pg)
+	if err != nil {
+		sendError(w, code, err)
 		return
 	}
 
 	resp := apiv1.Response{
 		Status: apiv1.ResponseSuccess,
 		Result: &apiv1.Result{
-			Items: items.ToAPIv1(),
+			Items:      items.ToAPIv1(),
+			NextCursor: next,
 		},
 	}
 
@@ -41,18 +44,22 @@ func (ctrl *Controller) CompletedAssigned(w http.ResponseWriter, r *http.Request
 		sendError(w, http.StatusInternalServerError, fmt.Errorf("missing assignee"))
 		return
 	}
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
-		return todo.Status == apiv1.Completed && todo.Assignee == assignee
-	})
+	pg, err := pageFromRequest(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, next, code, err := ctrl.listTodos(completedQuery(assignee), pg)
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
 	resp := apiv1.Response{
 		Status: apiv1.ResponseSuccess,
 		Result: &apiv1.Result{
-			Items: items.ToAPIv1(),
+			Items:      items.ToAPIv1(),
+			NextCursor: next,
 		},
 	}
 
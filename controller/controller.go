@@ -1,22 +1,65 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/feed"
+	"github.com/gotestbootcamp/go-todo-app/filter"
 	"github.com/gotestbootcamp/go-todo-app/ledger"
 	"github.com/gotestbootcamp/go-todo-app/middleware"
 	"github.com/gotestbootcamp/go-todo-app/uuid"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
 )
 
 type Controller struct {
-	router  *mux.Router
-	ld      *ledger.Ledger
-	uuidGen uuid.UUIDGenerator
+	router   *mux.Router
+	ld       *ledger.Ledger
+	uuidGen  uuid.UUIDGenerator
+	rpc      map[string]rpcMethod
+	events   *feed.Broker
+	webhooks *webhook.Dispatcher
+	tokens   *auth.Tokens
+	authn    *middleware.Authenticator
+}
+
+// Option customizes a Controller
+type Option func(ctrl *Controller)
+
+// WithWebhooks enables the webhook subscriptions, managed by the given dispatcher.
+// Without it, the webhook routes fail with 501 Not Implemented.
+func WithWebhooks(dispatcher *webhook.Dispatcher) Option {
+	return func(ctrl *Controller) {
+		ctrl.webhooks = dispatcher
+	}
+}
+
+// WithEvents publishes the change feed on the given broker, e.g. to end the event
+// streams on shutdown closing it. Without it, the controller creates its own broker.
+func WithEvents(broker *feed.Broker) Option {
+	return func(ctrl *Controller) {
+		ctrl.events = broker
+	}
+}
+
+// WithAuth enables the authentication of the requests, by the given API tokens and static users:
+// the anonymous requests are served only by the public routes, and the admin routes, like the
+// webhooks ones, serve only the static users. Without it, the admin routes fail with 501 Not Implemented.
+func WithAuth(tokens *auth.Tokens, users auth.Users) Option {
+	return func(ctrl *Controller) {
+		ctrl.tokens = tokens
+		ctrl.authn = &middleware.Authenticator{Tokens: tokens, Users: users}
+	}
 }
 
 type Route struct {
@@ -24,44 +67,72 @@ type Route struct {
 	Method  string
 	Pattern string
 	Handler http.HandlerFunc
+	// Access tells which requests the route serves, when the authentication is enabled
+	Access middleware.Access
 }
 
-func New(ld *ledger.Ledger) http.Handler {
+// New creates a new Controller serving the API, using the given ledger and
+// creating the IDs of the new objects with the given generator.
+func New(ld *ledger.Ledger, uuidGen uuid.UUIDGenerator, opts ...Option) http.Handler {
 	ctrl := Controller{
 		ld:      ld,
-		uuidGen: uuid.New(),
+		uuidGen: uuidGen,
 		router:  mux.NewRouter().StrictSlash(true),
+		events:  feed.NewBroker(feed.DefaultBufferSize),
+	}
+	for _, opt := range opts {
+		opt(&ctrl)
 	}
+	ctrl.rpc = ctrl.rpcMethods()
 	routes := []Route{
 		Route{
 			Name:    "backlog.index",
 			Method:  "GET",
 			Pattern: "/backlog",
 			Handler: ctrl.BacklogIndex,
+			Access:  middleware.Public,
 		},
 		Route{
 			Name:    "backlog.assigned",
 			Method:  "GET",
 			Pattern: "/backlog/{assignee}",
 			Handler: ctrl.BacklogAssigned,
+			Access:  middleware.Public,
+		},
+		Route{
+			Name:    "overdue.index",
+			Method:  "GET",
+			Pattern: "/overdue",
+			Handler: ctrl.OverdueIndex,
+			Access:  middleware.Public,
+		},
+		Route{
+			Name:    "label.index",
+			Method:  "GET",
+			Pattern: "/labels",
+			Handler: ctrl.LabelIndex,
+			Access:  middleware.Public,
 		},
 		Route{
 			Name:    "completed.index",
 			Method:  "GET",
 			Pattern: "/completed",
 			Handler: ctrl.CompletedIndex,
+			Access:  middleware.Public,
 		},
 		Route{
 			Name:    "completed.byassignee",
 			Method:  "GET",
 			Pattern: "/completed/{assignee}",
 			Handler: ctrl.CompletedAssigned,
+			Access:  middleware.Public,
 		},
 		Route{
 			Name:    "todo.index",
 			Method:  "GET",
 			Pattern: "/todos",
 			Handler: ctrl.TodoIndex,
+			Access:  middleware.Public,
 		},
 		Route{
 			Name:    "todo.create",
@@ -74,6 +145,7 @@ func New(ld *ledger.Ledger) http.Handler {
 			Method:  "GET",
 			Pattern: "/todos/{todoID}",
 			Handler: ctrl.TodoShow,
+			Access:  middleware.Public,
 		},
 		// PUT is defined to assume idempotency, so if you PUT an object twice, it should have no additional effect.
 		Route{
@@ -82,7 +154,38 @@ func New(ld *ledger.Ledger) http.Handler {
 			Pattern: "/todos/{todoID}",
 			Handler: ctrl.TodoUpdate,
 		},
-		// you can complete a TODO just once
+		Route{
+			Name:    "todo.label",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/label",
+			Handler: ctrl.TodoLabel,
+		},
+		Route{
+			Name:    "todo.unlabel",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/unlabel",
+			Handler: ctrl.TodoUnlabel,
+		},
+		Route{
+			Name:    "todo.unassign",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/unassign",
+			Handler: ctrl.TodoUnassign,
+		},
+		Route{
+			Name:    "todo.reassign",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/reassign",
+			Handler: ctrl.TodoReassign,
+		},
+		// you can reopen a completed TODO, either assigned or back in the backlog
+		Route{
+			Name:    "todo.reopen",
+			Method:  "POST",
+			Pattern: "/todos/{todoID}/reopen",
+			Handler: ctrl.TodoReopen,
+		},
+		// you can complete a TODO just once, unless reopened
 		Route{
 			Name:    "todo.complete",
 			Method:  "POST",
@@ -96,16 +199,128 @@ func New(ld *ledger.Ledger) http.Handler {
 			Pattern: "/todos/{todoID}/delete",
 			Handler: ctrl.TodoDelete,
 		},
+		// the history is kept also for the todos merged into others
+		Route{
+			Name:    "todo.history",
+			Method:  "GET",
+			Pattern: "/todos/{todoID}/history",
+			Handler: ctrl.TodoHistory,
+			Access:  middleware.Public,
+		},
 		Route{
 			Name:    "todo.merge",
 			Method:  "POST",
 			Pattern: "/todomerge/{todoID1}/{todoID2}",
 			Handler: ctrl.TodoMerge,
 		},
+		// full-text search over the title and description of the todos
+		Route{
+			Name:    "search",
+			Method:  "GET",
+			Pattern: "/search",
+			Handler: ctrl.Search,
+			Access:  middleware.Public,
+		},
+		// Server-Sent Events stream of the changes of the todos
+		Route{
+			Name:    "events",
+			Method:  "GET",
+			Pattern: "/events",
+			Handler: ctrl.Events,
+			Access:  middleware.Public,
+		},
+		Route{
+			Name:    "webhook.index",
+			Method:  "GET",
+			Pattern: "/webhooks",
+			Handler: ctrl.WebhookIndex,
+			Access:  middleware.Admin,
+		},
+		Route{
+			Name:    "webhook.create",
+			Method:  "POST",
+			Pattern: "/webhooks",
+			Handler: ctrl.WebhookCreate,
+			Access:  middleware.Admin,
+		},
+		// must come before webhook.show
+		Route{
+			Name:    "webhook.deadletters",
+			Method:  "GET",
+			Pattern: "/webhooks/deadletters",
+			Handler: ctrl.WebhookDeadLetters,
+			Access:  middleware.Admin,
+		},
+		Route{
+			Name:    "webhook.show",
+			Method:  "GET",
+			Pattern: "/webhooks/{webhookID}",
+			Handler: ctrl.WebhookShow,
+			Access:  middleware.Admin,
+		},
+		Route{
+			Name:    "webhook.delete",
+			Method:  "POST",
+			Pattern: "/webhooks/{webhookID}/delete",
+			Handler: ctrl.WebhookDelete,
+			Access:  middleware.Admin,
+		},
+		// backup and restore of the todos, as JSON Lines
+		Route{
+			Name:    "admin.export",
+			Method:  "GET",
+			Pattern: "/admin/export",
+			Handler: ctrl.AdminExport,
+			Access:  middleware.Admin,
+		},
+		Route{
+			Name:    "admin.import",
+			Method:  "POST",
+			Pattern: "/admin/import",
+			Handler: ctrl.AdminImport,
+			Access:  middleware.Admin,
+		},
+		// management of the API tokens
+		Route{
+			Name:    "token.index",
+			Method:  "GET",
+			Pattern: "/admin/tokens",
+			Handler: ctrl.TokenIndex,
+			Access:  middleware.Admin,
+		},
+		Route{
+			Name:    "token.create",
+			Method:  "POST",
+			Pattern: "/admin/tokens",
+			Handler: ctrl.TokenCreate,
+			Access:  middleware.Admin,
+		},
+		Route{
+			Name:    "token.revoke",
+			Method:  "POST",
+			Pattern: "/admin/tokens/{tokenID}/revoke",
+			Handler: ctrl.TokenRevoke,
+			Access:  middleware.Admin,
+		},
+		// JSON-RPC 2.0 endpoint, exposing the same operations of the other routes
+		Route{
+			Name:    "rpc",
+			Method:  "POST",
+			Pattern: "/rpc",
+			Handler: ctrl.RPC,
+		},
 	}
 
 	for _, route := range routes {
-		ctrl.router.Methods(route.Method).Path(route.Pattern).Name(route.Name).Handler(middleware.Logger(route.Handler, route.Name))
+		var handler http.Handler = route.Handler
+		switch {
+		case ctrl.authn != nil:
+			handler = middleware.Authenticate(handler, *ctrl.authn, route.Access)
+		case route.Access == middleware.Admin:
+			// without authentication, no one can be told a admin
+			handler = http.HandlerFunc(adminDisabled)
+		}
+		ctrl.router.Methods(route.Method).Path(route.Pattern).Name(route.Name).Handler(middleware.Logger(handler, route.Name))
 		log.Printf("API: method: %-8s route: %s", route.Method, route.Pattern)
 	}
 	return &ctrl
@@ -116,20 +331,66 @@ func (ctrl *Controller) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 }
 
 func sendError(w http.ResponseWriter, code int, err error) {
+	code = errorCode(code, err)
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(code)
 	resp := apiv1.Response{
 		Status: apiv1.ResponseError,
-		Error: &apiv1.Error{
-			Code: code,
-			Text: err.Error(),
-		},
+		Error:  apiError(code, err),
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		panic(err)
 	}
 }
 
+// statusClientClosedRequest is the non standard status code, made popular by nginx,
+// of the requests whose client went away before the response
+const statusClientClosedRequest = 499
+
+// errorCode returns the status code of a failure: the given one, unless the failure
+// is the request context being canceled, or its deadline being exceeded.
+func errorCode(code int, err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return code
+}
+
+// apiError converts a error on its API layer corresponding object
+func apiError(code int, err error) *apiv1.Error {
+	apiErr := apiv1.Error{
+		Code: code,
+		Text: err.Error(),
+	}
+	var paramErr paramError
+	var syntaxErr *filter.SyntaxError
+	if errors.As(err, &paramErr) && errors.As(err, &syntaxErr) {
+		apiErr.Location = &apiv1.ErrorLocation{
+			Parameter: paramErr.Parameter,
+			Offset:    syntaxErr.Offset,
+			Length:    syntaxErr.Length,
+		}
+	}
+	return &apiErr
+}
+
+// paramError is a error caused by the value of a request parameter
+type paramError struct {
+	Parameter string
+	Err       error
+}
+
+func (e paramError) Error() string {
+	return fmt.Sprintf("parameter %q: %v", e.Parameter, e.Err)
+}
+
+func (e paramError) Unwrap() error {
+	return e.Err
+}
+
 func sendItem(w http.ResponseWriter, id apiv1.ID, todo *apiv1.Todo) {
 	resp := apiv1.Response{
 		Status: apiv1.ResponseSuccess,
@@ -149,3 +410,26 @@ func sendItem(w http.ResponseWriter, id apiv1.ID, todo *apiv1.Todo) {
 		panic(err)
 	}
 }
+
+// etag returns the entity tag corresponding to a object revision
+func etag(revision uint64) string {
+	return strconv.Quote(strconv.FormatUint(revision, 10))
+}
+
+// revisionFromRequest returns the precondition on the object revision expressed by
+// the If-Match request header, if present; otherwise returns nil.
+func revisionFromRequest(r *http.Request) revisionMatch {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+	return func(revision uint64) bool {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == etag(revision) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,101 @@
+package controller_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestAdminExport(t *testing.T) {
+	ldg := memoryStorage()
+	handler := asAdmin(t, ldg, uuid.NewV4())
+	for _, id := range []store.ID{"b", "a"} {
+		if err := ldg.Set(context.Background(), id, model.New("todo "+string(id))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected code %d got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+	var ids []apiv1.ID
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var item apiv1.Item
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatal(err)
+		}
+		if item.Todo == nil || item.Todo.Title != "todo "+string(item.ID) {
+			t.Fatalf("unexpected item %+v", item)
+		}
+		ids = append(ids, item.ID)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("expected items a and b, got %v", ids)
+	}
+}
+
+func TestAdminImport(t *testing.T) {
+	backupData := `{"id":"a","todo":{"title":"imported a","status":"pending"}}
+{"id":"b","todo":{"title":"imported b","status":"pending"}}
+`
+	tests := []struct {
+		name     string
+		url      string
+		body     string
+		code     int
+		expected apiv1.ImportReport
+	}{
+		{"skip", "/admin/import?onConflict=skip", backupData, http.StatusOK, apiv1.ImportReport{Total: 2, Created: 1, Skipped: 1}},
+		{"overwrite", "/admin/import?onConflict=overwrite", backupData, http.StatusOK, apiv1.ImportReport{Total: 2, Created: 1, Overwritten: 1}},
+		{"dry run", "/admin/import?onConflict=skip&dryRun=true", backupData, http.StatusOK, apiv1.ImportReport{Total: 2, Created: 1, Skipped: 1}},
+		{"conflict", "/admin/import", backupData, http.StatusConflict, apiv1.ImportReport{}},
+		{"invalid", "/admin/import", `{"id":"c","todo":{"status":"sleeping"}}`, http.StatusUnprocessableEntity, apiv1.ImportReport{}},
+		{"invalid policy", "/admin/import?onConflict=merge", backupData, http.StatusBadRequest, apiv1.ImportReport{}},
+		{"invalid dry run", "/admin/import?dryRun=maybe", backupData, http.StatusBadRequest, apiv1.ImportReport{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ldg := memoryStorage()
+			handler := asAdmin(t, ldg, uuid.NewV4())
+			if err := ldg.Set(context.Background(), "a", model.New("existing a")); err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body)))
+			if w.Code != tc.code {
+				t.Fatalf("expected code %d got %d: %s", tc.code, w.Code, w.Body.String())
+			}
+			if tc.code != http.StatusOK {
+				return
+			}
+			var resp apiv1.Response
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Result == nil || resp.Result.Import == nil || *resp.Result.Import != tc.expected {
+				t.Fatalf("expected report %+v got %+v", tc.expected, resp.Result)
+			}
+			dryRun := strings.Contains(tc.url, "dryRun=true")
+			history, err := ldg.History(context.Background(), "b")
+			if imported := err == nil && len(history) == 1 && history[0].Operation == apiv1.OpImport; imported == dryRun {
+				t.Fatalf("unexpected history of b: %+v err=%v", history, err)
+			}
+		})
+	}
+}
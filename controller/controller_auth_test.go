@@ -0,0 +1,169 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+// asAdmin returns a controller with the authentication enabled, which serves all the
+// requests on behalf of a static user, for the tests of the admin routes.
+func asAdmin(t *testing.T, ldg *ledger.Ledger, uuidGen uuid.UUIDGenerator, opts ...controller.Option) http.Handler {
+	t.Helper()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokens(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := auth.NewUsers(map[string]string{"admin": "p4ssw0rd"})
+	handler := controller.New(ldg, uuidGen, append(opts, controller.WithAuth(tokens, users))...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.SetBasicAuth("admin", "p4ssw0rd")
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func TestAuth(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokens(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := auth.NewUsers(map[string]string{"admin": "p4ssw0rd"})
+	handler := controller.New(ldg, uuid.NewSequence(1), controller.WithAuth(tokens, users))
+
+	do := func(method, url string, body io.Reader, credentials func(r *http.Request), expectedCode int) apiv1.Response {
+		t.Helper()
+		r := httptest.NewRequest(method, url, body)
+		credentials(r)
+		// ignored for the authenticated requests
+		r.Header.Set(apiv1.ActorHeader, "mallory")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != expectedCode {
+			t.Fatalf("%s %s: expected %d got %d: %s", method, url, expectedCode, w.Code, w.Body.String())
+		}
+		var resp apiv1.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if expectedCode == http.StatusUnauthorized || expectedCode == http.StatusForbidden {
+			if resp.Status != apiv1.ResponseError || resp.Error == nil || resp.Error.Code != expectedCode {
+				t.Fatalf("%s %s: unexpected rejection: %+v", method, url, resp)
+			}
+			if challenged := w.Header().Get("WWW-Authenticate") != ""; challenged != (expectedCode == http.StatusUnauthorized) {
+				t.Fatalf("%s %s: unexpected authentication challenge: %v", method, url, challenged)
+			}
+		}
+		return resp
+	}
+	anonymous := func(r *http.Request) {}
+	basic := func(user, password string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, password) }
+	}
+	bearer := func(secret string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+secret) }
+	}
+	todo := func() io.Reader {
+		return bodyFromTodo(model.Todo{Title: "Sample Todo", Assignee: "Bob"})
+	}
+
+	// public routes serve the anonymous requests, but not the ones with bad credentials
+	do(http.MethodGet, "/todos", nil, anonymous, http.StatusOK)
+	do(http.MethodGet, "/todos", nil, basic("admin", "wrong"), http.StatusUnauthorized)
+	do(http.MethodGet, "/todos", nil, bearer("wrong"), http.StatusUnauthorized)
+	// the other routes require the authentication
+	do(http.MethodPost, "/todos", todo(), anonymous, http.StatusUnauthorized)
+	do(http.MethodGet, "/admin/tokens", nil, anonymous, http.StatusUnauthorized)
+	do(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"todo.list","id":1}`), anonymous, http.StatusUnauthorized)
+	do(http.MethodPost, "/admin/tokens", strings.NewReader(`{"name":""}`), basic("admin", "p4ssw0rd"), http.StatusUnprocessableEntity)
+
+	created := do(http.MethodPost, "/admin/tokens", strings.NewReader(`{"name":"ci"}`), basic("admin", "p4ssw0rd"), http.StatusCreated).Result.Tokens
+	if len(created) != 1 || created[0].Name != "ci" || created[0].Secret == "" {
+		t.Fatalf("unexpected created token: %+v", created)
+	}
+	secret := created[0].Secret
+	if blob := st.Blobs[store.Namespaced("token", string(created[0].ID))]; strings.Contains(string(blob), secret) {
+		t.Fatalf("secret stored in clear: %s", blob)
+	}
+	// the holders of the tokens are not administrators
+	do(http.MethodGet, "/admin/tokens", nil, bearer(secret), http.StatusForbidden)
+	do(http.MethodPost, "/admin/tokens", strings.NewReader(`{"name":"more"}`), bearer(secret), http.StatusForbidden)
+	do(http.MethodGet, "/admin/export", nil, bearer(secret), http.StatusForbidden)
+	do(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"http://127.0.0.1:6379/"}`), bearer(secret), http.StatusForbidden)
+	listed := do(http.MethodGet, "/admin/tokens", nil, basic("admin", "p4ssw0rd"), http.StatusOK).Result.Tokens
+	if len(listed) != 1 || listed[0].ID != created[0].ID || listed[0].Secret != "" {
+		t.Fatalf("unexpected listed tokens: %+v", listed)
+	}
+
+	// the principal is the actor, whatever the header says
+	items := do(http.MethodPost, "/todos", todo(), bearer(secret), http.StatusCreated).Result.Items
+	if len(items) != 1 {
+		t.Fatalf("unexpected created todo: %+v", items)
+	}
+	history := do(http.MethodGet, "/todos/"+string(items[0].ID)+"/history", nil, anonymous, http.StatusOK).Result.History
+	if len(history) != 1 || history[0].Actor != "ci" {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	// a store failure is not a unknown token
+	st.Error = errors.New("store unavailable")
+	do(http.MethodPost, "/admin/tokens/"+string(created[0].ID)+"/revoke", nil, basic("admin", "p4ssw0rd"), http.StatusInternalServerError)
+	st.Error = nil
+	do(http.MethodPost, "/admin/tokens/"+string(created[0].ID)+"/revoke", nil, basic("admin", "p4ssw0rd"), http.StatusOK)
+	do(http.MethodPost, "/todos", todo(), bearer(secret), http.StatusUnauthorized)
+	do(http.MethodPost, "/admin/tokens/"+string(created[0].ID)+"/revoke", nil, basic("admin", "p4ssw0rd"), http.StatusNotFound)
+}
+
+func TestAdminWithoutAuth(t *testing.T) {
+	ldg := memoryStorage()
+	if err := ldg.Set(context.Background(), "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+	handler := controller.New(ldg, uuid.NewSequence(1))
+	for _, tc := range []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{http.MethodGet, "/admin/tokens", ""},
+		{http.MethodGet, "/admin/export", ""},
+		{http.MethodPost, "/admin/import?onConflict=overwrite", `{"id":"1","todo":{"title":"bar","status":"pending"}}`},
+		{http.MethodGet, "/webhooks", ""},
+		{http.MethodPost, "/webhooks", `{"url":"http://127.0.0.1:6379/"}`},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)))
+		if w.Code != http.StatusNotImplemented {
+			t.Fatalf("%s %s: expected code %d got %d: %s", tc.method, tc.url, http.StatusNotImplemented, w.Code, w.Body.String())
+		}
+	}
+	if todo, err := ldg.Get("1"); err != nil || todo.Title != "foo" {
+		t.Fatalf("todo changed without authentication: %v err=%v", todo, err)
+	}
+}
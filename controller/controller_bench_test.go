@@ -2,6 +2,7 @@ package controller
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -17,7 +18,7 @@ func BenchmarkTodoFromRequest(b *testing.B) {
 	_ = todo.Assign("fede")
 	_ = todo.Describe("hello")
 
-	serialized, err := todo.Serialize()
+	serialized, err := json.Marshal(todo.ToAPIv1())
 	if err != nil {
 		panic("")
 	}
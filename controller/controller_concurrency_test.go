@@ -0,0 +1,82 @@
+package controller_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+// run with `go test -race` to make the most out of this test
+
+func TestConcurrentRequests(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+
+	numTodos := 8
+	for i := 0; i < numTodos; i++ {
+		if err := ldg.Set(context.Background(), todoID(i), model.New(fmt.Sprintf("todo#%d", i))); err != nil {
+			t.Fatalf("failed to setup the ledger: %v", err)
+		}
+	}
+
+	requests := []func(i int) *http.Request{
+		func(i int) *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/todos", nil)
+		},
+		func(i int) *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/backlog", nil)
+		},
+		func(i int) *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/todos/"+string(todoID(i)), nil)
+		},
+		func(i int) *http.Request {
+			return httptest.NewRequest(http.MethodPut, "/todos/"+string(todoID(i)), bodyFromTodo(model.Todo{Assignee: "fede", Description: "updated"}))
+		},
+		func(i int) *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/todos/"+string(todoID(i))+"/complete", bodyFromTodo(model.Todo{}))
+		},
+		func(i int) *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/backlog/fede", nil)
+		},
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for iter := 0; iter < 50; iter++ {
+				makeRequest := requests[(worker+iter)%len(requests)]
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, makeRequest((worker*iter)%numTodos))
+				if w.Code >= http.StatusInternalServerError {
+					t.Errorf("unexpected failure: %d %s", w.Code, w.Body.String())
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	for i := 0; i < numTodos; i++ {
+		todo, err := ldg.Get(todoID(i))
+		if err != nil {
+			t.Fatalf("todo %v lost: %v", todoID(i), err)
+		}
+		if todo.Status != apiv1.Completed && todo.Status != apiv1.Assigned && todo.Status != apiv1.Pending {
+			t.Errorf("todo %v has unexpected status %v", todoID(i), todo.Status)
+		}
+	}
+}
+
+func todoID(i int) store.ID {
+	return store.ID(fmt.Sprintf("todo-%d", i))
+}
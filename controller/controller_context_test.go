@@ -0,0 +1,91 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+// stalledStorage blocks its write operations until their context is done
+type stalledStorage struct {
+	store.Storage
+}
+
+func (st stalledStorage) Create(ctx context.Context, id store.ID, blob store.Blob) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (st stalledStorage) Save(ctx context.Context, id store.ID, blob store.Blob) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestStoreTimeout(t *testing.T) {
+	mem, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(context.Background(), stalledStorage{Storage: mem}, ledger.WithTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := controller.New(ldg, uuid.NewV4())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/todos", bodyFromTodo(model.New("foo"))))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected code %d got %d: %s", http.StatusGatewayTimeout, w.Code, w.Body.String())
+	}
+
+	resp := decodeRPC(t, rpcRequest(t, handler, `{"jsonrpc":"2.0","method":"todo.create","params":{"todo":{"title":"foo"}},"id":1}`))
+	if resp.Error == nil || resp.Error.Data == nil || resp.Error.Data.Code != http.StatusGatewayTimeout {
+		t.Fatalf("unexpected rpc response: %+v", resp)
+	}
+}
+
+func TestRequestCanceled(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+	if err := ldg.Set(context.Background(), "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+	}{
+		{"create", http.MethodPost, "/todos", `{"title":"bar"}`},
+		{"update", http.MethodPut, "/todos/1", `{"description":"bar"}`},
+		{"history", http.MethodGet, "/todos/1/history", ``},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)).WithContext(ctx)
+			handler.ServeHTTP(w, req)
+			// the non standard 499 Client Closed Request
+			if w.Code != 499 {
+				t.Fatalf("expected code 499 got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+	todo, err := ldg.Get("1")
+	if err != nil || todo.Description != "" {
+		t.Fatalf("canceled update applied: %v err=%v", todo, err)
+	}
+}
@@ -2,6 +2,7 @@ package controller_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -13,12 +14,13 @@ import (
 	"github.com/gotestbootcamp/go-todo-app/ledger"
 	"github.com/gotestbootcamp/go-todo-app/model"
 	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
 )
 
 // exercise
 
 func TestTodoCreate(t *testing.T) {
-	handler := controller.New(memoryStorage())
+	handler := controller.New(memoryStorage(), uuid.NewSequence(1234))
 
 	t.Run("test post", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/todos", bodyFromTodo(model.Todo{Title: "foo", Assignee: "fede", Description: "todo"}))
@@ -37,8 +39,6 @@ func TestTodoCreate(t *testing.T) {
 			t.Fatalf("expecting one item back")
 		}
 
-		// TODO the uuid is generated interacting with a remote server
-		// make it deterministic
 		if apiRes.Result.Items[0].ID != "1234" {
 			t.Fatalf("expecting id 1234 got %s", apiRes.Result.Items[0].ID)
 		}
@@ -50,7 +50,7 @@ func memoryStorage() *ledger.Ledger {
 	if err != nil {
 		panic("failed to initialize the memory storage")
 	}
-	ldg, err := ledger.New(st)
+	ldg, err := ledger.New(context.Background(), st)
 	if err != nil {
 		panic("failed to initialize the ledger")
 	}
@@ -58,7 +58,7 @@ func memoryStorage() *ledger.Ledger {
 	return ldg
 }
 func bodyFromTodo(t model.Todo) io.Reader {
-	serialized, err := t.Serialize()
+	serialized, err := json.Marshal(t.ToAPIv1())
 	if err != nil {
 		panic("")
 	}
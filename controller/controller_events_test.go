@@ -0,0 +1,171 @@
+package controller_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/feed"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+type sseEvent struct {
+	id    string
+	name  string
+	event apiv1.Event
+}
+
+// readEvents parses the Server-Sent Events from the stream until it ends or count events are read
+func readEvents(srv *httptest.Server, query, lastEventID string, count int) ([]sseEvent, error) {
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/events"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		return nil, fmt.Errorf("unexpected content type %q", ct)
+	}
+
+	var events []sseEvent
+	var cur sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for len(events) < count && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, cur)
+			cur = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			cur.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			cur.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &cur.event); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return events, scanner.Err()
+}
+
+func TestEvents(t *testing.T) {
+	handler := controller.New(memoryStorage(), uuid.NewSequence(1))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	post := func(method, url string, body model.Todo) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+url, bodyFromTodo(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("%s %s failed: %d", method, url, resp.StatusCode)
+		}
+	}
+
+	live := make(chan []sseEvent)
+	go func() {
+		events, err := readEvents(srv, "?assignee=ana", "", 2)
+		if err != nil {
+			t.Error(err)
+		}
+		live <- events
+	}()
+	// give the subscriber the time to connect
+	time.Sleep(100 * time.Millisecond)
+
+	post(http.MethodPost, "/todos", model.Todo{Title: "first"})
+	post(http.MethodPost, "/todos", model.Todo{Title: "second"})
+	post(http.MethodPut, "/todos/1", model.Todo{Assignee: "ana"})
+	post(http.MethodPost, "/todos/1/complete", model.Todo{})
+
+	select {
+	case events := <-live:
+		if len(events) != 2 || events[0].name != "assign" || events[1].name != "complete" {
+			t.Fatalf("unexpected live events: %+v", events)
+		}
+		if events[1].event.Item.ID != "1" || events[1].event.Item.Todo.Status != apiv1.Completed {
+			t.Fatalf("unexpected completion event: %+v", events[1].event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("live events not received")
+	}
+
+	replay, err := readEvents(srv, "", "2", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay) != 2 || replay[0].id != "3" || replay[1].id != "4" {
+		t.Fatalf("unexpected replay: %+v", replay)
+	}
+	if replay[0].event.Seq != 3 || replay[0].event.Operation != apiv1.OpAssign {
+		t.Fatalf("unexpected replayed event: %+v", replay[0].event)
+	}
+
+	replay, err = readEvents(srv, "?status=pending", "42", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay) != 3 || replay[0].name != "reset" || replay[1].id != "1" || replay[2].id != "2" {
+		t.Fatalf("unexpected replay after reset: %+v", replay)
+	}
+}
+
+func TestEventsFollowRevisions(t *testing.T) {
+	ldg := memoryStorage()
+	if err := ldg.Set(context.Background(), "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+	events := feed.NewBroker(feed.DefaultBufferSize)
+	sub, _, _ := events.Subscribe(0)
+	defer sub.Cancel()
+	handler := controller.New(ldg, uuid.NewV4(), controller.WithEvents(events))
+
+	// concurrent changes of the same todo
+	const numUpdates = 32
+	var wg sync.WaitGroup
+	for i := 0; i < numUpdates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/todos/1", bodyFromTodo(model.Todo{Description: fmt.Sprint(i)})))
+			if w.Code != http.StatusCreated {
+				t.Errorf("unexpected code %d: %s", w.Code, w.Body.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var last uint64
+	for i := 0; i < numUpdates; i++ {
+		ev := <-sub.Events
+		if ev.Item.Todo.Revision <= last {
+			t.Fatalf("event %d: revision %d after %d", ev.Seq, ev.Item.Todo.Revision, last)
+		}
+		last = ev.Item.Todo.Revision
+	}
+}
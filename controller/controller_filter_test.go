@@ -0,0 +1,77 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestTodoIndexFilter(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+
+	todos := map[store.ID]model.Todo{
+		"a": {Title: "deploy backend", Status: apiv1.Assigned, Assignee: "ana"},
+		"b": {Title: "deploy frontend", Status: apiv1.Pending},
+		"c": {Title: "write docs", Status: apiv1.Assigned, Assignee: "ana"},
+		"d": {Title: "deploy docs", Status: apiv1.Completed, Assignee: "ana"},
+	}
+	for id, todo := range todos {
+		if err := ldg.Set(context.Background(), id, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q := `status in (pending, assigned) and assignee = "ana" and title ~ "deploy"`
+	resp := getPage(t, handler, "/todos?q="+url.QueryEscape(q))
+	if len(resp.Result.Items) != 1 || resp.Result.Items[0].ID != "a" {
+		t.Fatalf("unexpected items: %v", resp.Result.Items)
+	}
+
+	resp = getPage(t, handler, "/todos?sort=title&q="+url.QueryEscape("title ~ deploy or status = completed"))
+	var got []apiv1.ID
+	for _, item := range resp.Result.Items {
+		got = append(got, item.ID)
+	}
+	if len(got) != 3 || got[0] != "a" || got[1] != "d" || got[2] != "b" {
+		t.Fatalf("unexpected items: %v", got)
+	}
+}
+
+func TestTodoIndexFilterSyntaxError(t *testing.T) {
+	handler := controller.New(memoryStorage(), uuid.NewV4())
+
+	w := httptest.NewRecorder()
+	q := `status = pending and mood = happy`
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos?q="+url.QueryEscape(q), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected code %d got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var resp apiv1.Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	expected := apiv1.ErrorLocation{Parameter: "q", Offset: 21, Length: 4}
+	if resp.Error == nil || resp.Error.Location == nil || *resp.Error.Location != expected {
+		t.Fatalf("expected location %+v, got %+v", expected, resp.Error)
+	}
+
+	w = rpcRequest(t, handler, `{"jsonrpc":"2.0","id":1,"method":"todo.list","params":{"query":"title ="}}`)
+	var rpcResp apiv1.RPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&rpcResp); err != nil {
+		t.Fatal(err)
+	}
+	expected = apiv1.ErrorLocation{Parameter: "query", Offset: 7, Length: 0}
+	if rpcResp.Error == nil || rpcResp.Error.Data == nil || rpcResp.Error.Data.Location == nil || *rpcResp.Error.Data.Location != expected {
+		t.Fatalf("expected location %+v, got %+v", expected, rpcResp.Error)
+	}
+}
@@ -0,0 +1,151 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestTodoHistory(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewSequence(1))
+
+	do := func(method, url string, body model.Todo) apiv1.Response {
+		t.Helper()
+		req := httptest.NewRequest(method, url, bodyFromTodo(body))
+		req.Header.Set(apiv1.ActorHeader, "alice")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var resp apiv1.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != apiv1.ResponseSuccess {
+			t.Fatalf("%s %s failed: %+v", method, url, resp.Error)
+		}
+		return resp
+	}
+
+	do(http.MethodPost, "/todos", model.Todo{Title: "first"})
+	do(http.MethodPost, "/todos", model.Todo{Title: "second"})
+	do(http.MethodPut, "/todos/1", model.Todo{Description: "details"})
+	do(http.MethodPut, "/todos/2", model.Todo{Assignee: "bob"})
+	do(http.MethodPost, "/todos/2/label", model.Todo{Labels: []string{"infra"}})
+	do(http.MethodPost, "/todomerge/1/2", model.Todo{})
+
+	history := do(http.MethodGet, "/todos/2/history", model.Todo{}).Result.History
+	var ops []apiv1.Operation
+	for _, entry := range history {
+		ops = append(ops, entry.Operation)
+		if entry.Actor != "alice" {
+			t.Errorf("unexpected actor in %+v", entry)
+		}
+	}
+	expected := []apiv1.Operation{apiv1.OpCreate, apiv1.OpAssign, apiv1.OpLabel, apiv1.OpMerge}
+	if len(ops) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, ops)
+	}
+	for i := range expected {
+		if ops[i] != expected[i] {
+			t.Fatalf("expected %v got %v", expected, ops)
+		}
+	}
+	if history[1].Changes[0] != (apiv1.Change{Field: "assignee", After: "bob"}) {
+		t.Errorf("unexpected assign changes: %+v", history[1].Changes)
+	}
+	mergedInto := history[3].MergedInto
+	if mergedInto != "3" {
+		t.Fatalf("expected merged into 3 got %q", mergedInto)
+	}
+
+	merged := do(http.MethodGet, "/todos/"+string(mergedInto)+"/history", model.Todo{}).Result.History
+	if len(merged) != 1 || merged[0].Operation != apiv1.OpMerge {
+		t.Fatalf("unexpected merged history: %+v", merged)
+	}
+	if from := merged[0].MergedFrom; len(from) != 2 || from[0] != "1" || from[1] != "2" {
+		t.Fatalf("unexpected merge sources: %v", from)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/404/history", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected not found got %d", w.Code)
+	}
+}
+
+// historyFailingStorage fails the writes of the histories, and can't apply batches
+type historyFailingStorage struct {
+	store.Storage
+}
+
+var errHistoryWrite = errors.New("history write failed")
+
+func (st historyFailingStorage) Create(ctx context.Context, id store.ID, blob store.Blob) error {
+	if id.Namespace() != "" {
+		return errHistoryWrite
+	}
+	return st.Storage.Create(ctx, id, blob)
+}
+
+func (st historyFailingStorage) Save(ctx context.Context, id store.ID, blob store.Blob) error {
+	if id.Namespace() != "" {
+		return errHistoryWrite
+	}
+	return st.Storage.Save(ctx, id, blob)
+}
+
+func TestChangeWithoutHistoryFails(t *testing.T) {
+	mem, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.Create(context.Background(), "1", mustSerialize(t, model.New("foo"))); err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(context.Background(), historyFailingStorage{Storage: mem})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := controller.New(ldg, uuid.NewSequence(2))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/todos", bodyFromTodo(model.New("bar"))),
+		httptest.NewRequest(http.MethodPut, "/todos/1", bodyFromTodo(model.Todo{Assignee: "bob"})),
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s %s: expected code %d got %d: %s", req.Method, req.URL, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+		}
+	}
+	// neither in the ledger nor in the store
+	if _, err := ldg.Get("2"); err == nil {
+		t.Errorf("todo created without history")
+	}
+	if todo, err := ldg.Get("1"); err != nil || todo.Assignee != "" {
+		t.Errorf("todo changed without history: %+v %v", todo, err)
+	}
+	if len(mem.Blobs) != 1 {
+		t.Errorf("unexpected blobs stored: %v", mem.Blobs)
+	}
+}
+
+func mustSerialize(t *testing.T, todo model.Todo) store.Blob {
+	t.Helper()
+	blob, err := todo.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
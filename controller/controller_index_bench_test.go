@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+const (
+	benchTodos     = 100000
+	benchAssignees = 10000
+)
+
+var (
+	benchOnce sync.Once
+	benchCtrl *Controller
+)
+
+// benchController returns a controller over a ledger of benchTodos todos, spread among
+// benchAssignees assignees and all the statuses. The ledger is built once and shared.
+func benchController(b *testing.B) *Controller {
+	ctx := context.Background()
+	b.Helper()
+	// the ledger logs every operation: keep the output readable and the timings honest
+	prev := log.Writer()
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() {
+		log.SetOutput(prev)
+	})
+
+	benchOnce.Do(func() {
+		st, err := fake.NewMem()
+		if err != nil {
+			b.Fatal(err)
+		}
+		ldg, err := ledger.New(ctx, st)
+		if err != nil {
+			b.Fatal(err)
+		}
+		statuses := []apiv1.Status{apiv1.Pending, apiv1.Assigned, apiv1.Completed, apiv1.Deleted}
+		for idx := 0; idx < benchTodos; idx++ {
+			todo := model.Todo{
+				Title:    fmt.Sprintf("todo %d", idx),
+				Status:   statuses[idx%len(statuses)],
+				Assignee: fmt.Sprintf("user%d", idx%benchAssignees),
+			}
+			if todo.Status == apiv1.Pending {
+				todo.Assignee = ""
+			}
+			if err := ldg.Set(ctx, store.ID(fmt.Sprintf("%08d", idx)), todo); err != nil {
+				b.Fatal(err)
+			}
+		}
+		benchCtrl = New(ldg, uuid.NewV4()).(*Controller)
+	})
+	return benchCtrl
+}
+
+// scanQuery selects the same todos of the given query without using the ledger indexes,
+// like the controller did before they existed.
+func scanQuery(query ledger.Query) ledger.Query {
+	return ledger.Query{
+		Wants: func(todo model.Todo) bool {
+			if query.Assignee != "" && todo.Assignee != query.Assignee {
+				return false
+			}
+			for _, status := range query.Statuses {
+				if todo.Status == status {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func benchmarkListTodos(b *testing.B, query ledger.Query, expected int) {
+	ctrl := benchController(b)
+	for _, bc := range []struct {
+		name  string
+		query ledger.Query
+	}{
+		{"indexed", query},
+		{"scan", scanQuery(query)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				items, _, code, err := ctrl.listTodos(bc.query, page{})
+				if err != nil {
+					b.Fatal("error", err, code)
+				}
+				if len(items) != expected {
+					b.Fatalf("expected %d items got %d", expected, len(items))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBacklogAssigned(b *testing.B) {
+	// user1 has todos 1, 10001, 20001..., all assigned
+	benchmarkListTodos(b, backlogQuery("user1"), benchTodos/benchAssignees)
+}
+
+func BenchmarkCompletedAssigned(b *testing.B) {
+	// user2 has todos 2, 10002, 20002..., all completed
+	benchmarkListTodos(b, completedQuery("user2"), benchTodos/benchAssignees)
+}
+
+func BenchmarkCompletedIndex(b *testing.B) {
+	// a quarter of the todos are completed, but a page is capped
+	benchmarkListTodos(b, completedQuery(""), MaxPageLimit)
+}
@@ -0,0 +1,92 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestLabelFiltering(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+
+	for id, labels := range map[string][]string{
+		"1": {"frontend"},
+		"2": {"frontend", "infra"},
+		"3": {"docs"},
+	} {
+		if err := ldg.Set(context.Background(), store.ID("todo-"+id), model.New("todo "+id)); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/todos/todo-"+id+"/label", bodyFromTodo(model.Todo{Labels: labels})))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("labeling failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/todos/todo-2/unlabel", bodyFromTodo(model.Todo{Labels: []string{"frontend"}})))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("unlabeling failed: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/todos/todo-3/label", bodyFromTodo(model.Todo{Labels: []string{"NOPE"}})))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid label accepted: %d %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		url      string
+		expected []apiv1.ID
+	}{
+		{"/todos?label=frontend", []apiv1.ID{"todo-1"}},
+		{"/backlog?label=infra", []apiv1.ID{"todo-2"}},
+		{"/backlog?label=infra&label=docs", nil},
+		{"/todos", []apiv1.ID{"todo-1", "todo-2", "todo-3"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			var resp apiv1.Response
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []apiv1.ID
+			for _, item := range resp.Result.Items {
+				got = append(got, item.ID)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, got)
+			}
+		})
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/labels", nil))
+	var resp apiv1.Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	expected := []apiv1.LabelCount{
+		{Label: "docs", Count: 1},
+		{Label: "frontend", Count: 1},
+		{Label: "infra", Count: 1},
+	}
+	if !reflect.DeepEqual(resp.Result.Labels, expected) {
+		t.Fatalf("expected %v got %v", expected, resp.Result.Labels)
+	}
+}
@@ -0,0 +1,77 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+type failingGenerator struct{}
+
+func (failingGenerator) NewUUID() (string, error) {
+	return "", errors.New("no more ids")
+}
+
+func TestTodoMergeAtomic(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name         string
+		uuidGen      uuid.UUIDGenerator
+		storeErr     error
+		url          string
+		expectedCode int
+	}{
+		{"id generation failure", failingGenerator{}, nil, "/todomerge/1/2", http.StatusServiceUnavailable},
+		{"store failure", uuid.NewSequence(3), errors.New("injected error"), "/todomerge/1/2", http.StatusUnprocessableEntity},
+		{"missing todo", uuid.NewSequence(3), nil, "/todomerge/1/404", http.StatusNotFound},
+		{"same todo", uuid.NewSequence(3), nil, "/todomerge/1/1", http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			st, err := fake.NewMem()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ldg, err := ledger.New(ctx, st)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []store.ID{"1", "2"} {
+				if err := ldg.Set(ctx, id, model.New("todo "+string(id))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			handler := controller.New(ldg, tc.uuidGen)
+
+			st.Error = tc.storeErr
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.url, nil))
+			if w.Code != tc.expectedCode {
+				t.Fatalf("expected code %d got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+			st.Error = nil
+
+			for _, id := range []store.ID{"1", "2"} {
+				if _, err := ldg.Get(id); err != nil {
+					t.Errorf("original %v lost: %v", id, err)
+				}
+			}
+			if _, err := ldg.Get("3"); err == nil {
+				t.Errorf("unexpected merged todo")
+			}
+			if len(st.Blobs) != 2 {
+				t.Errorf("unexpected store content: %d blobs", len(st.Blobs))
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestTodoIndexPagination(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+
+	now := time.Now()
+	todos := map[store.ID]model.Todo{
+		"a": {Title: "delta", Status: apiv1.Completed, LastUpdateTime: now.Add(-1 * time.Hour)},
+		"b": {Title: "alpha", Status: apiv1.Pending, LastUpdateTime: now.Add(-3 * time.Hour)},
+		"c": {Title: "charlie", Status: apiv1.Assigned, Assignee: "fede", LastUpdateTime: now.Add(-2 * time.Hour)},
+		"d": {Title: "bravo", Status: apiv1.Deleted, LastUpdateTime: now.Add(-4 * time.Hour)},
+		"e": {Title: "alpha", Status: apiv1.Pending, LastUpdateTime: now},
+	}
+	for id, todo := range todos {
+		if err := ldg.Set(context.Background(), id, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sort     string
+		expected string
+	}{
+		{"", "a b c d e"},
+		{"title", "b e d c a"},
+		{"-title", "a c d b e"},
+		{"updated", "e a c b d"},
+		{"-updated", "d b c a e"},
+		{"status", "b e c a d"},
+	}
+	for _, tc := range tests {
+		for _, limit := range []int{1, 2, 5} {
+			t.Run(fmt.Sprintf("sort=%q limit=%d", tc.sort, limit), func(t *testing.T) {
+				var got []string
+				cursor := ""
+				for pages := 0; ; pages++ {
+					if pages > len(todos) {
+						t.Fatalf("too many pages, got %v", got)
+					}
+					query := url.Values{}
+					query.Set("sort", tc.sort)
+					query.Set("limit", fmt.Sprint(limit))
+					if cursor != "" {
+						query.Set("cursor", cursor)
+					}
+					resp := getPage(t, handler, "/todos?"+query.Encode())
+					if len(resp.Result.Items) > limit {
+						t.Fatalf("expected at most %d items, got %d", limit, len(resp.Result.Items))
+					}
+					for _, item := range resp.Result.Items {
+						got = append(got, string(item.ID))
+					}
+					cursor = resp.Result.NextCursor
+					if cursor == "" {
+						break
+					}
+				}
+				if res := strings.Join(got, " "); res != tc.expected {
+					t.Fatalf("expected %q got %q", tc.expected, res)
+				}
+			})
+		}
+	}
+}
+
+func TestTodoIndexPaginationErrors(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+	for _, id := range []store.ID{"a", "b", "c"} {
+		if err := ldg.Set(context.Background(), id, model.New(string(id))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp := getPage(t, handler, "/todos?sort=title&limit=1")
+	if resp.Result.NextCursor == "" {
+		t.Fatalf("expected a next cursor")
+	}
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"zero limit", "/todos?limit=0"},
+		{"negative limit", "/todos?limit=-1"},
+		{"bad limit", "/todos?limit=many"},
+		{"unknown sort", "/todos?sort=mood"},
+		{"bad cursor", "/todos?cursor=not-a-cursor"},
+		{"cursor of other sort", "/todos?sort=updated&cursor=" + resp.Result.NextCursor},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected code %d got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRPCListPagination(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+	for _, id := range []store.ID{"a", "b", "c"} {
+		if err := ldg.Set(context.Background(), id, model.New(string(id))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []apiv1.ID
+	cursor := ""
+	for {
+		params, err := json.Marshal(apiv1.RPCListParams{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := rpcRequest(t, handler, `{"jsonrpc":"2.0","id":1,"method":"todo.list","params":`+string(params)+`}`)
+		var resp apiv1.RPCResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %v", resp.Error)
+		}
+		for _, item := range resp.Result.Items {
+			got = append(got, item.ID)
+		}
+		if cursor = resp.Result.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Fatalf("unexpected items: %v", got)
+	}
+}
+
+func getPage(t *testing.T, handler http.Handler, target string) apiv1.Response {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected code %d got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp apiv1.Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
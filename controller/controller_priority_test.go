@@ -0,0 +1,159 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestBacklogSortAndOverdue(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+	lastWeek := now.Add(-7 * 24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+
+	todos := map[store.ID]model.Todo{
+		"a": {Title: "a", Status: apiv1.Pending, Priority: apiv1.Low, DueTime: &yesterday},
+		"b": {Title: "b", Status: apiv1.Pending, Priority: apiv1.Urgent, DueTime: &tomorrow},
+		"c": {Title: "c", Status: apiv1.Assigned, Assignee: "fede", DueTime: &lastWeek},
+		"d": {Title: "d", Status: apiv1.Pending, Priority: apiv1.Urgent},
+		"e": {Title: "e", Status: apiv1.Completed, Assignee: "fede", DueTime: &lastWeek},
+	}
+	for id, todo := range todos {
+		if err := ldg.Set(context.Background(), id, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		code     int
+		expected []apiv1.ID
+	}{
+		{"by priority", "/backlog?sort=priority", http.StatusOK, []apiv1.ID{"b", "d", "c", "a"}},
+		{"by due", "/backlog?sort=due", http.StatusOK, []apiv1.ID{"c", "a", "b", "d"}},
+		{"overdue", "/overdue", http.StatusOK, []apiv1.ID{"c", "a"}},
+		{"unknown criteria", "/backlog?sort=mood", http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if w.Code != tc.code {
+				t.Fatalf("expected code %d got %d: %s", tc.code, w.Code, w.Body.String())
+			}
+			if tc.expected == nil {
+				return
+			}
+			var resp apiv1.Response
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []apiv1.ID
+			for _, item := range resp.Result.Items {
+				got = append(got, item.ID)
+			}
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, got)
+			}
+			for idx := range got {
+				if got[idx] != tc.expected[idx] {
+					t.Fatalf("expected %v got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdatePriorityAndDue(t *testing.T) {
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		todo     model.Todo
+		update   apiv1.Todo
+		code     int
+		expected apiv1.Todo
+	}{
+		{"priority of pending", model.Todo{Title: "a", Status: apiv1.Pending},
+			apiv1.Todo{Priority: apiv1.High},
+			http.StatusCreated, apiv1.Todo{Status: apiv1.Pending, Priority: apiv1.High}},
+		{"due of pending", model.Todo{Title: "a", Status: apiv1.Pending},
+			apiv1.Todo{DueTime: &tomorrow},
+			http.StatusCreated, apiv1.Todo{Status: apiv1.Pending, Priority: apiv1.Normal, DueTime: &tomorrow}},
+		{"priority of assigned", model.Todo{Title: "a", Status: apiv1.Assigned, Assignee: "bob"},
+			apiv1.Todo{Priority: apiv1.Urgent},
+			http.StatusCreated, apiv1.Todo{Status: apiv1.Assigned, Assignee: "bob", Priority: apiv1.Urgent}},
+		{"due of assigned, same assignee", model.Todo{Title: "a", Status: apiv1.Assigned, Assignee: "bob"},
+			apiv1.Todo{Assignee: "bob", DueTime: &tomorrow},
+			http.StatusCreated, apiv1.Todo{Status: apiv1.Assigned, Assignee: "bob", Priority: apiv1.Normal, DueTime: &tomorrow}},
+		{"assign pending", model.Todo{Title: "a", Status: apiv1.Pending},
+			apiv1.Todo{Assignee: "bob", Priority: apiv1.Low},
+			http.StatusCreated, apiv1.Todo{Status: apiv1.Assigned, Assignee: "bob", Priority: apiv1.Low}},
+		{"other assignee", model.Todo{Title: "a", Status: apiv1.Assigned, Assignee: "bob"},
+			apiv1.Todo{Assignee: "alice"},
+			http.StatusUnprocessableEntity, apiv1.Todo{}},
+		{"invalid priority", model.Todo{Title: "a", Status: apiv1.Pending},
+			apiv1.Todo{Priority: "whenever"},
+			http.StatusUnprocessableEntity, apiv1.Todo{}},
+		{"due in the past", model.Todo{Title: "a", Status: apiv1.Assigned, Assignee: "bob"},
+			apiv1.Todo{DueTime: &yesterday},
+			http.StatusUnprocessableEntity, apiv1.Todo{}},
+		{"description of overdue", model.Todo{Title: "a", Status: apiv1.Assigned, Assignee: "bob", DueTime: &yesterday},
+			apiv1.Todo{Title: "a", Description: "late", Status: apiv1.Assigned, Assignee: "bob", DueTime: &yesterday},
+			http.StatusCreated, apiv1.Todo{Description: "late", Status: apiv1.Assigned, Assignee: "bob", Priority: apiv1.Normal, DueTime: &yesterday}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ldg := memoryStorage()
+			handler := controller.New(ldg, uuid.NewV4())
+			if err := ldg.Set(context.Background(), "a", tc.todo); err != nil {
+				t.Fatal(err)
+			}
+			body, err := json.Marshal(tc.update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/todos/a", bytes.NewReader(body)))
+			if w.Code != tc.code {
+				t.Fatalf("expected code %d got %d: %s", tc.code, w.Code, w.Body.String())
+			}
+			todo, err := ldg.Get("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.code != http.StatusCreated {
+				if todo.Revision != 1 {
+					t.Errorf("rejected update stored: %+v", todo)
+				}
+				return
+			}
+			got := todo.ToAPIv1()
+			if got.Status != tc.expected.Status || got.Assignee != tc.expected.Assignee || got.Priority != tc.expected.Priority ||
+				got.Description != tc.expected.Description {
+				t.Errorf("expected %+v got %+v", tc.expected, got)
+			}
+			if (tc.expected.DueTime == nil) != (got.DueTime == nil) || (got.DueTime != nil && !got.DueTime.Equal(*tc.expected.DueTime)) {
+				t.Errorf("expected due %v got %v", tc.expected.DueTime, got.DueTime)
+			}
+		})
+	}
+}
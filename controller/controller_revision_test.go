@@ -0,0 +1,115 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestRevisions(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+	if err := ldg.Set(context.Background(), "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(req *http.Request, ifMatch string) *httptest.ResponseRecorder {
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(httptest.NewRequest(http.MethodGet, "/todos/1", nil), "")
+	if etag := w.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf("unexpected etag %q", etag)
+	}
+
+	w = do(httptest.NewRequest(http.MethodPut, "/todos/1", bodyFromTodo(model.Todo{Assignee: "fede"})), `"1"`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf("unexpected etag %q after update", etag)
+	}
+
+	w = do(httptest.NewRequest(http.MethodPost, "/todos/1/complete", bodyFromTodo(model.Todo{})), `"1"`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale completion not rejected: %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(httptest.NewRequest(http.MethodPost, "/todos/1/complete", bodyFromTodo(model.Todo{})), `"2"`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("completion failed: %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(httptest.NewRequest(http.MethodPost, "/todos/1/delete", bodyFromTodo(model.Todo{})), `"2"`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale deletion not rejected: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRevisionsSharedStore(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two processes sharing the same store
+	handlers := make([]http.Handler, 2)
+	for i := range handlers {
+		ldg, err := ledger.New(ctx, st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := ldg.Set(ctx, "1", model.New("foo")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		handlers[i] = controller.New(ldg, uuid.NewV4())
+	}
+
+	do := func(handler http.Handler, description, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/todos/1", bodyFromTodo(model.Todo{Description: description}))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(handlers[1], "first", ""); w.Code != http.StatusCreated {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(handlers[0], "second", `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale update not rejected: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(handlers[1], "third", ""); w.Code != http.StatusCreated {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(handlers[0], "fourth", ""); w.Code != http.StatusConflict {
+		t.Fatalf("concurrent update not rejected: %d %s", w.Code, w.Body.String())
+	}
+	// the conflict refreshed the stale copy
+	if w := do(handlers[0], "fifth", `"3"`); w.Code != http.StatusCreated {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	todo, err := model.DeserializeTodo(st.Blobs["1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todo.Description != "fifth" || todo.Revision != 4 {
+		t.Fatalf("unexpected todo %v", todo)
+	}
+}
@@ -0,0 +1,128 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func rpcRequest(t *testing.T, handler http.Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func decodeRPC(t *testing.T, w *httptest.ResponseRecorder) apiv1.RPCResponse {
+	t.Helper()
+	var resp apiv1.RPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestRPCLifecycle(t *testing.T) {
+	handler := controller.New(memoryStorage(), uuid.NewSequence(1))
+
+	resp := decodeRPC(t, rpcRequest(t, handler, `{"jsonrpc":"2.0","method":"todo.create","params":{"todo":{"title":"foo"}},"id":"a"}`))
+	if resp.Error != nil || string(resp.ID) != `"a"` || resp.Result.Items[0].ID != "1" {
+		t.Fatalf("unexpected create response: %+v", resp)
+	}
+
+	resp = decodeRPC(t, rpcRequest(t, handler, `{"jsonrpc":"2.0","method":"todo.update","params":{"id":"1","todo":{"assignee":"fede"},"revision":1},"id":2}`))
+	if resp.Error != nil || string(resp.ID) != "2" || resp.Result.Items[0].Todo.Status != apiv1.Assigned {
+		t.Fatalf("unexpected update response: %+v", resp)
+	}
+
+	resp = decodeRPC(t, rpcRequest(t, handler, `{"jsonrpc":"2.0","method":"backlog.list","params":{"assignee":"fede"},"id":3}`))
+	if resp.Error != nil || len(resp.Result.Items) != 1 {
+		t.Fatalf("unexpected backlog response: %+v", resp)
+	}
+
+	resp = decodeRPC(t, rpcRequest(t, handler, `{"jsonrpc":"2.0","method":"todo.complete","params":{"id":"1","revision":1},"id":4}`))
+	if resp.Error == nil || resp.Error.Code != apiv1.RPCServerError || resp.Error.Data.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale revision not rejected: %+v", resp)
+	}
+
+	resp = decodeRPC(t, rpcRequest(t, handler, `{"jsonrpc":"2.0","method":"todo.show","params":{"id":"999"},"id":5}`))
+	if resp.Error == nil || resp.Error.Data.Code != http.StatusNotFound {
+		t.Fatalf("unknown todo not reported: %+v", resp)
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	handler := controller.New(memoryStorage(), uuid.NewSequence(1))
+
+	tests := []struct {
+		name string
+		body string
+		code int
+		id   string
+	}{
+		{"parse error", `{"jsonrpc":"2.0","method"`, apiv1.RPCParseError, "null"},
+		{"invalid version", `{"jsonrpc":"1.0","method":"todo.list","id":1}`, apiv1.RPCInvalidRequest, "1"},
+		{"invalid id", `{"jsonrpc":"2.0","method":"todo.list","id":{}}`, apiv1.RPCInvalidRequest, "null"},
+		{"empty batch", `[]`, apiv1.RPCInvalidRequest, "null"},
+		{"unknown method", `{"jsonrpc":"2.0","method":"todo.frobnicate","id":1}`, apiv1.RPCMethodNotFound, "1"},
+		{"unknown params", `{"jsonrpc":"2.0","method":"todo.list","params":{"foo":1},"id":1}`, apiv1.RPCInvalidParams, "1"},
+		{"missing params", `{"jsonrpc":"2.0","method":"todo.show","id":null}`, apiv1.RPCInvalidParams, "null"},
+		{"namespaced id", `{"jsonrpc":"2.0","method":"todo.complete","params":{"id":"token/tok1"},"id":1}`, apiv1.RPCInvalidParams, "1"},
+		{"namespaced merge id", `{"jsonrpc":"2.0","method":"todo.merge","params":{"id1":"1","id2":"history/1"},"id":1}`, apiv1.RPCInvalidParams, "1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := decodeRPC(t, rpcRequest(t, handler, tc.body))
+			if resp.Error == nil || resp.Error.Code != tc.code {
+				t.Fatalf("expected error code %d, got %+v", tc.code, resp)
+			}
+			if string(resp.ID) != tc.id {
+				t.Fatalf("expected id %s, got %s", tc.id, string(resp.ID))
+			}
+		})
+	}
+}
+
+func TestRPCBatchAndNotifications(t *testing.T) {
+	handler := controller.New(memoryStorage(), uuid.NewSequence(1))
+
+	w := rpcRequest(t, handler, `{"jsonrpc":"2.0","method":"todo.create","params":{"todo":{"title":"foo"}}}`)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("notification got a response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = rpcRequest(t, handler, `[
+		{"jsonrpc":"2.0","method":"todo.create","params":{"todo":{"title":"bar"}}},
+		{"jsonrpc":"2.0","method":"todo.list","id":1},
+		1,
+		{"jsonrpc":"2.0","method":"todo.show","params":{"id":"1"},"id":2}
+	]`)
+	var resps []apiv1.RPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&resps); err != nil {
+		t.Fatalf("failed to decode batch response: %v", err)
+	}
+	if len(resps) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(resps))
+	}
+	if string(resps[0].ID) != "1" || len(resps[0].Result.Items) != 2 {
+		t.Fatalf("unexpected list response: %+v", resps[0])
+	}
+	if string(resps[1].ID) != "null" || resps[1].Error.Code != apiv1.RPCInvalidRequest {
+		t.Fatalf("unexpected invalid request response: %+v", resps[1])
+	}
+	if string(resps[2].ID) != "2" || resps[2].Result.Items[0].Todo.Title != "foo" {
+		t.Fatalf("unexpected show response: %+v", resps[2])
+	}
+
+	w = rpcRequest(t, handler, `[{"jsonrpc":"2.0","method":"todo.list"}]`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("batch of notifications got a response: %d %s", w.Code, w.Body.String())
+	}
+}
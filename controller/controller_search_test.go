@@ -0,0 +1,78 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestSearch(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+
+	todos := map[store.ID]model.Todo{
+		"a": {Title: "deploy backend", Status: apiv1.Assigned, Assignee: "ana"},
+		"b": {Title: "write docs", Description: "explain how to deploy", Status: apiv1.Pending},
+		"c": {Title: "deployment pipeline", Status: apiv1.Completed, Assignee: "bob"},
+		"d": {Title: "fix login", Status: apiv1.Pending},
+	}
+	for id, todo := range todos {
+		if err := ldg.Set(context.Background(), id, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		code     int
+		expected []apiv1.ID
+	}{
+		{"ranked", "/search?text=deploy", http.StatusOK, []apiv1.ID{"a", "c", "b"}},
+		// as prefix matches, the rarer "deployment" counts more than "deploy"
+		{"prefix", "/search?text=depl", http.StatusOK, []apiv1.ID{"c", "a", "b"}},
+		{"limit", "/search?text=deploy&limit=1", http.StatusOK, []apiv1.ID{"a"}},
+		{"by status", "/search?text=deploy&status=pending&status=completed", http.StatusOK, []apiv1.ID{"c", "b"}},
+		{"by assignee", "/search?text=deploy&assignee=bob", http.StatusOK, []apiv1.ID{"c"}},
+		{"no match", "/search?text=deploy+login", http.StatusOK, nil},
+		{"missing text", "/search", http.StatusBadRequest, nil},
+		{"invalid status", "/search?text=deploy&status=happy", http.StatusBadRequest, nil},
+		{"invalid limit", "/search?text=deploy&limit=0", http.StatusBadRequest, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if w.Code != tc.code {
+				t.Fatalf("expected code %d got %d: %s", tc.code, w.Code, w.Body.String())
+			}
+			if tc.code != http.StatusOK {
+				return
+			}
+			var resp apiv1.Response
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []apiv1.ID
+			for _, item := range resp.Result.Items {
+				got = append(got, item.ID)
+			}
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, got)
+			}
+			for idx := range got {
+				if got[idx] != tc.expected[idx] {
+					t.Fatalf("expected %v got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+)
+
+func TestTransitionRoutes(t *testing.T) {
+	ldg := memoryStorage()
+	handler := controller.New(ldg, uuid.NewV4())
+
+	todo := model.New("flaky test")
+	if err := todo.Assign("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(context.Background(), store.ID("todo-1"), todo); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		method           string
+		url              string
+		body             model.Todo
+		expectedCode     int
+		expectedStatus   apiv1.Status
+		expectedAssignee string
+	}{
+		{http.MethodPost, "/todos/todo-1/reassign", model.Todo{Assignee: "bob"}, http.StatusCreated, apiv1.Assigned, "bob"},
+		{http.MethodPost, "/todos/todo-1/reopen", model.Todo{}, http.StatusUnprocessableEntity, "", ""},
+		{http.MethodPost, "/todos/todo-1/unassign", model.Todo{}, http.StatusCreated, apiv1.Pending, ""},
+		{http.MethodPost, "/todos/todo-1/unassign", model.Todo{}, http.StatusUnprocessableEntity, "", ""},
+		{http.MethodPut, "/todos/todo-1", model.Todo{Assignee: "carol"}, http.StatusCreated, apiv1.Assigned, "carol"},
+		{http.MethodPost, "/todos/todo-1/complete", model.Todo{}, http.StatusCreated, apiv1.Completed, "carol"},
+		{http.MethodPost, "/todos/todo-1/reassign", model.Todo{Assignee: "bob"}, http.StatusUnprocessableEntity, "", ""},
+		{http.MethodPost, "/todos/todo-1/reopen", model.Todo{Assignee: "alice"}, http.StatusCreated, apiv1.Assigned, "alice"},
+		{http.MethodPost, "/todos/todo-missing/unassign", model.Todo{}, http.StatusNotFound, "", ""},
+	}
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(step.method, step.url, bodyFromTodo(step.body)))
+		if w.Code != step.expectedCode {
+			t.Fatalf("%s: expected code %d got %d: %s", step.url, step.expectedCode, w.Code, w.Body.String())
+		}
+		if w.Code != http.StatusCreated {
+			continue
+		}
+		var resp apiv1.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		got := resp.Result.Items[0].Todo
+		if got.Status != step.expectedStatus || got.Assignee != step.expectedAssignee {
+			t.Fatalf("%s: expected %s @%s got %s @%s", step.url, step.expectedStatus, step.expectedAssignee, got.Status, got.Assignee)
+		}
+	}
+
+	stored, err := ldg.Get(store.ID("todo-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"alice", "bob", "carol"}
+	if !reflect.DeepEqual(stored.PreviousAssignees, expected) {
+		t.Fatalf("expected previous assignees %v got %v", expected, stored.PreviousAssignees)
+	}
+}
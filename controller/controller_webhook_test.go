@@ -0,0 +1,123 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/controller"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/uuid"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher, err := webhook.NewDispatcher(ctx, st, webhook.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dispatcher.Close()
+	handler := asAdmin(t, ldg, uuid.NewSequence(1), controller.WithWebhooks(dispatcher))
+
+	type delivery struct {
+		payload apiv1.WebhookPayload
+		valid   bool
+	}
+	var secret string
+	deliveries := make(chan delivery, 8)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload apiv1.WebhookPayload
+		json.Unmarshal(body, &payload)
+		deliveries <- delivery{payload, webhook.Verify(secret, body, r.Header.Get(apiv1.SignatureHeader))}
+	}))
+	defer receiver.Close()
+
+	do := func(method, url string, body io.Reader, expectedCode int) apiv1.Response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, url, body))
+		if w.Code != expectedCode {
+			t.Fatalf("%s %s: expected %d got %d: %s", method, url, expectedCode, w.Code, w.Body.String())
+		}
+		var resp apiv1.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	webhookBody := func(apiWebhook apiv1.Webhook) io.Reader {
+		data, err := json.Marshal(apiWebhook)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.NewReader(data)
+	}
+
+	do(http.MethodPost, "/webhooks", webhookBody(apiv1.Webhook{URL: "not a url"}), http.StatusUnprocessableEntity)
+	created := do(http.MethodPost, "/webhooks", webhookBody(apiv1.Webhook{
+		URL:    receiver.URL,
+		Events: []apiv1.Operation{apiv1.OpAssign, apiv1.OpComplete},
+	}), http.StatusCreated).Result.Webhooks
+	if len(created) != 1 || created[0].ID != "1" || created[0].Secret == "" {
+		t.Fatalf("unexpected created webhook: %+v", created)
+	}
+	secret = created[0].Secret
+
+	listed := do(http.MethodGet, "/webhooks", nil, http.StatusOK).Result.Webhooks
+	if len(listed) != 1 || listed[0].ID != "1" || listed[0].Secret != "" {
+		t.Fatalf("unexpected listed webhooks: %+v", listed)
+	}
+
+	do(http.MethodPost, "/todos", bodyFromTodo(model.Todo{Title: "deploy"}), http.StatusCreated)
+	do(http.MethodPut, "/todos/2", bodyFromTodo(model.Todo{Assignee: "ana"}), http.StatusCreated)
+	select {
+	case d := <-deliveries:
+		if !d.valid {
+			t.Fatalf("invalid signature")
+		}
+		if d.payload.Webhook != "1" || d.payload.Event.Operation != apiv1.OpAssign || d.payload.Event.Item.ID != "2" {
+			t.Fatalf("unexpected delivery: %+v", d.payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("delivery not received")
+	}
+
+	do(http.MethodPost, "/webhooks/1/delete", nil, http.StatusOK)
+	do(http.MethodGet, "/webhooks/1", nil, http.StatusNotFound)
+	do(http.MethodPost, "/todos/2/complete", bodyFromTodo(model.Todo{}), http.StatusCreated)
+	select {
+	case d := <-deliveries:
+		t.Fatalf("unexpected delivery after unsubscribing: %+v", d.payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if letters := do(http.MethodGet, "/webhooks/deadletters", nil, http.StatusOK).Result.DeadLetters; len(letters) != 0 {
+		t.Fatalf("unexpected dead letters: %+v", letters)
+	}
+}
+
+func TestWebhooksDisabled(t *testing.T) {
+	handler := asAdmin(t, memoryStorage(), uuid.NewV4())
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/webhooks", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("expected %d got %d", http.StatusNotImplemented, w.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+// eventsKeepAlive is how often a idle stream sends a comment, to keep proxies from closing it
+const eventsKeepAlive = 15 * time.Second
+
+/*
+Events streams the changes of the todos as Server-Sent Events, optionally only the ones
+of a assignee (?assignee=) or of some statuses (?status=, can be repeated).
+Reconnecting clients get the events they missed, if still buffered, using the standard
+Last-Event-ID header; if some are no longer available, they get a "reset" event first.
+Test with this curl command:
+
+curl -N http://localhost:8080/events?assignee=ana
+*/
+func (ctrl *Controller) Events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	lastSeq, err := lastEventIDFromRequest(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	wants := eventWants(r.URL.Query().Get("assignee"), r.URL.Query()["status"])
+
+	sub, replay, missed := ctrl.events.Subscribe(lastSeq)
+	defer sub.Cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if missed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, ev := range replay {
+		if err := writeEvent(w, ev, wants); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(eventsKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.Events:
+			if !ok {
+				// we fell behind, or we are shutting down: let the client reconnect
+				// and replay what it missed
+				log.Printf("API: events: subscription dropped")
+				return
+			}
+			if err := writeEvent(w, ev, wants); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent sends a event in the Server-Sent Events format, if wanted
+func writeEvent(w http.ResponseWriter, ev apiv1.Event, wants func(apiv1.Event) bool) error {
+	if !wants(ev) {
+		return nil
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Operation, data)
+	return err
+}
+
+func lastEventIDFromRequest(r *http.Request) (uint64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func eventWants(assignee string, statuses []string) func(apiv1.Event) bool {
+	return func(ev apiv1.Event) bool {
+		todo := ev.Item.Todo
+		if todo == nil {
+			return false
+		}
+		if assignee != "" && todo.Assignee != assignee {
+			return false
+		}
+		if len(statuses) == 0 {
+			return true
+		}
+		for _, status := range statuses {
+			if todo.Status == apiv1.Status(status) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,358 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/filter"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// The operations are shared among all the API flavours (REST-ish routes, JSON-RPC).
+// Like todoFromRequest, on failure they return the HTTP status code and the error
+// describing the failure; each API flavour translates them in its own way.
+
+// revisionMatch is a precondition on the current revision of a object.
+// A nil revisionMatch means no precondition.
+type revisionMatch func(revision uint64) bool
+
+// revisionEquals returns a precondition requiring exactly the given revision
+func revisionEquals(expected uint64) revisionMatch {
+	return func(revision uint64) bool {
+		return revision == expected
+	}
+}
+
+func (ctrl *Controller) getTodo(todoID store.ID) (model.Todo, int, error) {
+	todo, err := ctrl.ld.Get(todoID)
+	if err != nil {
+		return model.Todo{}, http.StatusNotFound, err
+	}
+	return todo, 0, nil
+}
+
+func (ctrl *Controller) createTodo(ctx context.Context, actor string, apiTodo apiv1.Todo) (store.ID, model.Todo, int, error) {
+	todo, err := model.NewFromAPIv1(apiTodo)
+	if err != nil {
+		return store.NullID, model.Todo{}, http.StatusUnprocessableEntity, err
+	}
+	log.Printf("API: got object %v", todo)
+
+	todoID, err := ctrl.uuidGen.NewUUID()
+	if err != nil {
+		return store.NullID, model.Todo{}, http.StatusServiceUnavailable, err
+	}
+
+	// the todo is created only along with its history, and vice versa
+	err = ctrl.ld.Update(ctx, func(tx *ledger.Tx) error {
+		var err error
+		if todo, err = tx.Set(store.ID(todoID), todo); err != nil {
+			return err
+		}
+		tx.OnCommit(func() { ctrl.publish(apiv1.OpCreate, store.ID(todoID), todo) })
+		return tx.Record(store.ID(todoID), model.NewHistoryEntry(actor, apiv1.OpCreate, model.Todo{}, todo))
+	})
+	if err != nil {
+		return store.NullID, model.Todo{}, http.StatusUnprocessableEntity, err
+	}
+	return store.ID(todoID), todo, 0, nil
+}
+
+func (ctrl *Controller) updateTodo(ctx context.Context, todoID store.ID, actor string, apiTodo apiv1.Todo, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.applyTodo(ctx, todoID, actor, match, func(todo *model.Todo) (apiv1.Operation, error) {
+		if err := todo.Describe(apiTodo.Description); err != nil {
+			return "", err
+		}
+		if apiTodo.Priority != "" {
+			if err := todo.Prioritize(apiTodo.Priority); err != nil {
+				return "", err
+			}
+		}
+		// sending back the current due time is not a change, even once it is past
+		if apiTodo.DueTime != nil && (todo.DueTime == nil || !apiTodo.DueTime.Equal(*todo.DueTime)) {
+			if err := todo.Schedule(*apiTodo.DueTime); err != nil {
+				return "", err
+			}
+		}
+		// the assignee is optional, and sending back the current one is not a change
+		if apiTodo.Assignee == "" || apiTodo.Assignee == todo.Assignee {
+			return apiv1.OpDescribe, nil
+		}
+		return apiv1.OpAssign, todo.Assign(apiTodo.Assignee)
+	})
+}
+
+func (ctrl *Controller) labelTodo(ctx context.Context, todoID store.ID, actor string, labels []string, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.mutateTodo(ctx, todoID, actor, match, apiv1.OpLabel, func(todo *model.Todo) error {
+		return todo.AddLabels(labels...)
+	})
+}
+
+func (ctrl *Controller) unlabelTodo(ctx context.Context, todoID store.ID, actor string, labels []string, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.mutateTodo(ctx, todoID, actor, match, apiv1.OpUnlabel, func(todo *model.Todo) error {
+		return todo.RemoveLabels(labels...)
+	})
+}
+
+func (ctrl *Controller) unassignTodo(ctx context.Context, todoID store.ID, actor string, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.mutateTodo(ctx, todoID, actor, match, apiv1.OpUnassign, func(todo *model.Todo) error {
+		return todo.Unassign()
+	})
+}
+
+func (ctrl *Controller) reassignTodo(ctx context.Context, todoID store.ID, actor string, assignee string, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.mutateTodo(ctx, todoID, actor, match, apiv1.OpReassign, func(todo *model.Todo) error {
+		return todo.Reassign(assignee)
+	})
+}
+
+func (ctrl *Controller) reopenTodo(ctx context.Context, todoID store.ID, actor string, assignee string, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.mutateTodo(ctx, todoID, actor, match, apiv1.OpReopen, func(todo *model.Todo) error {
+		return todo.Reopen(assignee)
+	})
+}
+
+func (ctrl *Controller) completeTodo(ctx context.Context, todoID store.ID, actor string, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.mutateTodo(ctx, todoID, actor, match, apiv1.OpComplete, func(todo *model.Todo) error {
+		return todo.Complete()
+	})
+}
+
+func (ctrl *Controller) deleteTodo(ctx context.Context, todoID store.ID, actor string, match revisionMatch) (model.Todo, int, error) {
+	return ctrl.mutateTodo(ctx, todoID, actor, match, apiv1.OpDelete, func(todo *model.Todo) error {
+		return todo.Delete()
+	})
+}
+
+// mutateTodo loads a todo, checks the revision precondition, applies the given mutation
+// and stores back the todo along with its history entry, only if no one else updated it meanwhile.
+func (ctrl *Controller) mutateTodo(ctx context.Context, todoID store.ID, actor string, match revisionMatch, op apiv1.Operation, mutate func(todo *model.Todo) error) (model.Todo, int, error) {
+	return ctrl.applyTodo(ctx, todoID, actor, match, func(todo *model.Todo) (apiv1.Operation, error) {
+		return op, mutate(todo)
+	})
+}
+
+// applyTodo is like mutateTodo, for the mutations whose operation depends on the current todo.
+func (ctrl *Controller) applyTodo(ctx context.Context, todoID store.ID, actor string, match revisionMatch, mutate func(todo *model.Todo) (apiv1.Operation, error)) (model.Todo, int, error) {
+	var todo model.Todo
+	var op apiv1.Operation
+	code := http.StatusUnprocessableEntity
+	err := ctrl.ld.Update(ctx, func(tx *ledger.Tx) error {
+		var err error
+		todo, err = tx.Get(todoID)
+		if err != nil {
+			code = http.StatusNotFound
+			return err
+		}
+		log.Printf("API: got object %v", todoID)
+
+		if match != nil && !match(todo.Revision) {
+			code = http.StatusPreconditionFailed
+			return ledger.ErrRevisionMismatch
+		}
+
+		before := todo
+		if op, err = mutate(&todo); err != nil {
+			return err
+		}
+		log.Printf("API: %s object %v as: %q", op, todoID, todo)
+
+		if todo, err = tx.CompareAndSet(todoID, todo, todo.Revision); err != nil {
+			return err
+		}
+		tx.OnCommit(func() { ctrl.publish(op, todoID, todo) })
+		return tx.Record(todoID, model.NewHistoryEntry(actor, op, before, todo))
+	})
+	if errors.Is(err, ledger.ErrRevisionMismatch) {
+		// if the client explicitly asked for a revision, its precondition failed;
+		// otherwise the object was just updated by someone else meanwhile.
+		code = http.StatusPreconditionFailed
+		if match == nil {
+			code = http.StatusConflict
+		}
+	}
+	if err != nil {
+		return model.Todo{}, code, err
+	}
+	return todo, 0, nil
+}
+
+// mergeTodos replaces two todos with their merge. The originals are deleted only
+// if the merged todo is stored, and vice versa.
+func (ctrl *Controller) mergeTodos(ctx context.Context, todoID1, todoID2 store.ID, actor string) (store.ID, model.Todo, int, error) {
+	// the id must be available before touching anything
+	mergedID, err := ctrl.uuidGen.NewUUID()
+	if err != nil {
+		return store.NullID, model.Todo{}, http.StatusServiceUnavailable, err
+	}
+
+	var merged model.Todo
+	code := http.StatusUnprocessableEntity
+	err = ctrl.ld.Update(ctx, func(tx *ledger.Tx) error {
+		todo1, err := tx.Get(todoID1)
+		if err != nil {
+			code = http.StatusNotFound
+			return err
+		}
+		todo2, err := tx.Get(todoID2)
+		if err != nil {
+			code = http.StatusNotFound
+			return err
+		}
+		log.Printf("API: got objects %v - %v", todo1, todo2)
+
+		merged, err = model.Merge(todo1, todo2)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(todoID1); err != nil {
+			return err
+		}
+		if err := tx.Delete(todoID2); err != nil {
+			return err
+		}
+		merged, err = tx.Set(store.ID(mergedID), merged)
+		if err != nil {
+			return err
+		}
+		tx.OnCommit(func() { ctrl.publish(apiv1.OpMerge, store.ID(mergedID), merged, todoID1, todoID2) })
+
+		entry := model.NewHistoryEntry(actor, apiv1.OpMerge, model.Todo{}, merged)
+		entry.MergedFrom = []string{string(todoID1), string(todoID2)}
+		if err := tx.Record(store.ID(mergedID), entry); err != nil {
+			return err
+		}
+		for _, todoID := range []store.ID{todoID1, todoID2} {
+			entry := model.NewHistoryEntry(actor, apiv1.OpMerge, model.Todo{}, model.Todo{})
+			entry.MergedInto = mergedID
+			if err := tx.Record(todoID, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return store.NullID, model.Todo{}, code, err
+	}
+	return store.ID(mergedID), merged, 0, nil
+}
+
+// publish notifies the change feed, and the webhooks, of a operation performed on a todo.
+// Called once the operation is committed, before any other write to the ledger, so the
+// events are published in the same order as the changes were stored.
+func (ctrl *Controller) publish(op apiv1.Operation, todoID store.ID, todo model.Todo, mergedFrom ...store.ID) {
+	apiTodo := todo.ToAPIv1()
+	var apiMergedFrom []apiv1.ID
+	for _, id := range mergedFrom {
+		apiMergedFrom = append(apiMergedFrom, apiv1.ID(id))
+	}
+	ev := ctrl.events.Publish(op, apiv1.Item{ID: apiv1.ID(todoID), Todo: &apiTodo}, apiMergedFrom...)
+	if ctrl.webhooks != nil {
+		ctrl.webhooks.Notify(ev)
+	}
+}
+
+func (ctrl *Controller) todoHistory(ctx context.Context, todoID store.ID) (model.History, int, error) {
+	history, err := ctrl.ld.History(ctx, todoID)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	return history, 0, nil
+}
+
+// listLabels returns all the labels of the todos not deleted, with the number of todos
+// having each of them. The most used labels come first.
+func (ctrl *Controller) listLabels() ([]apiv1.LabelCount, int, error) {
+	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
+		return todo.Status != apiv1.Deleted && len(todo.Labels) > 0
+	})
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	counts := make(map[string]int)
+	for _, item := range items {
+		for _, label := range item.Todo.Labels {
+			counts[label]++
+		}
+	}
+	res := make([]apiv1.LabelCount, 0, len(counts))
+	for label, count := range counts {
+		res = append(res, apiv1.LabelCount{Label: label, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Label < res[j].Label
+	})
+	return res, 0, nil
+}
+
+// withLabels restricts the given query to the todos having all the given labels
+func withLabels(query ledger.Query, labels []string) ledger.Query {
+	if len(labels) == 0 {
+		return query
+	}
+	return withFilter(query, func(todo model.Todo) bool {
+		return todo.HasLabels(labels...)
+	})
+}
+
+// withFilter restricts the given query to the todos also matched by the given filter
+func withFilter(query ledger.Query, wants ledger.Wants) ledger.Query {
+	prev := query.Wants
+	if prev == nil {
+		query.Wants = wants
+		return query
+	}
+	query.Wants = func(todo model.Todo) bool {
+		return prev(todo) && wants(todo)
+	}
+	return query
+}
+
+// compileFilter compiles the filter expression given as value of the named request parameter
+func compileFilter(param, expr string) (ledger.Wants, error) {
+	wants, err := filter.Compile(expr)
+	if err != nil {
+		return nil, paramError{Parameter: param, Err: err}
+	}
+	return wants, nil
+}
+
+// The queries below use the ledger indexes by status and assignee, so they don't scan all the todos.
+
+// allQuery selects all the todos, optionally only the ones of the given assignee
+func allQuery(assignee string) ledger.Query {
+	return ledger.Query{Assignee: assignee}
+}
+
+// backlogQuery selects the ongoing todos, optionally only the ones of the given assignee
+func backlogQuery(assignee string) ledger.Query {
+	return ledger.Query{
+		Statuses: []apiv1.Status{apiv1.Pending, apiv1.Assigned},
+		Assignee: assignee,
+	}
+}
+
+// overdueQuery selects the ongoing todos whose due time is past, optionally only the ones of the given assignee
+func overdueQuery(now time.Time, assignee string) ledger.Query {
+	return withFilter(backlogQuery(assignee), func(todo model.Todo) bool {
+		return todo.IsOverdue(now)
+	})
+}
+
+// completedQuery selects the completed todos, optionally only the ones of the given assignee
+func completedQuery(assignee string) ledger.Query {
+	return ledger.Query{
+		Statuses: []apiv1.Status{apiv1.Completed},
+		Assignee: assignee,
+	}
+}
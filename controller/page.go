@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+const (
+	// MaxPageLimit caps the number of todos returned by a single list request
+	MaxPageLimit = 1000
+)
+
+var (
+	ErrInvalidLimit  = errors.New("limit must be a positive number")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// page describes which slice of a todo listing the client wants
+type page struct {
+	// Sort is the sort criteria, see orderBy
+	Sort string
+	// Limit caps the number of todos returned; zero means MaxPageLimit
+	Limit int
+	// Cursor, if not empty, is the opaque token returned by the previous page
+	Cursor string
+}
+
+// pageFromRequest returns the page requested by the sort, limit and cursor query parameters
+func pageFromRequest(r *http.Request) (page, error) {
+	query := r.URL.Query()
+	pg := page{
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return page{}, ErrInvalidLimit
+		}
+		pg.Limit = limit
+	}
+	return pg, nil
+}
+
+// cursor is the position of a page in a listing: the last todo of the previous page.
+// It carries all the fields the orders depend on, so it stays valid even if that todo
+// changes or goes away meanwhile.
+type cursor struct {
+	Sort     string         `json:"s,omitempty"`
+	ID       store.ID       `json:"i"`
+	Title    string         `json:"t,omitempty"`
+	Status   apiv1.Status   `json:"st,omitempty"`
+	Priority apiv1.Priority `json:"p,omitempty"`
+	Updated  time.Time      `json:"u"`
+	Due      *time.Time     `json:"d,omitempty"`
+}
+
+func encodeCursor(sortBy string, item ledger.Item) string {
+	data, err := json.Marshal(cursor{
+		Sort:     sortBy,
+		ID:       item.ID,
+		Title:    item.Todo.Title,
+		Status:   item.Todo.Status,
+		Priority: item.Todo.Priority,
+		Updated:  item.Todo.LastUpdateTime,
+		Due:      item.Todo.DueTime,
+	})
+	if err != nil {
+		// can't happen: all the fields are plain values
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the item after which the page starts. The cursor must
+// have been created for the same sort criteria.
+func decodeCursor(sortBy, token string) (*ledger.Item, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cur cursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID == store.NullID {
+		return nil, ErrInvalidCursor
+	}
+	if cur.Sort != sortBy {
+		return nil, fmt.Errorf("%w: created for sort %q, not %q", ErrInvalidCursor, cur.Sort, sortBy)
+	}
+	return &ledger.Item{
+		ID: cur.ID,
+		Todo: &model.Todo{
+			Title:          cur.Title,
+			Status:         cur.Status,
+			Priority:       cur.Priority,
+			LastUpdateTime: cur.Updated,
+			DueTime:        cur.Due,
+		},
+	}, nil
+}
+
+// listTodos returns the requested page of the todos selected by the query, and the cursor
+// of the next page, which is empty if this is the last page.
+func (ctrl *Controller) listTodos(query ledger.Query, pg page) (ledger.Items, string, int, error) {
+	order, err := orderBy(pg.Sort)
+	if err != nil {
+		return nil, "", http.StatusBadRequest, err
+	}
+	limit := pg.Limit
+	if limit <= 0 || limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	query.Order = order
+	query.Limit = limit
+	if pg.Cursor != "" {
+		query.After, err = decodeCursor(pg.Sort, pg.Cursor)
+		if err != nil {
+			return nil, "", http.StatusBadRequest, err
+		}
+	}
+	items, more, err := ctrl.ld.Query(query)
+	if err != nil {
+		return nil, "", http.StatusUnprocessableEntity, err
+	}
+	if !more {
+		return items, "", 0, nil
+	}
+	return items, encodeCursor(pg.Sort, items[len(items)-1]), 0, nil
+}
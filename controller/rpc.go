@@ -0,0 +1,489 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// rpcMethod implements a JSON-RPC method on behalf of the given actor, if known.
+// Its result is ignored for notifications.
+type rpcMethod func(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError)
+
+func (ctrl *Controller) rpcMethods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"todo.list":      ctrl.rpcTodoList,
+		"todo.show":      ctrl.rpcTodoShow,
+		"todo.create":    ctrl.rpcTodoCreate,
+		"todo.update":    ctrl.rpcTodoUpdate,
+		"todo.label":     ctrl.rpcTodoLabel,
+		"todo.unlabel":   ctrl.rpcTodoUnlabel,
+		"todo.unassign":  ctrl.rpcTodoUnassign,
+		"todo.reassign":  ctrl.rpcTodoReassign,
+		"todo.reopen":    ctrl.rpcTodoReopen,
+		"todo.complete":  ctrl.rpcTodoComplete,
+		"todo.delete":    ctrl.rpcTodoDelete,
+		"todo.merge":     ctrl.rpcTodoMerge,
+		"todo.history":   ctrl.rpcTodoHistory,
+		"backlog.list":   ctrl.rpcBacklogList,
+		"completed.list": ctrl.rpcCompletedList,
+		"overdue.list":   ctrl.rpcOverdueList,
+		"labels.list":    ctrl.rpcLabelsList,
+		"todo.search":    ctrl.rpcTodoSearch,
+	}
+}
+
+/*
+RPC serves JSON-RPC 2.0 requests, single or batched. Test with this curl command:
+
+curl -H "Content-Type: application/json" -d '{"jsonrpc":"2.0","method":"backlog.list","id":1}' http://localhost:8181/rpc
+*/
+func (ctrl *Controller) RPC(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil && err != io.EOF {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := r.Body.Close(); err != nil {
+		sendError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	actor := actorFromRequest(r)
+	if !json.Valid(body) {
+		sendRPC(w, rpcErrorResponse(nil, apiv1.RPCParseError, "parse error"))
+		return
+	}
+
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '[' {
+		resp := ctrl.rpcCall(r.Context(), actor, body)
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		sendRPC(w, resp)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		sendRPC(w, rpcErrorResponse(nil, apiv1.RPCInvalidRequest, "invalid request"))
+		return
+	}
+	resps := make([]*apiv1.RPCResponse, 0, len(batch))
+	for _, raw := range batch {
+		if resp := ctrl.rpcCall(r.Context(), actor, raw); resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		// all notifications
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	sendRPC(w, resps)
+}
+
+// rpcCall processes a single JSON-RPC request. Returns nil if no response is due.
+func (ctrl *Controller) rpcCall(ctx context.Context, actor string, raw json.RawMessage) *apiv1.RPCResponse {
+	var req apiv1.RPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return rpcErrorResponse(nil, apiv1.RPCInvalidRequest, "invalid request")
+	}
+	if !isValidRPCID(req.ID) {
+		return rpcErrorResponse(nil, apiv1.RPCInvalidRequest, "invalid request id")
+	}
+	if req.JSONRPC != apiv1.RPCVersion || req.Method == "" {
+		return rpcErrorResponse(req.ID, apiv1.RPCInvalidRequest, "invalid request")
+	}
+
+	method, ok := ctrl.rpc[req.Method]
+	if !ok {
+		if req.IsNotification() {
+			return nil
+		}
+		return rpcErrorResponse(req.ID, apiv1.RPCMethodNotFound, "method not found: "+req.Method)
+	}
+
+	log.Printf("API: rpc: calling %q", req.Method)
+	result, rpcErr := method(ctx, actor, req.Params)
+	if req.IsNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return &apiv1.RPCResponse{
+			JSONRPC: apiv1.RPCVersion,
+			Error:   rpcErr,
+			ID:      req.ID,
+		}
+	}
+	return &apiv1.RPCResponse{
+		JSONRPC: apiv1.RPCVersion,
+		Result:  result,
+		ID:      req.ID,
+	}
+}
+
+func (ctrl *Controller) rpcTodoList(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	return ctrl.rpcList(params, allQuery)
+}
+
+func (ctrl *Controller) rpcBacklogList(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	return ctrl.rpcList(params, backlogQuery)
+}
+
+func (ctrl *Controller) rpcCompletedList(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	return ctrl.rpcList(params, completedQuery)
+}
+
+func (ctrl *Controller) rpcOverdueList(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	var listParams apiv1.RPCListParams
+	if rpcErr := decodeRPCParams(params, &listParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if listParams.Sort == "" {
+		listParams.Sort = sortByDue
+	}
+	return ctrl.rpcListPage(overdueQuery(time.Now(), listParams.Assignee), listParams)
+}
+
+func (ctrl *Controller) rpcList(params json.RawMessage, makeQuery func(assignee string) ledger.Query) (*apiv1.Result, *apiv1.RPCError) {
+	var listParams apiv1.RPCListParams
+	if rpcErr := decodeRPCParams(params, &listParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	return ctrl.rpcListPage(withLabels(makeQuery(listParams.Assignee), listParams.Labels), listParams)
+}
+
+func (ctrl *Controller) rpcListPage(query ledger.Query, listParams apiv1.RPCListParams) (*apiv1.Result, *apiv1.RPCError) {
+	if listParams.Limit < 0 {
+		return nil, rpcInvalidParams(ErrInvalidLimit)
+	}
+	if listParams.Query != "" {
+		matches, err := compileFilter("query", listParams.Query)
+		if err != nil {
+			return nil, rpcServerError(http.StatusBadRequest, err)
+		}
+		query = withFilter(query, matches)
+	}
+	items, next, code, err := ctrl.listTodos(query, page{
+		Sort:   listParams.Sort,
+		Limit:  listParams.Limit,
+		Cursor: listParams.Cursor,
+	})
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return &apiv1.Result{Items: items.ToAPIv1(), NextCursor: next}, nil
+}
+
+func (ctrl *Controller) rpcTodoSearch(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	var searchParams apiv1.RPCSearchParams
+	if rpcErr := decodeRPCParams(params, &searchParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if searchParams.Limit < 0 {
+		return nil, rpcInvalidParams(ErrInvalidLimit)
+	}
+	items, code, err := ctrl.searchTodos(searchParams.Text, searchParams.Statuses, searchParams.Assignee, searchParams.Limit)
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return &apiv1.Result{Items: items.ToAPIv1()}, nil
+}
+
+func (ctrl *Controller) rpcLabelsList(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	if rpcErr := decodeRPCParams(params, &struct{}{}); rpcErr != nil {
+		return nil, rpcErr
+	}
+	labels, code, err := ctrl.listLabels()
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return &apiv1.Result{Labels: labels}, nil
+}
+
+func (ctrl *Controller) rpcTodoShow(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, false)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.getTodo(store.ID(todoParams.ID))
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoCreate(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, false, true)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todoID, todo, code, err := ctrl.createTodo(ctx, actor, *todoParams.Todo)
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(todoID, todo), nil
+}
+
+func (ctrl *Controller) rpcTodoUpdate(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, true)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.updateTodo(ctx, store.ID(todoParams.ID), actor, *todoParams.Todo, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoLabel(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, true)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.labelTodo(ctx, store.ID(todoParams.ID), actor, todoParams.Todo.Labels, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoUnlabel(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, true)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.unlabelTodo(ctx, store.ID(todoParams.ID), actor, todoParams.Todo.Labels, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoUnassign(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, false)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.unassignTodo(ctx, store.ID(todoParams.ID), actor, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoReassign(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, true)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.reassignTodo(ctx, store.ID(todoParams.ID), actor, todoParams.Todo.Assignee, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+// rpcTodoReopen reopens a completed todo; the todo parameter is optional
+// and only its assignee is used.
+func (ctrl *Controller) rpcTodoReopen(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, false)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	assignee := ""
+	if todoParams.Todo != nil {
+		assignee = todoParams.Todo.Assignee
+	}
+	todo, code, err := ctrl.reopenTodo(ctx, store.ID(todoParams.ID), actor, assignee, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoComplete(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, false)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.completeTodo(ctx, store.ID(todoParams.ID), actor, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoDelete(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, false)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	todo, code, err := ctrl.deleteTodo(ctx, store.ID(todoParams.ID), actor, todoParams.match())
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(store.ID(todoParams.ID), todo), nil
+}
+
+func (ctrl *Controller) rpcTodoMerge(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	var mergeParams apiv1.RPCMergeParams
+	if rpcErr := decodeRPCParams(params, &mergeParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if mergeParams.ID1 == "" || mergeParams.ID2 == "" {
+		return nil, rpcInvalidParams(errors.New("missing todo ids"))
+	}
+	if !isTodoID(mergeParams.ID1) || !isTodoID(mergeParams.ID2) {
+		return nil, rpcInvalidParams(errInvalidTodoID)
+	}
+	mergedID, merged, code, err := ctrl.mergeTodos(ctx, store.ID(mergeParams.ID1), store.ID(mergeParams.ID2), actor)
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return rpcItemResult(mergedID, merged), nil
+}
+
+func (ctrl *Controller) rpcTodoHistory(ctx context.Context, actor string, params json.RawMessage) (*apiv1.Result, *apiv1.RPCError) {
+	todoParams, rpcErr := todoParamsFromRPC(params, true, false)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	history, code, err := ctrl.todoHistory(ctx, store.ID(todoParams.ID))
+	if err != nil {
+		return nil, rpcServerError(code, err)
+	}
+	return &apiv1.Result{History: history.ToAPIv1()}, nil
+}
+
+// rpcTodoParams wraps the API parameters to add convenience methods
+type rpcTodoParams struct {
+	apiv1.RPCTodoParams
+}
+
+func (params rpcTodoParams) match() revisionMatch {
+	if params.Revision == nil {
+		return nil
+	}
+	return revisionEquals(*params.Revision)
+}
+
+func todoParamsFromRPC(params json.RawMessage, needID, needTodo bool) (rpcTodoParams, *apiv1.RPCError) {
+	var todoParams rpcTodoParams
+	if rpcErr := decodeRPCParams(params, &todoParams.RPCTodoParams); rpcErr != nil {
+		return todoParams, rpcErr
+	}
+	if needID && todoParams.ID == "" {
+		return todoParams, rpcInvalidParams(errors.New("missing todo id"))
+	}
+	if !isTodoID(todoParams.ID) {
+		return todoParams, rpcInvalidParams(errInvalidTodoID)
+	}
+	if needTodo && todoParams.Todo == nil {
+		return todoParams, rpcInvalidParams(errors.New("missing todo"))
+	}
+	return todoParams, nil
+}
+
+// errInvalidTodoID is returned for the ids which can't belong to a todo
+var errInvalidTodoID = errors.New("invalid todo id")
+
+// isTodoID tells if id may belong to a todo: the namespaced ids belong to other records
+// sharing the store, like the histories and the tokens.
+func isTodoID(id apiv1.ID) bool {
+	return !strings.Contains(string(id), store.NamespaceSeparator)
+}
+
+// decodeRPCParams decodes the by-name parameters. Missing parameters are fine,
+// the methods will check if the required ones are set.
+func decodeRPCParams(params json.RawMessage, v any) *apiv1.RPCError {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return rpcInvalidParams(err)
+	}
+	return nil
+}
+
+// isValidRPCID returns true if the given request id is absent, a string, a number or null.
+func isValidRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var val any
+	if err := json.Unmarshal(id, &val); err != nil {
+		return false
+	}
+	switch val.(type) {
+	case nil, string, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func rpcItemResult(todoID store.ID, todo model.Todo) *apiv1.Result {
+	apiTodo := todo.ToAPIv1()
+	return &apiv1.Result{
+		Items: []apiv1.Item{
+			{
+				ID:   apiv1.ID(todoID),
+				Todo: &apiTodo,
+			},
+		},
+	}
+}
+
+func rpcInvalidParams(err error) *apiv1.RPCError {
+	return &apiv1.RPCError{
+		Code:    apiv1.RPCInvalidParams,
+		Message: "invalid params: " + err.Error(),
+	}
+}
+
+func rpcServerError(code int, err error) *apiv1.RPCError {
+	return &apiv1.RPCError{
+		Code:    apiv1.RPCServerError,
+		Message: err.Error(),
+		Data:    apiError(errorCode(code, err), err),
+	}
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) *apiv1.RPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &apiv1.RPCResponse{
+		JSONRPC: apiv1.RPCVersion,
+		Error: &apiv1.RPCError{
+			Code:    code,
+			Message: message,
+		},
+		ID: id,
+	}
+}
+
+func sendRPC(w http.ResponseWriter, resp any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/search"
+)
+
+var ErrMissingText = errors.New("missing search text")
+
+/*
+Search lists the todos whose title and description include all the words of the text
+(?text=), most relevant first. Words match also as prefixes, e.g. "depl" matches "deploy".
+The results can be restricted to a assignee (?assignee=) or to some statuses (?status=,
+can be repeated), and capped (?limit=, defaults to MaxPageLimit). Test with this curl command:
+
+curl "http://localhost:8080/search?text=deploy+front&status=pending"
+*/
+func (ctrl *Controller) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			sendError(w, http.StatusBadRequest, ErrInvalidLimit)
+			return
+		}
+	}
+	var statuses []apiv1.Status
+	for _, status := range query["status"] {
+		statuses = append(statuses, apiv1.Status(status))
+	}
+	items, code, err := ctrl.searchTodos(query.Get("text"), statuses, query.Get("assignee"), limit)
+	if err != nil {
+		sendError(w, code, err)
+		return
+	}
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Items: items.ToAPIv1(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+func (ctrl *Controller) searchTodos(text string, statuses []apiv1.Status, assignee string, limit int) (ledger.Items, int, error) {
+	if len(search.Tokenize(text)) == 0 {
+		return nil, http.StatusBadRequest, ErrMissingText
+	}
+	wanted := make(map[apiv1.Status]bool, len(statuses))
+	for _, status := range statuses {
+		switch status {
+		case apiv1.Pending, apiv1.Assigned, apiv1.Completed, apiv1.Deleted:
+			wanted[status] = true
+		default:
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid status %q", status)
+		}
+	}
+	if limit <= 0 || limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	items, err := ctrl.ld.Search(text, func(todo model.Todo) bool {
+		return (len(wanted) == 0 || wanted[todo.Status]) && (assignee == "" || todo.Assignee == assignee)
+	}, limit)
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	return items, 0, nil
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+const (
+	// sortByPriority sorts the most urgent todos first, then the ones due earlier
+	sortByPriority = "priority"
+	// sortByDue sorts the todos due earlier first, then the most urgent; todos without a due time go last
+	sortByDue = "due"
+	// sortByUpdated sorts the most recently updated todos first
+	sortByUpdated = "updated"
+	// sortByTitle sorts the todos alphabetically by title
+	sortByTitle = "title"
+	// sortByStatus sorts the todos by status, following their lifecycle: pending, assigned, completed, deleted
+	sortByStatus = "status"
+
+	// sortDescending prefixed to a sort criteria reverses it, e.g. "-updated" sorts the oldest updates first
+	sortDescending = "-"
+)
+
+// orderBy returns the ledger order corresponding to the given sort criteria.
+// An empty criteria returns a nil order, which sorts the todos by ID.
+func orderBy(by string) (ledger.Order, error) {
+	criteria, reverse := strings.CutPrefix(by, sortDescending)
+	var order ledger.Order
+	switch criteria {
+	case "":
+		if reverse {
+			return nil, fmt.Errorf("unsupported sort criteria %q", by)
+		}
+		return nil, nil
+	case sortByPriority:
+		order = func(a, b model.Todo) int {
+			if c := comparePriority(a, b); c != 0 {
+				return c
+			}
+			return compareDue(a, b)
+		}
+	case sortByDue:
+		order = func(a, b model.Todo) int {
+			if c := compareDue(a, b); c != 0 {
+				return c
+			}
+			return comparePriority(a, b)
+		}
+	case sortByUpdated:
+		order = func(a, b model.Todo) int {
+			return b.LastUpdateTime.Compare(a.LastUpdateTime)
+		}
+	case sortByTitle:
+		order = func(a, b model.Todo) int {
+			return strings.Compare(a.Title, b.Title)
+		}
+	case sortByStatus:
+		order = func(a, b model.Todo) int {
+			return statusRank(a.Status) - statusRank(b.Status)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sort criteria %q", by)
+	}
+	if reverse {
+		return func(a, b model.Todo) int {
+			return order(b, a)
+		}, nil
+	}
+	return order, nil
+}
+
+func comparePriority(a, b model.Todo) int {
+	return model.PriorityRank(b.Priority) - model.PriorityRank(a.Priority)
+}
+
+func compareDue(a, b model.Todo) int {
+	switch {
+	case a.DueTime == nil && b.DueTime == nil:
+		return 0
+	case a.DueTime == nil:
+		return 1
+	case b.DueTime == nil:
+		return -1
+	default:
+		return a.DueTime.Compare(*b.DueTime)
+	}
+}
+
+// statusRank returns the position of a status in the todo lifecycle; unknown statuses go last
+func statusRank(status apiv1.Status) int {
+	switch status {
+	case apiv1.Pending:
+		return 0
+	case apiv1.Assigned:
+		return 1
+	case apiv1.Completed:
+		return 2
+	case apiv1.Deleted:
+		return 3
+	default:
+		return 4
+	}
+}
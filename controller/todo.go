@@ -3,29 +3,37 @@ package controller
 import (
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
-	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/auth"
 	"github.com/gotestbootcamp/go-todo-app/store"
 )
 
 func (ctrl *Controller) TodoIndex(w http.ResponseWriter, r *http.Request) {
-	items, err := ctrl.ld.Filter(func(todo model.Todo) bool {
-		return true
-	})
+	pg, err := pageFromRequest(r)
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	wants, err := compileFilter("q", r.URL.Query().Get("q"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, next, code, err := ctrl.listTodos(withFilter(withLabels(allQuery(""), r.URL.Query()["label"]), wants), pg)
+	if err != nil {
+		sendError(w, code, err)
 		return
 	}
 
 	resp := apiv1.Response{
 		Status: apiv1.ResponseSuccess,
 		Result: &apiv1.Result{
-			Items: items.ToAPIv1(),
+			Items:      items.ToAPIv1(),
+			NextCursor: next,
 		},
 	}
 
@@ -39,13 +47,14 @@ func (ctrl *Controller) TodoIndex(w http.ResponseWriter, r *http.Request) {
 func (ctrl *Controller) TodoShow(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
+	todo, code, err := ctrl.getTodo(store.ID(todoID))
 	if err != nil {
-		sendError(w, http.StatusNotFound, err)
+		sendError(w, code, err)
 		return
 	}
 
 	apiTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
 	sendItem(w, apiv1.ID(todoID), &apiTodo)
 }
 
@@ -61,17 +70,9 @@ func (ctrl *Controller) TodoCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todo := model.NewFromAPIv1(apiTodo)
-	log.Printf("API: got object %v", todo)
-
-	todoID, err := ctrl.uuidGen.NewUUID()
+	todoID, _, code, err := ctrl.createTodo(r.Context(), actorFromRequest(r), apiTodo)
 	if err != nil {
-		sendError(w, http.StatusServiceUnavailable, err)
-		return
-	}
-
-	if err := ctrl.ld.Set(store.ID(todoID), todo); err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
@@ -87,36 +88,39 @@ func (ctrl *Controller) TodoUpdate(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
+	todo, code, err := ctrl.updateTodo(r.Context(), store.ID(todoID), actorFromRequest(r), apiTodo, revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusNotFound, err)
+		sendError(w, code, err)
 		return
 	}
-	log.Printf("API: got object %v", todoID)
 
-	if err := todo.Describe(apiTodo.Description); err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
-		return
-	}
-	if err := todo.Assign(apiTodo.Assignee); err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
+	sendItem(w, apiv1.ID(todoID), &resTodo)
+}
+
+func (ctrl *Controller) TodoLabel(w http.ResponseWriter, r *http.Request) {
+	apiTodo, code, err := todoFromRequest(r)
+	if err != nil {
+		sendError(w, code, err)
 		return
 	}
 
-	log.Printf("API: updated object %v as: %q", todoID, todo)
-
-	err = ctrl.ld.Set(store.ID(todoID), todo)
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	todo, code, err := ctrl.labelTodo(r.Context(), store.ID(todoID), actorFromRequest(r), apiTodo.Labels, revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
 	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
 	sendItem(w, apiv1.ID(todoID), &resTodo)
 }
 
-func (ctrl *Controller) TodoComplete(w http.ResponseWriter, r *http.Request) {
-	_, code, err := todoFromRequest(r)
+func (ctrl *Controller) TodoUnlabel(w http.ResponseWriter, r *http.Request) {
+	apiTodo, code, err := todoFromRequest(r)
 	if err != nil {
 		sendError(w, code, err)
 		return
@@ -124,32 +128,39 @@ func (ctrl *Controller) TodoComplete(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
+	todo, code, err := ctrl.unlabelTodo(r.Context(), store.ID(todoID), actorFromRequest(r), apiTodo.Labels, revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusNotFound, err)
+		sendError(w, code, err)
 		return
 	}
-	log.Printf("API: got object %v", todoID)
 
-	if err := todo.Complete(); err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
+	sendItem(w, apiv1.ID(todoID), &resTodo)
+}
+
+func (ctrl *Controller) TodoUnassign(w http.ResponseWriter, r *http.Request) {
+	_, code, err := todoFromRequest(r)
+	if err != nil {
+		sendError(w, code, err)
 		return
 	}
 
-	log.Printf("API: completed object %v as: %q", todoID, todo)
-
-	err = ctrl.ld.Set(store.ID(todoID), todo)
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	todo, code, err := ctrl.unassignTodo(r.Context(), store.ID(todoID), actorFromRequest(r), revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
 	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
 	sendItem(w, apiv1.ID(todoID), &resTodo)
 }
 
-func (ctrl *Controller) TodoDelete(w http.ResponseWriter, r *http.Request) {
-	_, code, err := todoFromRequest(r)
+func (ctrl *Controller) TodoReassign(w http.ResponseWriter, r *http.Request) {
+	apiTodo, code, err := todoFromRequest(r)
 	if err != nil {
 		sendError(w, code, err)
 		return
@@ -157,77 +168,122 @@ func (ctrl *Controller) TodoDelete(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	todoID := vars["todoID"]
-	todo, err := ctrl.ld.Get(store.ID(todoID))
+	todo, code, err := ctrl.reassignTodo(r.Context(), store.ID(todoID), actorFromRequest(r), apiTodo.Assignee, revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusNotFound, err)
+		sendError(w, code, err)
 		return
 	}
-	log.Printf("API: got object %v", todoID)
 
-	if err := todo.Delete(); err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
+	sendItem(w, apiv1.ID(todoID), &resTodo)
+}
+
+func (ctrl *Controller) TodoReopen(w http.ResponseWriter, r *http.Request) {
+	apiTodo, code, err := todoFromRequest(r)
+	if err != nil {
+		sendError(w, code, err)
 		return
 	}
 
-	log.Printf("API: deleted object %v as: %q", todoID, todo)
-
-	err = ctrl.ld.Set(store.ID(todoID), todo)
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	todo, code, err := ctrl.reopenTodo(r.Context(), store.ID(todoID), actorFromRequest(r), apiTodo.Assignee, revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
 	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
 	sendItem(w, apiv1.ID(todoID), &resTodo)
 }
 
-func (ctrl *Controller) TodoMerge(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id1 := vars["todoID1"]
-	id2 := vars["todoID2"]
-
-	todo1, err := ctrl.ld.Get(store.ID(id1))
+func (ctrl *Controller) TodoComplete(w http.ResponseWriter, r *http.Request) {
+	_, code, err := todoFromRequest(r)
 	if err != nil {
-		sendError(w, http.StatusNotFound, err)
+		sendError(w, code, err)
 		return
 	}
-	todo2, err := ctrl.ld.Get(store.ID(id2))
+
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	todo, code, err := ctrl.completeTodo(r.Context(), store.ID(todoID), actorFromRequest(r), revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusNotFound, err)
+		sendError(w, code, err)
 		return
 	}
-	log.Printf("API: got objects %v - %v", todo1, todo2)
 
-	merged, err := model.Merge(todo1, todo2)
+	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
+	sendItem(w, apiv1.ID(todoID), &resTodo)
+}
+
+func (ctrl *Controller) TodoDelete(w http.ResponseWriter, r *http.Request) {
+	_, code, err := todoFromRequest(r)
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
-	err = ctrl.ld.Delete(store.ID(id1))
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	todo, code, err := ctrl.deleteTodo(r.Context(), store.ID(todoID), actorFromRequest(r), revisionFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
-	err = ctrl.ld.Delete(store.ID(id2))
+
+	resTodo := todo.ToAPIv1()
+	w.Header().Set("ETag", etag(todo.Revision))
+	sendItem(w, apiv1.ID(todoID), &resTodo)
+}
+
+func (ctrl *Controller) TodoMerge(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id1 := vars["todoID1"]
+	id2 := vars["todoID2"]
+
+	mergedID, merged, code, err := ctrl.mergeTodos(r.Context(), store.ID(id1), store.ID(id2), actorFromRequest(r))
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
 
-	mergedID, err := ctrl.uuidGen.NewUUID()
+	resTodo := merged.ToAPIv1()
+	sendItem(w, apiv1.ID(mergedID), &resTodo)
+}
+
+func (ctrl *Controller) TodoHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	todoID := vars["todoID"]
+	history, code, err := ctrl.todoHistory(r.Context(), store.ID(todoID))
 	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
+		sendError(w, code, err)
 		return
 	}
-	err = ctrl.ld.Set(store.ID(mergedID), merged)
-	if err != nil {
-		sendError(w, http.StatusUnprocessableEntity, err)
-		return
+
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			History: history.ToAPIv1(),
+		},
 	}
 
-	resTodo := merged.ToAPIv1()
-	sendItem(w, apiv1.ID(mergedID), &resTodo)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// actorFromRequest returns who is performing the request, empty if unknown.
+// The authenticated principal, if any, can't be overridden by the actor header.
+func actorFromRequest(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return p.Name
+	}
+	return r.Header.Get(apiv1.ActorHeader)
 }
 
 func todoFromRequest(r *http.Request) (apiv1.Todo, int, error) {
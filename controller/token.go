@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+var errAuthDisabled = errors.New("authentication not enabled")
+
+// adminDisabled serves the admin routes when the authentication is disabled
+func adminDisabled(w http.ResponseWriter, r *http.Request) {
+	sendError(w, http.StatusNotImplemented, errAuthDisabled)
+}
+
+func (ctrl *Controller) TokenIndex(w http.ResponseWriter, r *http.Request) {
+	if ctrl.tokens == nil {
+		sendError(w, http.StatusNotImplemented, errAuthDisabled)
+		return
+	}
+	var apiTokens []apiv1.Token
+	for _, id := range ctrl.tokens.List() {
+		tok, err := ctrl.tokens.Get(id)
+		if err != nil {
+			// revoked meanwhile
+			continue
+		}
+		apiTokens = append(apiTokens, tok.ToAPIv1(id, ""))
+	}
+	sendTokens(w, http.StatusOK, apiTokens...)
+}
+
+/*
+Test with this curl command:
+
+curl -u admin:password -H "Content-Type: application/json" -d '{"name":"ci"}' http://localhost:8080/admin/tokens
+*/
+func (ctrl *Controller) TokenCreate(w http.ResponseWriter, r *http.Request) {
+	if ctrl.tokens == nil {
+		sendError(w, http.StatusNotImplemented, errAuthDisabled)
+		return
+	}
+	var apiToken apiv1.Token
+	defer r.Body.Close()
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&apiToken); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, err := ctrl.uuidGen.NewUUID()
+	if err != nil {
+		sendError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	tok, secret, err := ctrl.tokens.Create(r.Context(), id, apiToken.Name)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	// the only time the secret is disclosed
+	sendTokens(w, http.StatusCreated, tok.ToAPIv1(id, secret))
+}
+
+func (ctrl *Controller) TokenRevoke(w http.ResponseWriter, r *http.Request) {
+	if ctrl.tokens == nil {
+		sendError(w, http.StatusNotImplemented, errAuthDisabled)
+		return
+	}
+	id := mux.Vars(r)["tokenID"]
+	tok, err := ctrl.tokens.Get(id)
+	if err != nil {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := ctrl.tokens.Revoke(r.Context(), id); err != nil {
+		code := http.StatusInternalServerError
+		if errors.As(err, &store.ErrNotFound{}) {
+			// revoked meanwhile
+			code = http.StatusNotFound
+		}
+		sendError(w, code, err)
+		return
+	}
+	sendTokens(w, http.StatusOK, tok.ToAPIv1(id, ""))
+}
+
+func sendTokens(w http.ResponseWriter, code int, apiTokens ...apiv1.Token) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Tokens: apiTokens,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+var errWebhooksDisabled = errors.New("webhooks not enabled")
+
+func (ctrl *Controller) WebhookIndex(w http.ResponseWriter, r *http.Request) {
+	if ctrl.webhooks == nil {
+		sendError(w, http.StatusNotImplemented, errWebhooksDisabled)
+		return
+	}
+	var apiWebhooks []apiv1.Webhook
+	for _, id := range ctrl.webhooks.List() {
+		sub, err := ctrl.webhooks.Get(id)
+		if err != nil {
+			// unsubscribed meanwhile
+			continue
+		}
+		apiWebhooks = append(apiWebhooks, sub.ToAPIv1(id, false))
+	}
+	sendWebhooks(w, http.StatusOK, apiWebhooks...)
+}
+
+/*
+Test with this curl command:
+
+curl -u admin:password -H "Content-Type: application/json" -d '{"url":"http://localhost:9090/hook","events":["assign","complete"]}' http://localhost:8080/webhooks
+*/
+func (ctrl *Controller) WebhookCreate(w http.ResponseWriter, r *http.Request) {
+	if ctrl.webhooks == nil {
+		sendError(w, http.StatusNotImplemented, errWebhooksDisabled)
+		return
+	}
+	var apiWebhook apiv1.Webhook
+	defer r.Body.Close()
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1048576)).Decode(&apiWebhook); err != nil {
+		sendError(w, http.StatusBadRequest, err)
+		return
+	}
+	sub, err := webhook.NewFromAPIv1(apiWebhook)
+	if err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	id, err := ctrl.uuidGen.NewUUID()
+	if err != nil {
+		sendError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err := ctrl.webhooks.Subscribe(r.Context(), id, sub); err != nil {
+		sendError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	// the only time the secret is disclosed
+	sendWebhooks(w, http.StatusCreated, sub.ToAPIv1(id, true))
+}
+
+func (ctrl *Controller) WebhookShow(w http.ResponseWriter, r *http.Request) {
+	if ctrl.webhooks == nil {
+		sendError(w, http.StatusNotImplemented, errWebhooksDisabled)
+		return
+	}
+	id := mux.Vars(r)["webhookID"]
+	sub, err := ctrl.webhooks.Get(id)
+	if err != nil {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	sendWebhooks(w, http.StatusOK, sub.ToAPIv1(id, false))
+}
+
+func (ctrl *Controller) WebhookDelete(w http.ResponseWriter, r *http.Request) {
+	if ctrl.webhooks == nil {
+		sendError(w, http.StatusNotImplemented, errWebhooksDisabled)
+		return
+	}
+	id := mux.Vars(r)["webhookID"]
+	sub, err := ctrl.webhooks.Get(id)
+	if err != nil {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := ctrl.webhooks.Unsubscribe(r.Context(), id); err != nil {
+		sendError(w, http.StatusNotFound, err)
+		return
+	}
+	sendWebhooks(w, http.StatusOK, sub.ToAPIv1(id, false))
+}
+
+func (ctrl *Controller) WebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if ctrl.webhooks == nil {
+		sendError(w, http.StatusNotImplemented, errWebhooksDisabled)
+		return
+	}
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			DeadLetters: ctrl.webhooks.DeadLetters(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+func sendWebhooks(w http.ResponseWriter, code int, apiWebhooks ...apiv1.Webhook) {
+	resp := apiv1.Response{
+		Status: apiv1.ResponseSuccess,
+		Result: &apiv1.Result{
+			Webhooks: apiWebhooks,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,143 @@
+package feed
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+const (
+	// DefaultBufferSize is the default number of past events kept for replay
+	DefaultBufferSize = 1024
+	// subscriberQueueSize is how many events a subscriber can lag behind before being dropped
+	subscriberQueueSize = 64
+)
+
+// Broker fans out the published events to all the subscribers.
+// Broker is safe for concurrent use.
+type Broker struct {
+	lock   sync.Mutex
+	seq    uint64
+	size   int
+	buffer []apiv1.Event
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription delivers the events published after its creation.
+type Subscription struct {
+	// Events delivers the events in sequence order. It is closed when the subscription
+	// is canceled, when the broker is closed, or when the subscriber falls too much behind:
+	// in this case, the subscriber should subscribe again to resume from the last event it got.
+	Events <-chan apiv1.Event
+	events chan apiv1.Event
+	broker *Broker
+}
+
+// NewBroker creates a new Broker keeping the given number of past events for replay.
+func NewBroker(size int) *Broker {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Broker{
+		size: size,
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the next sequence number to a event and delivers it to the subscribers.
+// Returns the event as published.
+func (b *Broker) Publish(op apiv1.Operation, item apiv1.Item, mergedFrom ...apiv1.ID) apiv1.Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.seq++
+	ev := apiv1.Event{
+		Seq:        b.seq,
+		Time:       time.Now(),
+		Operation:  op,
+		Item:       item,
+		MergedFrom: mergedFrom,
+	}
+	if len(b.buffer) == b.size {
+		copy(b.buffer, b.buffer[1:])
+		b.buffer = b.buffer[:len(b.buffer)-1]
+	}
+	b.buffer = append(b.buffer, ev)
+
+	for sub := range b.subs {
+		select {
+		case sub.events <- ev:
+		default:
+			log.Printf("feed: dropping subscriber lagging behind at event %d", ev.Seq)
+			b.drop(sub)
+		}
+	}
+	return ev
+}
+
+// Subscribe creates a new subscription. If lastSeq is not zero, returns also the buffered
+// events following lastSeq, and reports if any event following lastSeq is no longer
+// available for replay, e.g. because it was evicted from the buffer, or because lastSeq
+// comes from a previous run of the process.
+func (b *Broker) Subscribe(lastSeq uint64) (*Subscription, []apiv1.Event, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	events := make(chan apiv1.Event, subscriberQueueSize)
+	sub := &Subscription{
+		Events: events,
+		events: events,
+		broker: b,
+	}
+	if b.closed {
+		close(events)
+		return sub, nil, false
+	}
+	b.subs[sub] = struct{}{}
+
+	if lastSeq == 0 {
+		return sub, nil, false
+	}
+	if lastSeq > b.seq {
+		return sub, append([]apiv1.Event(nil), b.buffer...), true
+	}
+	var replay []apiv1.Event
+	for _, ev := range b.buffer {
+		if ev.Seq > lastSeq {
+			replay = append(replay, ev)
+		}
+	}
+	missed := len(replay) > 0 && replay[0].Seq != lastSeq+1
+	return sub, replay, missed
+}
+
+// Close ends all the subscriptions, closing their Events channels, so the subscribers
+// stop waiting for events, e.g. to let the server shut down. The subscriptions created
+// afterwards get their Events channel already closed. Events can still be published.
+func (b *Broker) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+}
+
+// Cancel stops the delivery of the events and closes the Events channel.
+// Canceling a subscription more than once is fine.
+func (sub *Subscription) Cancel() {
+	sub.broker.lock.Lock()
+	defer sub.broker.lock.Unlock()
+	sub.broker.drop(sub)
+}
+
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
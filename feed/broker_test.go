@@ -0,0 +1,119 @@
+package feed_test
+
+import (
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/feed"
+)
+
+func publish(b *feed.Broker, count int) {
+	for i := 0; i < count; i++ {
+		b.Publish(apiv1.OpCreate, apiv1.Item{ID: "1"})
+	}
+}
+
+func seqs(events []apiv1.Event) []uint64 {
+	var res []uint64
+	for _, ev := range events {
+		res = append(res, ev.Seq)
+	}
+	return res
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	b := feed.NewBroker(4)
+	publish(b, 6)
+
+	tests := []struct {
+		name           string
+		lastSeq        uint64
+		expectedSeqs   []uint64
+		expectedMissed bool
+	}{
+		{"new subscriber", 0, nil, false},
+		{"up to date", 6, nil, false},
+		{"buffered", 3, []uint64{4, 5, 6}, false},
+		{"evicted", 1, []uint64{3, 4, 5, 6}, true},
+		{"previous run", 42, []uint64{3, 4, 5, 6}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sub, replay, missed := b.Subscribe(tc.lastSeq)
+			defer sub.Cancel()
+			if got := seqs(replay); len(got) != len(tc.expectedSeqs) {
+				t.Fatalf("expected %v got %v", tc.expectedSeqs, got)
+			} else {
+				for i := range got {
+					if got[i] != tc.expectedSeqs[i] {
+						t.Fatalf("expected %v got %v", tc.expectedSeqs, got)
+					}
+				}
+			}
+			if missed != tc.expectedMissed {
+				t.Fatalf("expected missed=%v got %v", tc.expectedMissed, missed)
+			}
+		})
+	}
+}
+
+func TestSubscriptionDelivery(t *testing.T) {
+	b := feed.NewBroker(feed.DefaultBufferSize)
+	sub, _, _ := b.Subscribe(0)
+	publish(b, 3)
+	for expected := uint64(1); expected <= 3; expected++ {
+		ev := <-sub.Events
+		if ev.Seq != expected {
+			t.Fatalf("expected seq %d got %d", expected, ev.Seq)
+		}
+	}
+	sub.Cancel()
+	sub.Cancel()
+	if _, ok := <-sub.Events; ok {
+		t.Fatalf("events not closed after cancel")
+	}
+}
+
+func TestSubscriptionLagging(t *testing.T) {
+	b := feed.NewBroker(feed.DefaultBufferSize)
+	sub, _, _ := b.Subscribe(0)
+	defer sub.Cancel()
+	publish(b, 1000)
+
+	var last uint64
+	for ev := range sub.Events {
+		last = ev.Seq
+	}
+	if last == 0 || last >= 1000 {
+		t.Fatalf("lagging subscriber not dropped, last event %d", last)
+	}
+
+	// the dropped subscriber can resume from the last event it got
+	sub, replay, missed := b.Subscribe(last)
+	defer sub.Cancel()
+	if missed || len(replay) != int(1000-last) {
+		t.Fatalf("unexpected replay: %d events missed=%v", len(replay), missed)
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := feed.NewBroker(feed.DefaultBufferSize)
+	sub, _, _ := b.Subscribe(0)
+	publish(b, 1)
+	b.Close()
+
+	if ev, ok := <-sub.Events; !ok || ev.Seq != 1 {
+		t.Fatalf("pending event not delivered: %v ok=%v", ev, ok)
+	}
+	if _, ok := <-sub.Events; ok {
+		t.Fatalf("events not closed after close")
+	}
+	sub.Cancel()
+
+	late, _, _ := b.Subscribe(0)
+	publish(b, 1)
+	if _, ok := <-late.Events; ok {
+		t.Fatalf("subscribed after close")
+	}
+	late.Cancel()
+}
@@ -0,0 +1,5 @@
+// Package feed implements a in-memory change feed: a sequence of events describing the
+// operations performed on the todos, which subscribers can follow as they happen.
+// The most recent events are kept in a bounded buffer, so subscribers which lost the
+// connection can resume from the last event they got.
+package feed
@@ -0,0 +1,20 @@
+// Package filter implements a small expression language to select todos, like
+//
+//	status in (pending, assigned) and assignee = "ana" and updated > 2026-01-01 and title ~ "deploy"
+//
+// Expressions are made of comparisons between a field and a value, combined with
+// "and", "or", "not" and parentheses; "and" binds tighter than "or".
+// The fields are title, description, assignee, status, priority, label, due and updated.
+// The operators are = and != (equality), <, <=, > and >= (order: priority, due and updated only),
+// ~ and !~ (case insensitive substring match: text fields and label only) and "in" followed by a
+// parenthesized list of values, which matches any of them.
+// Values are either double quoted strings, with \" and \\ as escapes, or bare words made of
+// letters, digits and any of - _ . : + @ /.
+// Times are either dates (2006-01-02, UTC), which cover the whole day, or RFC3339 timestamps;
+// todos without a due time never match a comparison on due, except !=.
+// The label field compares against each label of a todo: label = "ops" selects the todos having
+// the "ops" label, while label != "ops" selects the ones lacking it.
+//
+// Compile turns an expression in a ledger.Wants predicate, or returns a *SyntaxError
+// locating the offending part of the expression.
+package filter
@@ -0,0 +1,267 @@
+package filter
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+// field compiles the comparisons involving a todo field
+type field interface {
+	compile(op, value token) (ledger.Wants, error)
+	compileIn(values []token) (ledger.Wants, error)
+}
+
+var fields = map[string]field{
+	"title":       textField(func(todo model.Todo) string { return todo.Title }),
+	"description": textField(func(todo model.Todo) string { return todo.Description }),
+	"assignee":    textField(func(todo model.Todo) string { return todo.Assignee }),
+	"status":      statusField{},
+	"priority":    priorityField{},
+	"label":       labelField{},
+	"due":         timeField(func(todo model.Todo) *time.Time { return todo.DueTime }),
+	"updated": timeField(func(todo model.Todo) *time.Time {
+		return &todo.LastUpdateTime
+	}),
+}
+
+func fieldNames() string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func unsupported(op token) error {
+	return op.errorf("operator %q not supported by this field", op.text)
+}
+
+// textField compares strings: exactly, or by case insensitive substring
+type textField func(todo model.Todo) string
+
+func (get textField) compile(op, value token) (ledger.Wants, error) {
+	expected := value.text
+	switch op.text {
+	case "=":
+		return func(todo model.Todo) bool { return get(todo) == expected }, nil
+	case "!=":
+		return func(todo model.Todo) bool { return get(todo) != expected }, nil
+	case "~", "!~":
+		match := op.text == "~"
+		expected = strings.ToLower(expected)
+		return func(todo model.Todo) bool {
+			return strings.Contains(strings.ToLower(get(todo)), expected) == match
+		}, nil
+	default:
+		return nil, unsupported(op)
+	}
+}
+
+func (get textField) compileIn(values []token) (ledger.Wants, error) {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value.text] = true
+	}
+	return func(todo model.Todo) bool { return set[get(todo)] }, nil
+}
+
+// statusField compares statuses, which must be valid
+type statusField struct{}
+
+func parseStatus(value token) (apiv1.Status, error) {
+	status := apiv1.Status(strings.ToLower(value.text))
+	switch status {
+	case apiv1.Pending, apiv1.Assigned, apiv1.Completed, apiv1.Deleted:
+		return status, nil
+	default:
+		return "", value.errorf("invalid status %q, expected one of pending, assigned, completed, deleted", value.text)
+	}
+}
+
+func (statusField) compile(op, value token) (ledger.Wants, error) {
+	if op.text != "=" && op.text != "!=" {
+		return nil, unsupported(op)
+	}
+	status, err := parseStatus(value)
+	if err != nil {
+		return nil, err
+	}
+	match := op.text == "="
+	return func(todo model.Todo) bool { return (todo.Status == status) == match }, nil
+}
+
+func (statusField) compileIn(values []token) (ledger.Wants, error) {
+	set := make(map[apiv1.Status]bool, len(values))
+	for _, value := range values {
+		status, err := parseStatus(value)
+		if err != nil {
+			return nil, err
+		}
+		set[status] = true
+	}
+	return func(todo model.Todo) bool { return set[todo.Status] }, nil
+}
+
+// priorityField compares priorities by urgency: low < normal < high < urgent.
+// Todos without a priority have the normal one.
+type priorityField struct{}
+
+func parsePriority(value token) (int, error) {
+	rank := model.PriorityRank(apiv1.Priority(strings.ToLower(value.text)))
+	if rank < 0 || value.text == "" {
+		return 0, value.errorf("invalid priority %q, expected one of low, normal, high, urgent", value.text)
+	}
+	return rank, nil
+}
+
+func (priorityField) compile(op, value token) (ledger.Wants, error) {
+	if op.text == "~" || op.text == "!~" {
+		return nil, unsupported(op)
+	}
+	expected, err := parsePriority(value)
+	if err != nil {
+		return nil, err
+	}
+	compare := compareInts(op.text)
+	return func(todo model.Todo) bool {
+		return compare(model.PriorityRank(todo.Priority), expected)
+	}, nil
+}
+
+func (priorityField) compileIn(values []token) (ledger.Wants, error) {
+	set := make(map[int]bool, len(values))
+	for _, value := range values {
+		rank, err := parsePriority(value)
+		if err != nil {
+			return nil, err
+		}
+		set[rank] = true
+	}
+	return func(todo model.Todo) bool { return set[model.PriorityRank(todo.Priority)] }, nil
+}
+
+// compareInts returns the comparison corresponding to a order operator
+func compareInts(op string) func(a, b int) bool {
+	switch op {
+	case "=":
+		return func(a, b int) bool { return a == b }
+	case "!=":
+		return func(a, b int) bool { return a != b }
+	case "<":
+		return func(a, b int) bool { return a < b }
+	case "<=":
+		return func(a, b int) bool { return a <= b }
+	case ">":
+		return func(a, b int) bool { return a > b }
+	default: // ">="
+		return func(a, b int) bool { return a >= b }
+	}
+}
+
+// labelField compares each label of a todo: it matches if any label does, or,
+// for the negated operators, if none does.
+type labelField struct{}
+
+func (labelField) compile(op, value token) (ledger.Wants, error) {
+	expected := value.text
+	switch op.text {
+	case "=", "!=":
+		match := op.text == "="
+		return func(todo model.Todo) bool { return todo.HasLabels(expected) == match }, nil
+	case "~", "!~":
+		match := op.text == "~"
+		expected = strings.ToLower(expected)
+		return func(todo model.Todo) bool {
+			for _, label := range todo.Labels {
+				if strings.Contains(strings.ToLower(label), expected) {
+					return match
+				}
+			}
+			return !match
+		}, nil
+	default:
+		return nil, unsupported(op)
+	}
+}
+
+func (labelField) compileIn(values []token) (ledger.Wants, error) {
+	return func(todo model.Todo) bool {
+		for _, value := range values {
+			if todo.HasLabels(value.text) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// timeField compares times against a interval: a whole day for dates, a instant for timestamps
+type timeField func(todo model.Todo) *time.Time
+
+// parseTime returns the interval [from, to) described by the value
+func parseTime(value token) (time.Time, time.Time, error) {
+	if day, err := time.Parse(time.DateOnly, value.text); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	if ts, err := time.Parse(time.RFC3339, value.text); err == nil {
+		return ts, ts.Add(time.Nanosecond), nil
+	}
+	return time.Time{}, time.Time{}, value.errorf("invalid time %q, expected a date (2006-01-02) or a RFC3339 timestamp", value.text)
+}
+
+func (get timeField) compile(op, value token) (ledger.Wants, error) {
+	from, to, err := parseTime(value)
+	if err != nil {
+		return nil, err
+	}
+	var match func(ts time.Time) bool
+	switch op.text {
+	case "=":
+		match = func(ts time.Time) bool { return !ts.Before(from) && ts.Before(to) }
+	case "!=":
+		return func(todo model.Todo) bool {
+			ts := get(todo)
+			return ts == nil || ts.Before(from) || !ts.Before(to)
+		}, nil
+	case "<":
+		match = func(ts time.Time) bool { return ts.Before(from) }
+	case "<=":
+		match = func(ts time.Time) bool { return ts.Before(to) }
+	case ">":
+		match = func(ts time.Time) bool { return !ts.Before(to) }
+	case ">=":
+		match = func(ts time.Time) bool { return !ts.Before(from) }
+	default:
+		return nil, unsupported(op)
+	}
+	return func(todo model.Todo) bool {
+		ts := get(todo)
+		return ts != nil && match(*ts)
+	}, nil
+}
+
+func (get timeField) compileIn(values []token) (ledger.Wants, error) {
+	var matches []ledger.Wants
+	for _, value := range values {
+		eq := token{kind: tokenOperator, text: "="}
+		wants, err := get.compile(eq, value)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, wants)
+	}
+	return func(todo model.Todo) bool {
+		for _, wants := range matches {
+			if wants(todo) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,131 @@
+package filter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/filter"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+func TestCompile(t *testing.T) {
+	updated := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	todo := model.Todo{
+		Title:          "Deploy the frontend",
+		Description:    `fix the "login" page`,
+		Assignee:       "ana",
+		Status:         apiv1.Assigned,
+		Priority:       apiv1.High,
+		DueTime:        &due,
+		Labels:         []string{"ops", "web"},
+		LastUpdateTime: updated,
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"", true},
+		{"   ", true},
+		{`status in (pending, assigned) and assignee = "ana" and updated > 2026-01-01 and title ~ "deploy"`, true},
+		{`status = pending`, false},
+		{`STATUS != Completed`, true},
+		{`assignee = ana`, true},
+		{`assignee = "Ana"`, false},
+		{`assignee in (bob, ana)`, true},
+		{`title ~ FRONT`, true},
+		{`title !~ front`, false},
+		{`description ~ "\"login\""`, true},
+		{`priority = high`, true},
+		{`priority > normal`, true},
+		{`priority >= urgent`, false},
+		{`priority in (low, urgent)`, false},
+		{`label = ops`, true},
+		{`label != ops`, false},
+		{`label ~ we`, true},
+		{`label in (db, web)`, true},
+		{`updated = 2026-03-10`, true},
+		{`updated < 2026-03-10`, false},
+		{`updated <= 2026-03-10`, true},
+		{`updated > 2026-03-10`, false},
+		{`updated >= 2026-03-10T12:00:00Z`, true},
+		{`updated > 2026-03-10T13:00:00+02:00`, true},
+		{`due < 2026-04-02 and due > 2026-03-31`, true},
+		{`due in (2026-03-31, 2026-04-01)`, true},
+		{`not status = assigned`, false},
+		{`status = pending or assignee = ana`, true},
+		{`status = pending or assignee = bob and title ~ deploy`, false},
+		{`(status = pending or assignee = ana) and title ~ deploy`, true},
+		{`not (label = ops or label = db)`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			wants, err := filter.Compile(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := wants(todo); got != tc.expected {
+				t.Fatalf("expected %v got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompileWithoutDue(t *testing.T) {
+	todo := model.New("foo")
+	for expr, expected := range map[string]bool{
+		"due < 2100-01-01":  false,
+		"due > 2000-01-01":  false,
+		"due != 2000-01-01": true,
+		"priority = normal": true,
+	} {
+		wants, err := filter.Compile(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := wants(todo); got != expected {
+			t.Fatalf("%s: expected %v got %v", expr, expected, got)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		offset int
+		length int
+	}{
+		{`mood = happy`, 0, 4},
+		{`status = happy`, 9, 5},
+		{`status < pending`, 7, 1},
+		{`title = "deploy`, 8, 7},
+		{`title = "a\nb"`, 10, 2},
+		{`title = `, 8, 0},
+		{`title deploy`, 6, 6},
+		{`title ! deploy`, 6, 1},
+		{`title = a and`, 13, 0},
+		{`title = a b`, 10, 1},
+		{`(title = a`, 10, 0},
+		{`status in pending`, 10, 7},
+		{`status in (pending assigned)`, 19, 8},
+		{`updated > yesterday`, 10, 9},
+		{`priority = ""`, 11, 2},
+		{`title = a & b`, 10, 1},
+		{`title = "àè" and mood = x`, 17, 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := filter.Compile(tc.expr)
+			var syntaxErr *filter.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Offset != tc.offset || syntaxErr.Length != tc.length {
+				t.Fatalf("expected error at %d+%d, got %d+%d: %v", tc.offset, tc.length, syntaxErr.Offset, syntaxErr.Length, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError describes a invalid expression, and where the problem is
+type SyntaxError struct {
+	// Offset is the position of the offending text in the expression, in characters from 0
+	Offset int
+	// Length is the length of the offending text, in characters. Zero at the end of the expression.
+	Length int
+	// Msg describes the problem
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at offset %d: %s", e.Offset, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of filter"
+	case tokenWord:
+		return "word"
+	case tokenString:
+		return "string"
+	case tokenOperator:
+		return "operator"
+	case tokenLParen:
+		return `"("`
+	case tokenRParen:
+		return `")"`
+	case tokenComma:
+		return `","`
+	default:
+		return "unknown token"
+	}
+}
+
+type token struct {
+	kind tokenKind
+	// text is the operator, the word or the unquoted string
+	text string
+	// offset and length locate the token in the expression, in characters
+	offset int
+	length int
+}
+
+func (tok token) String() string {
+	switch tok.kind {
+	case tokenWord, tokenOperator:
+		return fmt.Sprintf("%q", tok.text)
+	case tokenString:
+		return fmt.Sprintf("string %q", tok.text)
+	default:
+		return tok.kind.String()
+	}
+}
+
+// is returns true if the token is the given keyword, case insensitive
+func (tok token) is(keyword string) bool {
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (tok token) errorf(format string, args ...any) *SyntaxError {
+	return &SyntaxError{
+		Offset: tok.offset,
+		Length: tok.length,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// tokenize splits the expression in tokens; the last one is always tokenEOF
+func tokenize(expr string) ([]token, error) {
+	src := []rune(expr)
+	var tokens []token
+	pos := 0
+	for pos < len(src) {
+		ch := src[pos]
+		start := pos
+		switch {
+		case unicode.IsSpace(ch):
+			pos++
+			continue
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", offset: start, length: 1})
+			pos++
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", offset: start, length: 1})
+			pos++
+		case ch == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", offset: start, length: 1})
+			pos++
+		case ch == '=' || ch == '~':
+			tokens = append(tokens, token{kind: tokenOperator, text: string(ch), offset: start, length: 1})
+			pos++
+		case ch == '!' || ch == '<' || ch == '>':
+			op := string(ch)
+			pos++
+			if pos < len(src) && (src[pos] == '=' || (ch == '!' && src[pos] == '~')) {
+				op += string(src[pos])
+				pos++
+			}
+			if op == "!" {
+				return nil, &SyntaxError{Offset: start, Length: 1, Msg: `unexpected "!", did you mean "!=" or "!~"?`}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, offset: start, length: pos - start})
+		case ch == '"':
+			var sb strings.Builder
+			pos++
+			for {
+				if pos >= len(src) {
+					return nil, &SyntaxError{Offset: start, Length: pos - start, Msg: "unterminated string"}
+				}
+				if src[pos] == '"' {
+					pos++
+					break
+				}
+				if src[pos] == '\\' {
+					if pos+1 >= len(src) || (src[pos+1] != '"' && src[pos+1] != '\\') {
+						return nil, &SyntaxError{Offset: pos, Length: min(2, len(src)-pos), Msg: `invalid escape, only \" and \\ are allowed`}
+					}
+					pos++
+				}
+				sb.WriteRune(src[pos])
+				pos++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), offset: start, length: pos - start})
+		case isWordRune(ch):
+			for pos < len(src) && isWordRune(src[pos]) {
+				pos++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(src[start:pos]), offset: start, length: pos - start})
+		default:
+			return nil, &SyntaxError{Offset: start, Length: 1, Msg: fmt.Sprintf("unexpected character %q", ch)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, offset: len(src)}), nil
+}
+
+func isWordRune(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || strings.ContainsRune("-_.:+@/", ch)
+}
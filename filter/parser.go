@@ -0,0 +1,170 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+// Compile parses the given expression and returns the predicate selecting the todos
+// it matches. An empty (or blank) expression selects all the todos.
+// On failure, returns a *SyntaxError and the predicate must be ignored.
+func Compile(expr string) (ledger.Wants, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return func(todo model.Todo) bool {
+			return true
+		}, nil
+	}
+	wants, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, tok.errorf(`unexpected %v, expected "and", "or" or end of filter`, tok)
+	}
+	return wants, nil
+}
+
+// parser is a recursive descent parser for the grammar:
+//
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field operator value | field "in" "(" value { "," value } ")"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (ledger.Wants, error) {
+	wants, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		left := wants
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		wants = func(todo model.Todo) bool {
+			return left(todo) || right(todo)
+		}
+	}
+	return wants, nil
+}
+
+func (p *parser) parseAnd() (ledger.Wants, error) {
+	wants, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		left := wants
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		wants = func(todo model.Todo) bool {
+			return left(todo) && right(todo)
+		}
+	}
+	return wants, nil
+}
+
+func (p *parser) parseUnary() (ledger.Wants, error) {
+	tok := p.peek()
+	switch {
+	case tok.is("not"):
+		p.next()
+		wants, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(todo model.Todo) bool {
+			return !wants(todo)
+		}, nil
+	case tok.kind == tokenLParen:
+		p.next()
+		wants, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, closing.errorf(`unexpected %v, expected ")" closing the "(" at offset %d`, closing, tok.offset)
+		}
+		return wants, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (ledger.Wants, error) {
+	name := p.next()
+	if name.kind != tokenWord {
+		return nil, name.errorf("unexpected %v, expected a field name", name)
+	}
+	fld, ok := fields[strings.ToLower(name.text)]
+	if !ok {
+		return nil, name.errorf("unknown field %q, expected one of %s", name.text, fieldNames())
+	}
+
+	op := p.next()
+	if op.is("in") {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return fld.compileIn(values)
+	}
+	if op.kind != tokenOperator {
+		return nil, op.errorf(`unexpected %v, expected a operator after field %q`, op, name.text)
+	}
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, value.errorf("unexpected %v, expected a value for field %q", value, name.text)
+	}
+	return fld.compile(op, value)
+}
+
+func (p *parser) parseList() ([]token, error) {
+	if open := p.next(); open.kind != tokenLParen {
+		return nil, open.errorf(`unexpected %v, expected "(" starting the list of values`, open)
+	}
+	var values []token
+	for {
+		value := p.next()
+		if value.kind != tokenWord && value.kind != tokenString {
+			return nil, value.errorf("unexpected %v, expected a value", value)
+		}
+		values = append(values, value)
+		switch sep := p.next(); sep.kind {
+		case tokenComma:
+			continue
+		case tokenRParen:
+			return values, nil
+		default:
+			return nil, sep.errorf(`unexpected %v, expected "," or ")"`, sep)
+		}
+	}
+}
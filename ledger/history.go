@@ -0,0 +1,48 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// historyNamespace is the store namespace holding the todo histories
+const historyNamespace = "history"
+
+// MaxHistoryEntries caps the entries kept in the history of a todo, which is rewritten
+// by every change: beyond it, the oldest entries are dropped, except the first one,
+// which tells how the todo came to be, e.g. from a merge.
+const MaxHistoryEntries = 256
+
+func historyID(id store.ID) store.ID {
+	return store.Namespaced(historyNamespace, string(id))
+}
+
+// History returns the audit history of a todo, oldest entry first, up to MaxHistoryEntries.
+// Histories outlive their todos, so they are available also for the todos merged into others.
+// Returns store.ErrNotFound if nothing was ever recorded for the todo.
+func (ld *Ledger) History(ctx context.Context, id store.ID) (model.History, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	ctx, cancel := ld.storeContext(ctx)
+	defer cancel()
+	blob, err := ld.storer.Load(ctx, historyID(id))
+	if errors.Is(err, store.ErrNotFound{ID: historyID(id)}) {
+		return nil, store.ErrNotFound{ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return model.DeserializeHistory(blob)
+}
+
+// Record appends entries to the audit history of a todo, creating it if needed.
+// Histories are stored directly in the store, and are not cached by the ledger.
+// On failure, error is not nil.
+func (ld *Ledger) Record(ctx context.Context, id store.ID, entries ...model.HistoryEntry) error {
+	return ld.Update(ctx, func(tx *Tx) error {
+		return tx.Record(id, entries...)
+	})
+}
@@ -0,0 +1,112 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+func TestHistorySurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ldg.History(ctx, "1"); !errors.Is(err, store.ErrNotFound{ID: "1"}) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	todo := model.New("foo")
+	if err := ldg.Set(ctx, "1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Record(ctx, "1", model.NewHistoryEntry("alice", apiv1.OpCreate, model.Todo{}, todo)); err != nil {
+		t.Fatal(err)
+	}
+	before := todo
+	if err := todo.Assign("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Record(ctx, "1", model.NewHistoryEntry("bob", apiv1.OpAssign, before, todo)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new ledger over the same store must not mistake the history for a todo
+	ldg, err = ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ldg.Filter(func(todo model.Todo) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "1" {
+		t.Fatalf("unexpected items: %v", items)
+	}
+
+	history, err := ldg.History(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 entries got %d: %v", len(history), history)
+	}
+	if history[0].Operation != apiv1.OpCreate || history[0].Actor != "alice" {
+		t.Errorf("unexpected first entry: %+v", history[0])
+	}
+	if history[1].Operation != apiv1.OpAssign || history[1].Actor != "bob" {
+		t.Errorf("unexpected second entry: %+v", history[1])
+	}
+}
+
+func TestHistoryIsCapped(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todo := model.New("foo")
+	if err := ldg.Record(ctx, "1", model.NewHistoryEntry("alice", apiv1.OpCreate, model.Todo{}, todo)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < ledger.MaxHistoryEntries+10; i++ {
+		entry := model.NewHistoryEntry("bob", apiv1.OpDescribe, todo, todo)
+		entry.Actor = strconv.Itoa(i)
+		if err := ldg.Record(ctx, "1", entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := ldg.History(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != ledger.MaxHistoryEntries {
+		t.Fatalf("expected %d entries got %d", ledger.MaxHistoryEntries, len(history))
+	}
+	if history[0].Operation != apiv1.OpCreate {
+		t.Errorf("first entry dropped: %+v", history[0])
+	}
+	if first, last := history[1].Actor, history[len(history)-1].Actor; first != "11" || last != strconv.Itoa(ledger.MaxHistoryEntries+9) {
+		t.Errorf("unexpected entries kept: from %q to %q", first, last)
+	}
+}
@@ -1,22 +1,71 @@
 package ledger
 
 import (
+	"context"
 	"errors"
 	"log"
+	"sync"
+	"time"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
 	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/search"
 	"github.com/gotestbootcamp/go-todo-app/store"
 )
 
 var (
-	ErrNotFound = errors.New("object not found")
+	ErrNotFound         = errors.New("object not found")
+	ErrRevisionMismatch = errors.New("object revision mismatch")
 )
 
-// Ledger represents a Todo object store
+// Ledger represents a Todo object store. Ledger is safe for concurrent use:
+// readers don't block each other, while writers are serialized, including
+// the write to the durable store. Only the methods reaching the durable store
+// take a context, which bounds the store operations; the others are served from memory.
 type Ledger struct {
-	storer store.Storage
-	blobs  map[store.ID]store.Blob
+	lock    sync.RWMutex
+	storer  store.Storage
+	timeout time.Duration
+	objects map[store.ID]*object
+	// index is the full-text index of the cached todos
+	index *search.Index
+	// byStatus and byAssignee are the secondary indexes of the cached todos
+	byStatus   map[apiv1.Status]idSet
+	byAssignee map[string]idSet
+}
+
+// Option customizes a Ledger
+type Option func(ld *Ledger)
+
+// WithTimeout bounds every operation on the durable store to the given duration,
+// on top of the deadline of the context, if any. Zero means no bound.
+func WithTimeout(timeout time.Duration) Option {
+	return func(ld *Ledger) {
+		ld.timeout = timeout
+	}
+}
+
+// idSet is a set of object IDs
+type idSet map[store.ID]struct{}
+
+func (set idSet) add(id store.ID) {
+	set[id] = struct{}{}
+}
+
+// object is a todo as cached by the ledger: the blob as stored, and the decoded todo,
+// so readers don't need to deserialize it every time. Cached objects are never modified,
+// only replaced, so readers can keep using them after releasing the lock.
+type object struct {
+	blob store.Blob
+	todo model.Todo
+}
+
+func newObject(blob store.Blob) (*object, error) {
+	todo, err := model.DeserializeTodo(blob)
+	if err != nil {
+		return nil, err
+	}
+	return &object{blob: blob, todo: todo}, nil
 }
 
 // Item binds a Todo object with its ID. Note that IDs are managed and owned by the Ledger.
@@ -52,26 +101,44 @@ type Wants func(todo model.Todo) bool
 
 // New creates and initializes a new Ledger based on the given datastore and its contents.
 // To initialize itself, a Ledger eagerly loads all the content of the datastore.
+// The initial load is bounded only by the given context, not by the WithTimeout option.
 // Returns error if the initialization fails; in this case, the returned ledger instance must be ignored.
-func New(storer store.Storage) (*Ledger, error) {
-	items, err := storer.LoadAll()
-	if err != nil {
-		return nil, err
-	}
+func New(ctx context.Context, storer store.Storage, opts ...Option) (*Ledger, error) {
 	ld := Ledger{
 		storer: storer,
-		blobs:  make(map[store.ID]store.Blob, len(items)),
+		index:  search.NewIndex(),
+
+		byStatus:   make(map[apiv1.Status]idSet),
+		byAssignee: make(map[string]idSet),
 	}
+	for _, opt := range opts {
+		opt(&ld)
+	}
+	items, err := storer.LoadAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ld.objects = make(map[store.ID]*object, len(items))
 	for _, item := range items {
-		ld.blobs[item.ID] = item.Blob
+		if item.ID.Namespace() != "" {
+			// not a todo, e.g. a history
+			continue
+		}
+		obj, err := newObject(item.Blob)
+		if err != nil {
+			return nil, err
+		}
+		ld.cache(item.ID, obj)
 	}
-	log.Printf("ledger: loaded %d blobs", len(ld.blobs))
+	log.Printf("ledger: loaded %d blobs", len(ld.objects))
 	return &ld, nil
 
 }
 
 // Close deinitializes this ledger and closes the attached datastore.
 func (ld *Ledger) Close() error {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
 	return ld.storer.Close()
 }
 
@@ -79,17 +146,16 @@ func (ld *Ledger) Close() error {
 // On failure, the error value is not nil and the resulting collection
 // must be ignored.
 func (ld *Ledger) Filter(wants Wants) (Items, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
 	var items []Item
-	log.Printf("ledger: Filter: scanning %d blobs", len(ld.blobs))
-	for id, blob := range ld.blobs {
-		todo, err := model.DeserializeTodo(blob)
-		if err != nil {
-			return items, err
-		}
-		if !wants(todo) {
+	log.Printf("ledger: Filter: scanning %d objects", len(ld.objects))
+	for id, obj := range ld.objects {
+		if !wants(obj.todo) {
 			continue
 		}
 		log.Printf("ledger: Filter: object %v included", id)
+		todo := obj.todo.Clone()
 		items = append(items, Item{
 			ID:   id,
 			Todo: &todo,
@@ -100,64 +166,167 @@ func (ld *Ledger) Filter(wants Wants) (Items, error) {
 
 // Get returns a todo object from its id. On failure, error is not nil
 func (ld *Ledger) Get(id store.ID) (model.Todo, error) {
-	blob, ok := ld.blobs[id]
+	ld.lock.RLock()
+	obj, ok := ld.objects[id]
+	ld.lock.RUnlock()
 	if !ok {
 		return model.Todo{}, store.ErrNotFound{ID: id}
 	}
-	todo, err := model.DeserializeTodo(blob)
-	if err != nil {
-		return model.Todo{}, err
-	}
+	todo := obj.todo.Clone()
 	log.Printf("ledger: Set: retrieved from cache object %v: %s", id, todo.String())
 	return todo, nil
 }
 
-// Set creates or updates Todo objects in the store.
-func (ld *Ledger) Set(id store.ID, todo model.Todo) (rerr error) {
-	blob, err := todo.Serialize()
-	if err != nil {
-		return err
-	}
-	log.Printf("ledger: Set: %s (blob=%d bytes)", todo.String(), len(blob))
+// Set creates or updates Todo objects in the store, regardless of their current revision.
+func (ld *Ledger) Set(ctx context.Context, id store.ID, todo model.Todo) error {
+	_, err := ld.set(ctx, id, todo, nil)
+	return err
+}
+
+// CompareAndSet updates a Todo object in the store only if its current revision is the given one.
+// Returns the object as stored, including its new revision. If the current revision differs,
+// returns ErrRevisionMismatch and the stored object is left untouched.
+func (ld *Ledger) CompareAndSet(ctx context.Context, id store.ID, todo model.Todo, revision uint64) (model.Todo, error) {
+	return ld.set(ctx, id, todo, &revision)
+}
 
+func (ld *Ledger) set(ctx context.Context, id store.ID, todo model.Todo, revision *uint64) (_ model.Todo, rerr error) {
 	if id == store.NullID {
-		return errors.New("can't set null id")
+		return model.Todo{}, errors.New("can't set null id")
 	}
 
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	ctx, cancel := ld.storeContext(ctx)
+	defer cancel()
+
 	log.Printf("ledger: Set: updating object %v", id)
-	curBlob, found := ld.blobs[id]
-	if !found {
-		ld.blobs[id] = blob
-		log.Printf("ledger: Set: created cache object %v", id)
-		rerr = ld.storer.Create(id, blob)
-		log.Printf("ledger: Set: created store object %v err=%v", id, err)
-		return rerr
+	cur, found := ld.objects[id]
+	todo.Revision = 1
+	if found {
+		if revision != nil && *revision != cur.todo.Revision {
+			log.Printf("ledger: Set: object %v revision mismatch: current=%d expected=%d", id, cur.todo.Revision, *revision)
+			return model.Todo{}, ErrRevisionMismatch
+		}
+		todo.Revision = cur.todo.Revision + 1
+	} else if revision != nil {
+		return model.Todo{}, store.ErrNotFound{ID: id}
+	}
+
+	blob, err := todo.Serialize()
+	if err != nil {
+		return model.Todo{}, err
 	}
+	// cache the todo exactly as readers would load it from the store
+	obj, err := newObject(blob)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	log.Printf("ledger: Set: %s (blob=%d bytes)", todo.String(), len(blob))
+
 	// rollback
 	defer func() {
 		if rerr == nil {
 			return
 		}
 		log.Printf("ledger: Set: rollbacking object %v", id)
-		ld.blobs[id] = curBlob
+		if !found {
+			ld.uncache(id)
+			return
+		}
+		if errors.Is(rerr, ErrRevisionMismatch) {
+			// our cached copy is stale, let's refresh it
+			if freshBlob, err := ld.storer.Load(ctx, id); err == nil {
+				if fresh, err := newObject(freshBlob); err == nil {
+					ld.cache(id, fresh)
+					return
+				}
+			}
+		}
+		ld.cache(id, cur)
 	}()
-	ld.blobs[id] = blob
+	ld.cache(id, obj)
+	if !found {
+		log.Printf("ledger: Set: created cache object %v", id)
+		rerr = ld.storer.Create(ctx, id, blob)
+		log.Printf("ledger: Set: created store object %v err=%v", id, rerr)
+		return todo, rerr
+	}
 	log.Printf("ledger: Set: updated cache object %v", id)
-	rerr = ld.storer.Save(id, blob)
-	log.Printf("ledger: Set: updated store object %v err=%v", id, err)
-	return rerr
+	if cas, ok := ld.storer.(store.CompareAndSwapper); ok && revision != nil {
+		rerr = cas.CompareAndSwap(ctx, id, cur.blob, blob)
+		if errors.Is(rerr, store.ErrConflict{ID: id}) {
+			// someone else sharing the store updated the object behind our back
+			rerr = ErrRevisionMismatch
+		}
+	} else {
+		rerr = ld.storer.Save(ctx, id, blob)
+	}
+	log.Printf("ledger: Set: updated store object %v err=%v", id, rerr)
+	return todo, rerr
 }
 
 // Delete removes a Todo from the ledger. The ledger may recycle IDs of deleted objects.
 // On failure, error is not nil.
-func (ld *Ledger) Delete(id store.ID) error {
+func (ld *Ledger) Delete(ctx context.Context, id store.ID) error {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	ctx, cancel := ld.storeContext(ctx)
+	defer cancel()
+
 	log.Printf("ledger: Delete: deleting object %v", id)
-	err := ld.storer.Delete(id)
+	err := ld.storer.Delete(ctx, id)
 	if err != nil {
 		log.Printf("ledger: Delete:failed to delete object %v: %v", id, err)
 		return err
 	}
-	delete(ld.blobs, id)
+	ld.uncache(id)
 	log.Printf("ledger: Delete: deleted object %v", id)
 	return nil
 }
+
+// storeContext derives from ctx the context of a operation on the durable store
+func (ld *Ledger) storeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ld.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, ld.timeout)
+}
+
+// cache stores a todo object in the cache, keeping the indexes in sync. Must be called holding the write lock.
+func (ld *Ledger) cache(id store.ID, obj *object) {
+	ld.uncache(id)
+	ld.objects[id] = obj
+	ld.index.Add(id, obj.todo)
+	addToIndex(ld.byStatus, obj.todo.Status, id)
+	addToIndex(ld.byAssignee, obj.todo.Assignee, id)
+}
+
+// uncache removes a todo object from the cache, keeping the indexes in sync. Must be called holding the write lock.
+func (ld *Ledger) uncache(id store.ID) {
+	obj, ok := ld.objects[id]
+	if !ok {
+		return
+	}
+	delete(ld.objects, id)
+	ld.index.Remove(id)
+	removeFromIndex(ld.byStatus, obj.todo.Status, id)
+	removeFromIndex(ld.byAssignee, obj.todo.Assignee, id)
+}
+
+func addToIndex[K comparable](index map[K]idSet, key K, id store.ID) {
+	set, ok := index[key]
+	if !ok {
+		set = make(idSet)
+		index[key] = set
+	}
+	set.add(id)
+}
+
+func removeFromIndex[K comparable](index map[K]idSet, key K, id store.ID) {
+	set := index[key]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
@@ -0,0 +1,249 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+func TestSetRollback(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	expErr := errors.New("injected error")
+	st.Error = expErr
+
+	if err := ldg.Set(ctx, "1", model.New("bar")); !errors.Is(err, expErr) {
+		t.Fatalf("expected %v got %v", expErr, err)
+	}
+	todo, err := ldg.Get("1")
+	if err != nil || todo.Title != "foo" {
+		t.Fatalf("update not rolled back: %v err=%v", todo, err)
+	}
+
+	if err := ldg.Set(ctx, "2", model.New("baz")); !errors.Is(err, expErr) {
+		t.Fatalf("expected %v got %v", expErr, err)
+	}
+	if _, err := ldg.Get("2"); !errors.Is(err, store.ErrNotFound{ID: "2"}) {
+		t.Fatalf("creation not rolled back: err=%v", err)
+	}
+}
+
+// stalledStorage blocks its write operations until their context is done
+type stalledStorage struct {
+	store.Storage
+}
+
+func (st stalledStorage) Create(ctx context.Context, id store.ID, blob store.Blob) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (st stalledStorage) Save(ctx context.Context, id store.ID, blob store.Blob) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSetContext(t *testing.T) {
+	mem, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(context.Background(), stalledStorage{Storage: mem}, ledger.WithTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ldg.Set(context.Background(), "1", model.New("foo")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ldg.Set(ctx, "1", model.New("foo")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+	if _, err := ldg.Get("1"); !errors.Is(err, store.ErrNotFound{ID: "1"}) {
+		t.Fatalf("creation not rolled back: err=%v", err)
+	}
+}
+
+// run with `go test -race` to make the most out of this test
+
+func TestConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for iter := 0; iter < 100; iter++ {
+				id := store.ID(fmt.Sprintf("%d", iter%10))
+				switch (worker + iter) % 4 {
+				case 0:
+					_ = ldg.Set(ctx, id, model.New(fmt.Sprintf("todo#%d-%d", worker, iter)))
+				case 1:
+					_, _ = ldg.Get(id)
+				case 2:
+					_, _ = ldg.Filter(func(todo model.Todo) bool { return todo.IsOngoing() })
+				case 3:
+					_ = ldg.Delete(ctx, id)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// the cache and the durable store must agree
+	items, err := ldg.Filter(func(todo model.Todo) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(st.Blobs) {
+		t.Fatalf("ledger has %d items, store has %d", len(items), len(st.Blobs))
+	}
+}
+
+func TestCompareAndSet(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+	todo, err := ldg.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todo.Revision != 1 {
+		t.Fatalf("unexpected initial revision %d", todo.Revision)
+	}
+
+	_ = todo.Describe("first update")
+	updated, err := ldg.CompareAndSet(ctx, "1", todo, todo.Revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Revision != 2 {
+		t.Fatalf("unexpected revision %d after update", updated.Revision)
+	}
+
+	// todo still carries the old revision
+	_ = todo.Describe("stale update")
+	if _, err := ldg.CompareAndSet(ctx, "1", todo, todo.Revision); !errors.Is(err, ledger.ErrRevisionMismatch) {
+		t.Fatalf("expected %v got %v", ledger.ErrRevisionMismatch, err)
+	}
+	cur, err := ldg.Get("1")
+	if err != nil || cur.Description != "first update" || cur.Revision != 2 {
+		t.Fatalf("stale update applied: %v err=%v", cur, err)
+	}
+
+	if _, err := ldg.CompareAndSet(ctx, "2", todo, 1); !errors.Is(err, store.ErrNotFound{ID: "2"}) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestCompareAndSetStoreConflict(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	// another process sharing the store updates the object
+	other := model.New("updated elsewhere")
+	other.Revision = 2
+	blob, err := other.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Blobs["1"] = blob
+
+	todo, err := ldg.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = todo.Describe("local update")
+	if _, err := ldg.CompareAndSet(ctx, "1", todo, todo.Revision); !errors.Is(err, ledger.ErrRevisionMismatch) {
+		t.Fatalf("expected %v got %v", ledger.ErrRevisionMismatch, err)
+	}
+	// the stale cache is refreshed
+	cur, err := ldg.Get("1")
+	if err != nil || cur.Title != "updated elsewhere" {
+		t.Fatalf("cache not refreshed: %v err=%v", cur, err)
+	}
+}
+
+func TestLoadLegacyBlobs(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as serialized before the envelope was introduced
+	st.Blobs["1"] = []byte(`{"Title":"legacy","Status":"pending","LastUpdateTime":"2014-02-04T00:00:00Z","Revision":1}` + "\n")
+
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todo, err := ldg.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "legacy" || todo.Revision != 1 {
+		t.Fatalf("unexpected todo: %v", todo)
+	}
+
+	// updating the todo stores it in the current format
+	_ = todo.Describe("updated")
+	if _, err := ldg.CompareAndSet(ctx, "1", todo, todo.Revision); err != nil {
+		t.Fatal(err)
+	}
+	env, err := model.OpenEnvelope(st.Blobs["1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Version != model.TodoVersion {
+		t.Fatalf("expected version %d got %d", model.TodoVersion, env.Version)
+	}
+}
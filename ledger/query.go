@@ -0,0 +1,156 @@
+package ledger
+
+import (
+	"log"
+	"slices"
+	"sort"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Order compares two todo objects: returns a negative number if a comes before b,
+// a positive number if b comes before a, zero if the order is indifferent.
+type Order func(a, b model.Todo) int
+
+// Query selects a page of todo objects, in a stable order.
+type Query struct {
+	// Statuses, if not empty, selects only the todos having any of the given statuses
+	Statuses []apiv1.Status
+	// Assignee, if not empty, selects only the todos of the given assignee
+	Assignee string
+	// Wants further selects the todos; nil selects all of them. Selecting by Statuses and Assignee
+	// is cheaper, because the ledger keeps indexes by status and by assignee.
+	Wants Wants
+	// Order sorts the todos. Ties, and all the todos if nil, are sorted by ID.
+	Order Order
+	// After, if not nil, restricts the results to the todos which come after it in the order.
+	// The item doesn't need to be known to the ledger: its todo only needs the fields used by Order.
+	After *Item
+	// Limit caps the number of results; zero means no cap
+	Limit int
+}
+
+// compare sorts the items by the query order, then by ID
+func (q Query) compare(a, b Item) int {
+	if q.Order != nil {
+		if c := q.Order(*a.Todo, *b.Todo); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a.ID < b.ID:
+		return -1
+	case a.ID > b.ID:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// matches returns true if the todo is selected by the query
+func (q Query) matches(todo model.Todo) bool {
+	if q.Assignee != "" && todo.Assignee != q.Assignee {
+		return false
+	}
+	if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, todo.Status) {
+		return false
+	}
+	return q.Wants == nil || q.Wants(todo)
+}
+
+// candidates returns the sets of IDs of the objects which may be selected by the query, using
+// the smallest applicable index, or nil if no index applies. Must be called holding the lock.
+func (ld *Ledger) candidates(q Query) []idSet {
+	var byStatus []idSet
+	count := 0
+	seen := make(map[apiv1.Status]bool, len(q.Statuses))
+	for _, status := range q.Statuses {
+		if seen[status] {
+			continue
+		}
+		seen[status] = true
+		byStatus = append(byStatus, ld.byStatus[status])
+		count += len(ld.byStatus[status])
+	}
+	switch {
+	case q.Assignee != "" && (len(q.Statuses) == 0 || len(ld.byAssignee[q.Assignee]) <= count):
+		return []idSet{ld.byAssignee[q.Assignee]}
+	case len(q.Statuses) > 0:
+		return byStatus
+	default:
+		return nil
+	}
+}
+
+// Query returns the todo objects selected by the query in order, and true if more objects
+// follow the last one returned. Using the last returned item as After of the next query
+// pages through all the selected objects, even if they change meanwhile.
+// On failure, the error value is not nil and the resulting collection must be ignored.
+func (ld *Ledger) Query(q Query) (Items, bool, error) {
+	ld.lock.RLock()
+	// the cached objects are immutable: sort and page them without copying, then clone just the page
+	var selected []Item
+	add := func(id store.ID, obj *object) {
+		if !q.matches(obj.todo) {
+			return
+		}
+		item := Item{ID: id, Todo: &obj.todo}
+		if q.After != nil && q.compare(*q.After, item) >= 0 {
+			return
+		}
+		selected = append(selected, item)
+	}
+	if candidates := ld.candidates(q); candidates != nil {
+		for _, ids := range candidates {
+			for id := range ids {
+				add(id, ld.objects[id])
+			}
+		}
+	} else {
+		for id, obj := range ld.objects {
+			add(id, obj)
+		}
+	}
+	ld.lock.RUnlock()
+	log.Printf("ledger: Query: selected %d objects", len(selected))
+
+	sort.Slice(selected, func(i, j int) bool {
+		return q.compare(selected[i], selected[j]) < 0
+	})
+	more := false
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected, more = selected[:q.Limit], true
+	}
+	items := make(Items, 0, len(selected))
+	for _, item := range selected {
+		todo := item.Todo.Clone()
+		items = append(items, Item{ID: item.ID, Todo: &todo})
+	}
+	return items, more, nil
+}
+
+// Search returns the todo objects whose title and description include all the terms of the
+// given text, most relevant first, see search.Index. Wants, if not nil, further restricts
+// the results, and limit, if positive, caps their number.
+// On failure, the error value is not nil and the resulting collection must be ignored.
+func (ld *Ledger) Search(text string, wants Wants, limit int) (Items, error) {
+	ld.lock.RLock()
+	defer ld.lock.RUnlock()
+	hits := ld.index.Search(text)
+	log.Printf("ledger: Search: %d objects match %q", len(hits), text)
+	var items Items
+	for _, hit := range hits {
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+		obj, ok := ld.objects[hit.ID]
+		if !ok || (wants != nil && !wants(obj.todo)) {
+			continue
+		}
+		todo := obj.todo.Clone()
+		items = append(items, Item{ID: hit.ID, Todo: &todo})
+	}
+	return items, nil
+}
@@ -0,0 +1,212 @@
+package ledger_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+func TestQueryPages(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// titles in reverse order of ids, with a tie to be broken by id
+	titles := map[store.ID]string{"1": "e", "2": "d", "3": "c", "4": "c", "5": "a", "6": "b"}
+	for id, title := range titles {
+		todo := model.New(title)
+		if id == "6" {
+			todo.Status = apiv1.Completed
+		}
+		if err := ldg.Set(ctx, id, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byTitle := func(a, b model.Todo) int {
+		return strings.Compare(a.Title, b.Title)
+	}
+	pending := func(todo model.Todo) bool {
+		return todo.Status == apiv1.Pending
+	}
+
+	tests := []struct {
+		name     string
+		query    ledger.Query
+		expected string
+	}{
+		{"all by id", ledger.Query{}, "1 2 3 4 5 6"},
+		{"all by title", ledger.Query{Order: byTitle}, "5 6 3 4 2 1"},
+		{"pending by title", ledger.Query{Wants: pending, Order: byTitle}, "5 3 4 2 1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, limit := range []int{0, 1, 2, 4} {
+				var got []string
+				query := tc.query
+				query.Limit = limit
+				for {
+					items, more, err := ldg.Query(query)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if limit > 0 && len(items) > limit {
+						t.Fatalf("limit %d: got %d items", limit, len(items))
+					}
+					for _, item := range items {
+						got = append(got, string(item.ID))
+					}
+					if !more {
+						break
+					}
+					if len(items) == 0 {
+						t.Fatalf("limit %d: more items promised but none returned", limit)
+					}
+					query.After = &items[len(items)-1]
+				}
+				if res := strings.Join(got, " "); res != tc.expected {
+					t.Fatalf("limit %d: expected %q got %q", limit, tc.expected, res)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryAfterDeleted(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx := 1; idx <= 5; idx++ {
+		if err := ldg.Set(ctx, store.ID(fmt.Sprint(idx)), model.New("foo")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, more, err := ldg.Query(ledger.Query{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !more || len(items) != 2 {
+		t.Fatalf("unexpected first page: more=%v items=%v", more, items)
+	}
+	// the position is kept even if the last item of the page goes away
+	if err := ldg.Delete(ctx, items[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	items, more, err = ldg.Query(ledger.Query{After: &items[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more || len(items) != 3 || items[0].ID != "3" {
+		t.Fatalf("unexpected second page: more=%v items=%v", more, items)
+	}
+}
+
+func TestQueryIndexes(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos := map[store.ID]model.Todo{
+		"1": {Title: "a", Status: apiv1.Pending},
+		"2": {Title: "b", Status: apiv1.Assigned, Assignee: "ana"},
+		"3": {Title: "c", Status: apiv1.Completed, Assignee: "ana"},
+		"4": {Title: "d", Status: apiv1.Assigned, Assignee: "bob"},
+	}
+	for id, todo := range todos {
+		if err := ldg.Set(ctx, id, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(name string, q ledger.Query, expected string) {
+		t.Helper()
+		items, _, err := ldg.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, item := range items {
+			got = append(got, string(item.ID))
+		}
+		if res := strings.Join(got, " "); res != expected {
+			t.Fatalf("%s: expected %q got %q", name, expected, res)
+		}
+	}
+	ongoing := []apiv1.Status{apiv1.Pending, apiv1.Assigned}
+	check("ongoing", ledger.Query{Statuses: ongoing}, "1 2 4")
+	check("ana", ledger.Query{Assignee: "ana"}, "2 3")
+	check("ongoing of ana", ledger.Query{Statuses: ongoing, Assignee: "ana"}, "2")
+	check("duplicated statuses", ledger.Query{Statuses: []apiv1.Status{apiv1.Assigned, apiv1.Assigned}}, "2 4")
+	check("unknown assignee", ledger.Query{Assignee: "carl"}, "")
+	check("no deleted", ledger.Query{Statuses: []apiv1.Status{apiv1.Deleted}}, "")
+
+	// the indexes follow the changes, including the transactional ones
+	todo, err := ldg.Get("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := todo.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "2", todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Delete(ctx, "4"); err != nil {
+		t.Fatal(err)
+	}
+	err = ldg.Update(ctx, func(tx *ledger.Tx) error {
+		if err := tx.Delete("1"); err != nil {
+			return err
+		}
+		_, err := tx.Set("5", model.Todo{Title: "e", Status: apiv1.Assigned, Assignee: "ana"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("ongoing after changes", ledger.Query{Statuses: ongoing}, "5")
+	check("completed after changes", ledger.Query{Statuses: []apiv1.Status{apiv1.Completed}}, "2 3")
+	check("bob after changes", ledger.Query{Assignee: "bob"}, "")
+
+	// a failed transaction leaves the indexes untouched
+	err = ldg.Update(ctx, func(tx *ledger.Tx) error {
+		if _, err := tx.Set("6", model.Todo{Title: "f", Status: apiv1.Pending}); err != nil {
+			return err
+		}
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Fatal("expected the transaction to fail")
+	}
+	check("pending after failure", ledger.Query{Statuses: []apiv1.Status{apiv1.Pending}}, "")
+
+	// a new ledger rebuilds the indexes from the store
+	ldg, err = ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("ana after restart", ledger.Query{Assignee: "ana"}, "2 3 5")
+}
@@ -0,0 +1,93 @@
+package ledger_test
+
+import (
+	"context"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+func searchIDs(t *testing.T, ldg *ledger.Ledger, text string) []store.ID {
+	t.Helper()
+	items, err := ldg.Search(text, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []store.ID
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestSearchFollowsChanges(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "1", model.New("deploy backend")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "2", model.New("write docs")); err != nil {
+		t.Fatal(err)
+	}
+	if ids := searchIDs(t, ldg, "deploy"); len(ids) != 1 || ids[0] != "1" {
+		t.Fatalf("unexpected results: %v", ids)
+	}
+
+	todo, err := ldg.Get("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := todo.Describe("how to deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "2", todo); err != nil {
+		t.Fatal(err)
+	}
+	if ids := searchIDs(t, ldg, "deploy"); len(ids) != 2 {
+		t.Fatalf("unexpected results: %v", ids)
+	}
+
+	if err := ldg.Delete(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	err = ldg.Update(ctx, func(tx *ledger.Tx) error {
+		_, err := tx.Set("3", model.New("deploy frontend"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := searchIDs(t, ldg, "deploy"); len(ids) != 2 || ids[0] != "3" || ids[1] != "2" {
+		t.Fatalf("unexpected results: %v", ids)
+	}
+
+	// a new ledger rebuilds the index from the store
+	ldg, err = ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := searchIDs(t, ldg, "deploy"); len(ids) != 2 || ids[0] != "3" || ids[1] != "2" {
+		t.Fatalf("unexpected results after restart: %v", ids)
+	}
+
+	items, err := ldg.Search("deploy", func(todo model.Todo) bool {
+		return todo.Status == apiv1.Pending && todo.Title == "write docs"
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "2" {
+		t.Fatalf("unexpected filtered results: %v", items)
+	}
+}
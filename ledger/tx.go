@@ -0,0 +1,303 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Tx stages Set, CompareAndSet, Delete and Record operations, which are committed or rolled back together
+// by Ledger.Update. Reads through a Tx see the staged operations. A Tx is only valid
+// within the function passed to Ledger.Update, and is not safe for concurrent use.
+type Tx struct {
+	ld  *Ledger
+	ctx context.Context
+	// staged holds the blobs written by the transaction; nil means deleted
+	staged map[store.ID]store.Blob
+	ops    []store.Op
+	// undo holds the operations which revert ops, used if the storage can't apply batches
+	undo []store.Op
+	// onCommit holds the functions to run once committed
+	onCommit []func()
+}
+
+// Update runs fn within a transaction, blocking any other write to the ledger meanwhile.
+// If fn succeeds, the staged operations are committed together in the cache and in the store;
+// otherwise, or if the commit fails, none of them is applied and the error is returned.
+// If the store implements store.Batcher, the commit is atomic even in face of crashes;
+// otherwise the ledger applies the operations one by one, reverting them on failure.
+// The context bounds all the store operations of the transaction, including the commit.
+func (ld *Ledger) Update(ctx context.Context, fn func(tx *Tx) error) error {
+	ld.lock.Lock()
+	defer ld.lock.Unlock()
+	ctx, cancel := ld.storeContext(ctx)
+	defer cancel()
+
+	tx := &Tx{
+		ld:     ld,
+		ctx:    ctx,
+		staged: make(map[store.ID]store.Blob),
+	}
+	if err := fn(tx); err != nil {
+		log.Printf("ledger: Update: rollbacking %d operations: %v", len(tx.ops), err)
+		return err
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+	for _, fn := range tx.onCommit {
+		fn()
+	}
+	return nil
+}
+
+// OnCommit registers a function to run if and once the transaction is committed, before
+// any other write to the ledger, e.g. to notify the changes in the same order they were
+// committed. The function must not block, nor use the ledger.
+func (tx *Tx) OnCommit(fn func()) {
+	tx.onCommit = append(tx.onCommit, fn)
+}
+
+// Get returns a todo object from its id, including the changes staged in the transaction.
+// The namespaced ids don't belong to todos, and are never found. On failure, error is not nil
+func (tx *Tx) Get(id store.ID) (model.Todo, error) {
+	if id.Namespace() != "" {
+		return model.Todo{}, store.ErrNotFound{ID: id}
+	}
+	if _, staged := tx.staged[id]; !staged {
+		if obj, ok := tx.ld.objects[id]; ok {
+			return obj.todo.Clone(), nil
+		}
+	}
+	blob, found, err := tx.lookup(id)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	if !found {
+		return model.Todo{}, store.ErrNotFound{ID: id}
+	}
+	return model.DeserializeTodo(blob)
+}
+
+// Set stages the creation or the update of a todo object, regardless of its current revision.
+// Returns the object as it will be stored, including its new revision.
+func (tx *Tx) Set(id store.ID, todo model.Todo) (model.Todo, error) {
+	return tx.set(id, todo, nil)
+}
+
+// CompareAndSet stages the update of a todo object only if its current revision is the given one.
+// Returns the object as it will be stored, including its new revision. If the current revision
+// differs, returns ErrRevisionMismatch; the same error is returned by Ledger.Update if another
+// process sharing the store updates the object before the transaction is committed.
+func (tx *Tx) CompareAndSet(id store.ID, todo model.Todo, revision uint64) (model.Todo, error) {
+	return tx.set(id, todo, &revision)
+}
+
+func (tx *Tx) set(id store.ID, todo model.Todo, revision *uint64) (model.Todo, error) {
+	if id == store.NullID {
+		return model.Todo{}, errors.New("can't set null id")
+	}
+	curBlob, found, err := tx.lookup(id)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	todo.Revision = 1
+	if found {
+		cur, err := tx.Get(id)
+		if err != nil {
+			return model.Todo{}, err
+		}
+		if revision != nil && *revision != cur.Revision {
+			log.Printf("ledger: Update: object %v revision mismatch: current=%d expected=%d", id, cur.Revision, *revision)
+			return model.Todo{}, ErrRevisionMismatch
+		}
+		todo.Revision = cur.Revision + 1
+	} else if revision != nil {
+		return model.Todo{}, store.ErrNotFound{ID: id}
+	}
+	blob, err := todo.Serialize()
+	if err != nil {
+		return model.Todo{}, err
+	}
+	_, staged := tx.staged[id]
+	tx.put(id, curBlob, found, blob)
+	if revision != nil && !staged {
+		// let the store check nobody else changed the object since it was cached
+		tx.ops[len(tx.ops)-1].Old = curBlob
+	}
+	return todo, nil
+}
+
+// Delete stages the removal of a todo object. On failure, error is not nil.
+func (tx *Tx) Delete(id store.ID) error {
+	curBlob, found, err := tx.lookup(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return store.ErrNotFound{ID: id}
+	}
+	tx.staged[id] = nil
+	tx.ops = append(tx.ops, store.Op{Kind: store.OpDelete, ID: id})
+	tx.undo = append(tx.undo, store.Op{Kind: store.OpCreate, ID: id, Blob: curBlob})
+	return nil
+}
+
+// Record stages the append of entries to the audit history of a todo, creating it if needed.
+// The oldest entries beyond MaxHistoryEntries are dropped, see MaxHistoryEntries.
+func (tx *Tx) Record(id store.ID, entries ...model.HistoryEntry) error {
+	if id == store.NullID {
+		return errors.New("can't record null id")
+	}
+	hid := historyID(id)
+	curBlob, found, err := tx.lookup(hid)
+	if err != nil {
+		return err
+	}
+	var history model.History
+	if found {
+		history, err = model.DeserializeHistory(curBlob)
+		if err != nil {
+			return err
+		}
+	}
+	history = append(history, entries...)
+	if len(history) > MaxHistoryEntries {
+		history = append(history[:1:1], history[len(history)-MaxHistoryEntries+1:]...)
+	}
+	blob, err := history.Serialize()
+	if err != nil {
+		return err
+	}
+	log.Printf("ledger: Record: object %v has %d history entries", id, len(history))
+	tx.put(hid, curBlob, found, blob)
+	return nil
+}
+
+func (tx *Tx) put(id store.ID, curBlob store.Blob, found bool, blob store.Blob) {
+	tx.staged[id] = blob
+	if found {
+		tx.ops = append(tx.ops, store.Op{Kind: store.OpSave, ID: id, Blob: blob})
+		tx.undo = append(tx.undo, store.Op{Kind: store.OpSave, ID: id, Blob: curBlob})
+		return
+	}
+	tx.ops = append(tx.ops, store.Op{Kind: store.OpCreate, ID: id, Blob: blob})
+	tx.undo = append(tx.undo, store.Op{Kind: store.OpDelete, ID: id})
+}
+
+// lookup returns the current blob of a object, as seen by the transaction.
+// Only todos are cached by the ledger: other objects are loaded from the store.
+func (tx *Tx) lookup(id store.ID) (store.Blob, bool, error) {
+	if blob, ok := tx.staged[id]; ok {
+		return blob, blob != nil, nil
+	}
+	if id.Namespace() == "" {
+		obj, ok := tx.ld.objects[id]
+		if !ok {
+			return nil, false, nil
+		}
+		return obj.blob, true, nil
+	}
+	blob, err := tx.ld.storer.Load(tx.ctx, id)
+	if errors.Is(err, store.ErrNotFound{ID: id}) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return blob, true, nil
+}
+
+func (tx *Tx) commit() error {
+	log.Printf("ledger: Update: committing %d operations", len(tx.ops))
+	var err error
+	if batcher, ok := tx.ld.storer.(store.Batcher); ok {
+		err = batcher.Batch(tx.ctx, tx.ops)
+	} else {
+		err = tx.apply()
+	}
+	if err != nil {
+		log.Printf("ledger: Update: commit failed: %v", err)
+		if errors.As(err, &store.ErrConflict{}) {
+			// someone else sharing the store updated the objects behind our back
+			tx.refresh()
+			return ErrRevisionMismatch
+		}
+		return err
+	}
+	for id, blob := range tx.staged {
+		if id.Namespace() != "" {
+			continue
+		}
+		if blob == nil {
+			tx.ld.uncache(id)
+			continue
+		}
+		obj, err := newObject(blob)
+		if err != nil {
+			// can't happen: the blob was just serialized by Set
+			return err
+		}
+		tx.ld.cache(id, obj)
+	}
+	return nil
+}
+
+// refresh reloads from the store the cached objects which the transaction expected unchanged.
+func (tx *Tx) refresh() {
+	ctx, cancel := tx.ld.storeContext(context.WithoutCancel(tx.ctx))
+	defer cancel()
+	for _, op := range tx.ops {
+		if op.Old == nil || op.ID.Namespace() != "" {
+			continue
+		}
+		blob, err := tx.ld.storer.Load(ctx, op.ID)
+		if errors.Is(err, store.ErrNotFound{ID: op.ID}) {
+			tx.ld.uncache(op.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("ledger: Update: failed to refresh object %v: %v", op.ID, err)
+			continue
+		}
+		if obj, err := newObject(blob); err == nil {
+			tx.ld.cache(op.ID, obj)
+		}
+	}
+}
+
+// apply performs the operations one by one, reverting the ones already applied on failure.
+func (tx *Tx) apply() error {
+	for idx, op := range tx.ops {
+		if err := applyOp(tx.ctx, tx.ld.storer, op); err != nil {
+			// revert even if the failure is the context being done
+			undoCtx, cancel := tx.ld.storeContext(context.WithoutCancel(tx.ctx))
+			defer cancel()
+			for undoIdx := idx - 1; undoIdx >= 0; undoIdx-- {
+				undoOp := tx.undo[undoIdx]
+				if undoErr := applyOp(undoCtx, tx.ld.storer, undoOp); undoErr != nil {
+					log.Printf("ledger: Update: failed to revert object %v: %v", undoOp.ID, undoErr)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOp(ctx context.Context, storer store.Storage, op store.Op) error {
+	switch op.Kind {
+	case store.OpCreate:
+		return storer.Create(ctx, op.ID, op.Blob)
+	case store.OpSave:
+		if cas, ok := storer.(store.CompareAndSwapper); ok && op.Old != nil {
+			return cas.CompareAndSwap(ctx, op.ID, op.Old, op.Blob)
+		}
+		return storer.Save(ctx, op.ID, op.Blob)
+	default:
+		return storer.Delete(ctx, op.ID)
+	}
+}
@@ -0,0 +1,291 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/ledger"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+// sequentialStorage hides the optional interfaces of the wrapped Storage,
+// and fails once the write operation following the given number of successful ones.
+type sequentialStorage struct {
+	store.Storage
+	writes int
+	err    error
+}
+
+func (st *sequentialStorage) write() error {
+	if st.writes == 0 {
+		err := st.err
+		st.err = nil
+		return err
+	}
+	st.writes--
+	return nil
+}
+
+func (st *sequentialStorage) Create(ctx context.Context, id store.ID, blob store.Blob) error {
+	if err := st.write(); err != nil {
+		return err
+	}
+	return st.Storage.Create(ctx, id, blob)
+}
+
+func (st *sequentialStorage) Save(ctx context.Context, id store.ID, blob store.Blob) error {
+	if err := st.write(); err != nil {
+		return err
+	}
+	return st.Storage.Save(ctx, id, blob)
+}
+
+func (st *sequentialStorage) Delete(ctx context.Context, id store.ID) error {
+	if err := st.write(); err != nil {
+		return err
+	}
+	return st.Storage.Delete(ctx, id)
+}
+
+// swap deletes "1" and "2", and creates "3"
+func swap(tx *ledger.Tx) error {
+	if err := tx.Delete("1"); err != nil {
+		return err
+	}
+	if err := tx.Delete("2"); err != nil {
+		return err
+	}
+	_, err := tx.Set("3", model.New("baz"))
+	return err
+}
+
+func setupTx(t *testing.T, st store.Storage) *ledger.Ledger {
+	ctx := context.Background()
+	t.Helper()
+	ldg, err := ledger.New(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "1", model.New("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldg.Set(ctx, "2", model.New("bar")); err != nil {
+		t.Fatal(err)
+	}
+	return ldg
+}
+
+func expectTodos(t *testing.T, ldg *ledger.Ledger, st store.Storage, present, missing []store.ID) {
+	ctx := context.Background()
+	t.Helper()
+	for _, id := range present {
+		if _, err := ldg.Get(id); err != nil {
+			t.Errorf("expected %v in cache, got %v", id, err)
+		}
+		if _, err := st.Load(ctx, id); err != nil {
+			t.Errorf("expected %v in store, got %v", id, err)
+		}
+	}
+	for _, id := range missing {
+		if _, err := ldg.Get(id); !errors.Is(err, store.ErrNotFound{ID: id}) {
+			t.Errorf("unexpected %v in cache: %v", id, err)
+		}
+		if _, err := st.Load(ctx, id); !errors.Is(err, store.ErrNotFound{ID: id}) {
+			t.Errorf("unexpected %v in store: %v", id, err)
+		}
+	}
+}
+
+func TestUpdateCommit(t *testing.T) {
+	mem, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, st := range map[string]store.Storage{
+		"batch":      mem,
+		"sequential": &sequentialStorage{Storage: mem, writes: 100},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for id := range mem.Blobs {
+				delete(mem.Blobs, id)
+			}
+			ldg := setupTx(t, st)
+			if err := ldg.Update(context.Background(), swap); err != nil {
+				t.Fatal(err)
+			}
+			expectTodos(t, ldg, st, []store.ID{"3"}, []store.ID{"1", "2"})
+		})
+	}
+}
+
+func TestUpdateRollback(t *testing.T) {
+	ctx := context.Background()
+	expErr := errors.New("injected error")
+
+	t.Run("function failure", func(t *testing.T) {
+		st, err := fake.NewMem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ldg := setupTx(t, st)
+		err = ldg.Update(ctx, func(tx *ledger.Tx) error {
+			if err := swap(tx); err != nil {
+				return err
+			}
+			if _, err := tx.Get("1"); !errors.Is(err, store.ErrNotFound{ID: "1"}) {
+				t.Errorf("staged delete not visible: %v", err)
+			}
+			return expErr
+		})
+		if !errors.Is(err, expErr) {
+			t.Fatalf("expected %v got %v", expErr, err)
+		}
+		expectTodos(t, ldg, st, []store.ID{"1", "2"}, []store.ID{"3"})
+	})
+
+	t.Run("batch failure", func(t *testing.T) {
+		st, err := fake.NewMem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ldg := setupTx(t, st)
+		st.Error = expErr
+		if err := ldg.Update(ctx, swap); !errors.Is(err, expErr) {
+			t.Fatalf("expected %v got %v", expErr, err)
+		}
+		st.Error = nil
+		expectTodos(t, ldg, st, []store.ID{"1", "2"}, []store.ID{"3"})
+	})
+
+	t.Run("sequential failure", func(t *testing.T) {
+		mem, err := fake.NewMem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := &sequentialStorage{Storage: mem, writes: 2}
+		ldg := setupTx(t, st)
+		// let the deletions succeed, then fail the creation
+		st.writes, st.err = 2, expErr
+		if err := ldg.Update(ctx, swap); !errors.Is(err, expErr) {
+			t.Fatalf("expected %v got %v", expErr, err)
+		}
+		expectTodos(t, ldg, st, []store.ID{"1", "2"}, []store.ID{"3"})
+	})
+}
+
+func TestUpdateOnCommit(t *testing.T) {
+	ctx := context.Background()
+	expErr := errors.New("injected error")
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg := setupTx(t, st)
+
+	calls := 0
+	onCommit := func(tx *ledger.Tx) error {
+		tx.OnCommit(func() { calls++ })
+		return swap(tx)
+	}
+	st.Error = expErr
+	if err := ldg.Update(ctx, onCommit); !errors.Is(err, expErr) {
+		t.Fatalf("expected %v got %v", expErr, err)
+	}
+	st.Error = nil
+	if err := ldg.Update(ctx, func(tx *ledger.Tx) error {
+		onCommit(tx)
+		return expErr
+	}); !errors.Is(err, expErr) {
+		t.Fatalf("expected %v got %v", expErr, err)
+	}
+	if calls != 0 {
+		t.Fatalf("called %d times without commit", calls)
+	}
+	if err := ldg.Update(ctx, onCommit); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call got %d", calls)
+	}
+}
+
+func TestUpdateCompareAndSet(t *testing.T) {
+	ctx := context.Background()
+	mem, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fl, err := store.NewFileLog(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fl.Close() })
+
+	for name, st := range map[string]store.Storage{
+		"batch":            mem,
+		"compare and swap": fl,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ldg := setupTx(t, st)
+			describe := func(revision uint64) func(tx *ledger.Tx) error {
+				return func(tx *ledger.Tx) error {
+					todo, err := tx.Get("1")
+					if err != nil {
+						return err
+					}
+					_ = todo.Describe("local update")
+					_, err = tx.CompareAndSet("1", todo, revision)
+					return err
+				}
+			}
+			if err := ldg.Update(ctx, describe(2)); !errors.Is(err, ledger.ErrRevisionMismatch) {
+				t.Fatalf("expected %v got %v", ledger.ErrRevisionMismatch, err)
+			}
+
+			// another process sharing the store updates the object
+			other := model.New("updated elsewhere")
+			other.Revision = 2
+			blob, err := other.Serialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := st.Save(ctx, "1", blob); err != nil {
+				t.Fatal(err)
+			}
+			if err := ldg.Update(ctx, describe(1)); !errors.Is(err, ledger.ErrRevisionMismatch) {
+				t.Fatalf("expected %v got %v", ledger.ErrRevisionMismatch, err)
+			}
+			// the store is left untouched, and the stale cache is refreshed
+			cur, err := ldg.Get("1")
+			if err != nil || cur.Title != "updated elsewhere" || cur.Description != "" {
+				t.Fatalf("cache not refreshed: %v err=%v", cur, err)
+			}
+			if err := ldg.Update(ctx, describe(2)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUpdateGetNamespaced(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldg := setupTx(t, st)
+	// another kind of record, which happens to decode as a todo
+	id := store.Namespaced("token", "1")
+	st.Blobs[id] = st.Blobs["1"]
+
+	err = ldg.Update(ctx, func(tx *ledger.Tx) error {
+		_, err := tx.Get(id)
+		return err
+	})
+	if !errors.Is(err, store.ErrNotFound{ID: id}) {
+		t.Fatalf("expected %v got %v", store.ErrNotFound{ID: id}, err)
+	}
+}
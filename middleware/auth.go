@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+)
+
+var (
+	errMissingCredentials = errors.New("authentication required")
+	errBadAuthorization   = errors.New("malformed Authorization header")
+	errAdminRequired      = errors.New("administrator required")
+)
+
+// Access tells which requests a route serves, when the authentication is enabled
+type Access int
+
+const (
+	// Authenticated routes serve the requests with valid credentials
+	Authenticated Access = iota
+	// Public routes serve also the anonymous requests
+	Public
+	// Admin routes serve only the administrators, see auth.Principal.IsAdmin
+	Admin
+)
+
+// Authenticator verifies the credentials carried by the requests.
+// Either field can be nil, which rejects the corresponding credentials.
+type Authenticator struct {
+	// Tokens verifies the bearer tokens
+	Tokens *auth.Tokens
+	// Users verifies the basic auth credentials
+	Users auth.Users
+}
+
+// Authenticate verifies the credentials of the requests, a bearer API token or basic auth,
+// and attaches their principal to the request context, see auth.FromContext.
+// The requests carrying invalid credentials are rejected with 401 Unauthorized, like the ones
+// carrying none, unless the route is public; the requests of the principals lacking
+// the access to the route are rejected with 403 Forbidden.
+func Authenticate(inner http.Handler, authn Authenticator, access Access) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			if access != Public {
+				reject(w, http.StatusUnauthorized, errMissingCredentials)
+				return
+			}
+			inner.ServeHTTP(w, r)
+			return
+		}
+		p, err := authn.verify(r, header)
+		if err != nil {
+			reject(w, http.StatusUnauthorized, err)
+			return
+		}
+		if access == Admin && !p.IsAdmin() {
+			reject(w, http.StatusForbidden, errAdminRequired)
+			return
+		}
+		inner.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+	})
+}
+
+func (authn Authenticator) verify(r *http.Request, header string) (auth.Principal, error) {
+	if user, password, ok := r.BasicAuth(); ok {
+		if authn.Users == nil {
+			return auth.Principal{}, auth.ErrInvalidCredentials
+		}
+		return authn.Users.Verify(user, password)
+	}
+	scheme, secret, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || secret == "" {
+		return auth.Principal{}, errBadAuthorization
+	}
+	if authn.Tokens == nil {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	return authn.Tokens.Verify(secret)
+}
+
+// reject fails a request, with the same error response of the API routes
+func reject(w http.ResponseWriter, code int, err error) {
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer, Basic realm="todo"`)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	resp := apiv1.Response{
+		Status: apiv1.ResponseError,
+		Error: &apiv1.Error{
+			Code: code,
+			Text: err.Error(),
+		},
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/auth"
+	"github.com/gotestbootcamp/go-todo-app/middleware"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokens(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, secret, err := tokens.Create(ctx, "1", "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, revoked, err := tokens.Create(ctx, "2", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.Revoke(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+	authn := middleware.Authenticator{
+		Tokens: tokens,
+		Users:  auth.NewUsers(map[string]string{"admin": "p4ssw0rd"}),
+	}
+
+	// the inner handler replies with the principal it got
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.FromContext(r.Context())
+		json.NewEncoder(w).Encode(p)
+	})
+
+	bearer := func(secret string) string { return "Bearer " + secret }
+	tests := []struct {
+		name          string
+		access        middleware.Access
+		authorization string
+		basic         []string
+		code          int
+		expected      auth.Principal
+	}{
+		{"public, anonymous", middleware.Public, "", nil, http.StatusOK, auth.Principal{}},
+		{"public, token", middleware.Public, bearer(secret), nil, http.StatusOK, auth.Principal{Name: "ci", Method: auth.MethodToken, TokenID: "1"}},
+		{"public, bad token", middleware.Public, bearer("wrong"), nil, http.StatusUnauthorized, auth.Principal{}},
+		{"missing header", middleware.Authenticated, "", nil, http.StatusUnauthorized, auth.Principal{}},
+		{"malformed header", middleware.Authenticated, "Token " + secret, nil, http.StatusUnauthorized, auth.Principal{}},
+		{"empty bearer", middleware.Authenticated, "Bearer ", nil, http.StatusUnauthorized, auth.Principal{}},
+		{"bad token", middleware.Authenticated, bearer("wrong"), nil, http.StatusUnauthorized, auth.Principal{}},
+		{"revoked token", middleware.Authenticated, bearer(revoked), nil, http.StatusUnauthorized, auth.Principal{}},
+		{"token", middleware.Authenticated, bearer(secret), nil, http.StatusOK, auth.Principal{Name: "ci", Method: auth.MethodToken, TokenID: "1"}},
+		{"basic", middleware.Authenticated, "", []string{"admin", "p4ssw0rd"}, http.StatusOK, auth.Principal{Name: "admin", Method: auth.MethodBasic}},
+		{"bad password", middleware.Authenticated, "", []string{"admin", "wrong"}, http.StatusUnauthorized, auth.Principal{}},
+		{"admin, anonymous", middleware.Admin, "", nil, http.StatusUnauthorized, auth.Principal{}},
+		{"admin, token", middleware.Admin, bearer(secret), nil, http.StatusForbidden, auth.Principal{}},
+		{"admin, basic", middleware.Admin, "", []string{"admin", "p4ssw0rd"}, http.StatusOK, auth.Principal{Name: "admin", Method: auth.MethodBasic}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+			if tc.basic != nil {
+				r.SetBasicAuth(tc.basic[0], tc.basic[1])
+			}
+			w := httptest.NewRecorder()
+			middleware.Authenticate(inner, authn, tc.access).ServeHTTP(w, r)
+			if w.Code != tc.code {
+				t.Fatalf("expected code %d got %d: %s", tc.code, w.Code, w.Body.String())
+			}
+			if tc.code != http.StatusOK {
+				var resp apiv1.Response
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Status != apiv1.ResponseError || resp.Error == nil || resp.Error.Code != tc.code {
+					t.Errorf("unexpected error response: %+v", resp)
+				}
+				return
+			}
+			var got auth.Principal
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected principal %+v got %+v", tc.expected, got)
+			}
+		})
+	}
+}
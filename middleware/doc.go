@@ -1,4 +1,4 @@
 // Package middleware include utilities which can be transparently
 // injected in the http.Handler{,Func} chain to augment it with
-// functionalities, like transparent logging
+// functionalities, like transparent logging or authentication
 package middleware
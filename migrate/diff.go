@@ -0,0 +1,62 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Diff returns a line-oriented diff of two versions of a record, in the unified format
+// but without hunks: all the lines are reported. JSON records are indented first,
+// so the changes are reported field by field.
+func Diff(name string, before, after []byte) string {
+	a := lines(before)
+	b := lines(after)
+
+	// longest common subsequence of the lines, lcs[i][j] being the one of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", name, name)
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&sb, " %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&sb, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&sb, "+%s\n", b[j])
+			j++
+		}
+	}
+	return sb.String()
+}
+
+func lines(blob []byte) []string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, blob, "", "  "); err == nil {
+		blob = buf.Bytes()
+	}
+	text := strings.TrimRight(string(blob), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
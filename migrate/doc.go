@@ -0,0 +1,7 @@
+// Package migrate rewrites the records of a store.Storage when their format evolves,
+// e.g. to backfill new fields or to convert their encoding.
+// Migrations are applied in order of version, each one to all the records; the version
+// of the last applied migration, and the progress of the one being applied, are recorded
+// in a reserved key, so a interrupted run resumes where it stopped. Migrations must be
+// idempotent, because the records processed after the last checkpoint are processed again.
+package migrate
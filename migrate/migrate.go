@@ -0,0 +1,221 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// DefaultCheckpointEvery is how many records are processed between two progress checkpoints
+const DefaultCheckpointEvery = 100
+
+// StateID is the reserved key recording the migration state. Its namespace is reserved
+// to the migrations: its records are never migrated.
+var StateID = store.Namespaced("meta", "migration")
+
+var (
+	ErrInvalidMigrations = errors.New("invalid migrations")
+)
+
+// Migration rewrites the records of a store
+type Migration struct {
+	// Version orders the migrations; must be positive and unique
+	Version int
+	// Name is a short description of the migration
+	Name string
+	// Rewrite returns the new content of a record, or nil if the record must be left untouched.
+	// Must be idempotent. Returning error aborts the migration.
+	Rewrite func(id store.ID, blob store.Blob) (store.Blob, error)
+}
+
+// State is the progress of the migrations of a store, as recorded in StateID
+type State struct {
+	// Version is the version of the last migration fully applied; zero means none
+	Version int `json:"version"`
+	// Pending, if not zero, is the version of the migration being applied
+	Pending int `json:"pending,omitempty"`
+	// After is the last record processed by the pending migration; the records are processed in order of ID
+	After store.ID `json:"after,omitempty"`
+}
+
+// Options tune a migration run
+type Options struct {
+	// DryRun reports the changes to Out without writing anything
+	DryRun bool
+	// Out, if not nil, receives the diff of each record changed
+	Out io.Writer
+	// CheckpointEvery is how many records are processed between two progress checkpoints.
+	// Zero means DefaultCheckpointEvery.
+	CheckpointEvery int
+}
+
+// Report summarizes the run of a migration
+type Report struct {
+	Version int
+	Name    string
+	// Scanned is the number of records processed
+	Scanned int
+	// Changed is the number of records rewritten, or to be rewritten in dry run
+	Changed int
+}
+
+// LoadState returns the migration state of the store. A store never migrated has the zero state.
+func LoadState(ctx context.Context, st store.Storage) (State, error) {
+	blob, err := st.Load(ctx, StateID)
+	if errors.Is(err, store.ErrNotFound{ID: StateID}) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	var state State
+	if err := json.Unmarshal(blob, &state); err != nil {
+		return State{}, store.ErrCorruptedContent{Name: string(StateID)}
+	}
+	return state, nil
+}
+
+func saveState(ctx context.Context, st store.Storage, state State) error {
+	blob, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	err = st.Save(ctx, StateID, blob)
+	if errors.Is(err, store.ErrNotFound{ID: StateID}) {
+		err = st.Create(ctx, StateID, blob)
+	}
+	return err
+}
+
+// Run applies to the store the migrations not applied yet, in order of version,
+// resuming the one interrupted, if any. Returns a report for each migration run.
+// On failure, returns the reports of the migrations completed and the error;
+// the migration state records the progress, so running again resumes from there.
+func Run(ctx context.Context, st store.Storage, migrations []Migration, opts Options) ([]Report, error) {
+	migrations, err := sorted(migrations)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CheckpointEvery <= 0 {
+		opts.CheckpointEvery = DefaultCheckpointEvery
+	}
+	state, err := LoadState(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("migrate: store at version %d (pending=%d after=%q)", state.Version, state.Pending, state.After)
+
+	items, err := st.LoadAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[store.ID]store.Blob, len(items))
+	ids := make([]store.ID, 0, len(items))
+	for _, item := range items {
+		if item.ID.Namespace() == StateID.Namespace() {
+			continue
+		}
+		records[item.ID] = item.Blob
+		ids = append(ids, item.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var reports []Report
+	for _, m := range migrations {
+		if m.Version <= state.Version {
+			continue
+		}
+		after := store.NullID
+		if state.Pending == m.Version {
+			after = state.After
+			log.Printf("migrate: resuming migration %d %q after record %q", m.Version, m.Name, after)
+		}
+		report, err := run(ctx, st, m, ids, records, after, opts)
+		if err != nil {
+			return reports, fmt.Errorf("migration %d %q: %w", m.Version, m.Name, err)
+		}
+		reports = append(reports, report)
+		state = State{Version: m.Version}
+		if !opts.DryRun {
+			if err := saveState(ctx, st, state); err != nil {
+				return reports, err
+			}
+		}
+		log.Printf("migrate: done migration %d %q: scanned=%d changed=%d dryRun=%v", m.Version, m.Name, report.Scanned, report.Changed, opts.DryRun)
+	}
+	return reports, nil
+}
+
+// run applies a migration to the records following after. records is updated with the
+// rewritten blobs, so the following migrations see them even in dry run.
+func run(ctx context.Context, st store.Storage, m Migration, ids []store.ID, records map[store.ID]store.Blob, after store.ID, opts Options) (Report, error) {
+	report := Report{Version: m.Version, Name: m.Name}
+	checkpoint := func(id store.ID) error {
+		if opts.DryRun {
+			return nil
+		}
+		return saveState(ctx, st, State{Version: m.Version - 1, Pending: m.Version, After: id})
+	}
+	if err := checkpoint(after); err != nil {
+		return report, err
+	}
+	start := sort.Search(len(ids), func(i int) bool { return ids[i] > after })
+	for idx, id := range ids[start:] {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		blob := records[id]
+		rewritten, err := m.Rewrite(id, blob)
+		if err != nil {
+			return report, fmt.Errorf("record %q: %w", id, err)
+		}
+		report.Scanned++
+		if rewritten != nil && !bytes.Equal(rewritten, blob) {
+			report.Changed++
+			if opts.Out != nil {
+				fmt.Fprint(opts.Out, Diff(fmt.Sprintf("%d/%s", m.Version, id), blob, rewritten))
+			}
+			if !opts.DryRun {
+				if err := save(ctx, st, id, blob, rewritten); err != nil {
+					return report, fmt.Errorf("record %q: %w", id, err)
+				}
+			}
+			records[id] = rewritten
+		}
+		if (idx+1)%opts.CheckpointEvery == 0 {
+			if err := checkpoint(id); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// save replaces a record, making sure nobody changed it meanwhile, if the store allows it
+func save(ctx context.Context, st store.Storage, id store.ID, old, blob store.Blob) error {
+	if cas, ok := st.(store.CompareAndSwapper); ok {
+		return cas.CompareAndSwap(ctx, id, old, blob)
+	}
+	return st.Save(ctx, id, blob)
+}
+
+func sorted(migrations []Migration) ([]Migration, error) {
+	res := append([]Migration(nil), migrations...)
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+	for idx, m := range res {
+		if m.Version <= 0 || m.Rewrite == nil {
+			return nil, fmt.Errorf("%w: migration %d %q", ErrInvalidMigrations, m.Version, m.Name)
+		}
+		if idx > 0 && res[idx-1].Version == m.Version {
+			return nil, fmt.Errorf("%w: duplicated version %d", ErrInvalidMigrations, m.Version)
+		}
+	}
+	return res, nil
+}
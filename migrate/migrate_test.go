@@ -0,0 +1,178 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/migrate"
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+)
+
+const legacyTodo = `{"Title":"legacy","Status":"pending","LastUpdateTime":"2014-02-04T00:00:00Z","Revision":1}`
+
+func TestRegisteredMigrations(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := model.New("current").Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Blobs["1"] = []byte(legacyTodo)
+	st.Blobs["2"] = current
+	st.Blobs["history/1"] = []byte(`[]`)
+
+	var out bytes.Buffer
+	reports, err := migrate.Run(ctx, st, migrate.Registered(), migrate.Options{DryRun: true, Out: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Scanned != 3 || reports[0].Changed != 1 {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	if string(st.Blobs["1"]) != legacyTodo {
+		t.Fatalf("dry run changed the record: %s", st.Blobs["1"])
+	}
+	if _, ok := st.Blobs[migrate.StateID]; ok {
+		t.Fatalf("dry run recorded the state")
+	}
+	diff := out.String()
+	if !strings.Contains(diff, `-  "Title": "legacy",`) || !strings.Contains(diff, `+  "kind": "todo",`) {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+
+	reports, err = migrate.Run(ctx, st, migrate.Registered(), migrate.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Changed != 1 {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	env, err := model.OpenEnvelope(st.Blobs["1"])
+	if err != nil || env.Version != model.TodoVersion {
+		t.Fatalf("record not migrated: %s err=%v", st.Blobs["1"], err)
+	}
+	if !bytes.Equal(st.Blobs["2"], current) {
+		t.Fatalf("current record rewritten: %s", st.Blobs["2"])
+	}
+	state, err := migrate.LoadState(ctx, st)
+	if err != nil || state != (migrate.State{Version: 1}) {
+		t.Fatalf("unexpected state %+v err=%v", state, err)
+	}
+
+	// nothing left to do
+	reports, err = migrate.Run(ctx, st, migrate.Registered(), migrate.Options{})
+	if err != nil || len(reports) != 0 {
+		t.Fatalf("unexpected reports %+v err=%v", reports, err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	ctx := context.Background()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []store.ID{"a", "b", "c", "d"} {
+		st.Blobs[id] = store.Blob(id)
+	}
+
+	var seen []store.ID
+	failOn := store.ID("c")
+	upper := migrate.Migration{
+		Version: 1,
+		Name:    "upper",
+		Rewrite: func(id store.ID, blob store.Blob) (store.Blob, error) {
+			seen = append(seen, id)
+			if id == failOn {
+				return nil, errors.New("interrupted")
+			}
+			return bytes.ToUpper(blob), nil
+		},
+	}
+	suffix := migrate.Migration{
+		Version: 2,
+		Name:    "suffix",
+		Rewrite: func(id store.ID, blob store.Blob) (store.Blob, error) {
+			if bytes.HasSuffix(blob, []byte("!")) {
+				return nil, nil
+			}
+			return append(append(store.Blob{}, blob...), '!'), nil
+		},
+	}
+	migrations := []migrate.Migration{suffix, upper}
+
+	_, err = migrate.Run(ctx, st, migrations, migrate.Options{CheckpointEvery: 1})
+	if err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	state, err := migrate.LoadState(ctx, st)
+	if err != nil || state != (migrate.State{Pending: 1, After: "b"}) {
+		t.Fatalf("unexpected state %+v err=%v", state, err)
+	}
+
+	failOn = store.NullID
+	seen = nil
+	reports, err := migrate.Run(ctx, st, migrations, migrate.Options{CheckpointEvery: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 || seen[0] != "c" || seen[1] != "d" {
+		t.Fatalf("expected to resume from c, processed %v", seen)
+	}
+	if len(reports) != 2 || reports[0].Version != 1 || reports[1].Version != 2 {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	for id, blob := range st.Blobs {
+		if id == migrate.StateID {
+			continue
+		}
+		if expected := strings.ToUpper(string(id)) + "!"; string(blob) != expected {
+			t.Fatalf("record %v: expected %q got %q", id, expected, blob)
+		}
+	}
+	state, err = migrate.LoadState(ctx, st)
+	if err != nil || state != (migrate.State{Version: 2}) {
+		t.Fatalf("unexpected state %+v err=%v", state, err)
+	}
+}
+
+func TestInvalidMigrations(t *testing.T) {
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	noop := func(id store.ID, blob store.Blob) (store.Blob, error) { return nil, nil }
+	for name, migrations := range map[string][]migrate.Migration{
+		"zero version":       {{Version: 0, Rewrite: noop}},
+		"missing rewrite":    {{Version: 1}},
+		"duplicated version": {{Version: 1, Rewrite: noop}, {Version: 1, Rewrite: noop}},
+	} {
+		if _, err := migrate.Run(context.Background(), st, migrations, migrate.Options{}); !errors.Is(err, migrate.ErrInvalidMigrations) {
+			t.Fatalf("%s: expected %v got %v", name, migrate.ErrInvalidMigrations, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := migrate.Diff("1", []byte(`{"a":1,"b":2}`), []byte(`{"a":1,"b":3,"c":4}`))
+	expected := `--- 1
++++ 1
+ {
+   "a": 1,
+-  "b": 2
++  "b": 3,
++  "c": 4
+ }
+`
+	if got != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
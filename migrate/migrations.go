@@ -0,0 +1,38 @@
+package migrate
+
+import (
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Registered returns the migrations shipped with the application, in order of version.
+// Add the new migrations at the end, with the next version; never change the applied ones.
+func Registered() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "store the todos in the current envelope",
+			Rewrite: rewriteTodoEnvelope,
+		},
+	}
+}
+
+// rewriteTodoEnvelope upgrades the todos serialized in older formats, including the legacy
+// ones lacking the envelope, to the current schema version
+func rewriteTodoEnvelope(id store.ID, blob store.Blob) (store.Blob, error) {
+	if id.Namespace() != "" {
+		return nil, nil
+	}
+	env, err := model.OpenEnvelope(blob)
+	if err != nil {
+		return nil, err
+	}
+	if env.Version == model.TodoVersion {
+		return nil, nil
+	}
+	todo, err := model.DeserializeTodo(blob)
+	if err != nil {
+		return nil, err
+	}
+	return todo.Serialize()
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// CodecJSON encodes the payload of a envelope as JSON
+	CodecJSON = "json"
+
+	// KindTodo identifies the serialized todos
+	KindTodo = "todo"
+)
+
+var (
+	ErrUnsupportedCodec   = errors.New("unsupported codec")
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+	ErrUnexpectedKind     = errors.New("unexpected object kind")
+)
+
+// Envelope wraps the serialized objects, describing how to decode them.
+// The objects serialized before the envelope was introduced lack it: they are
+// the bare JSON of the object, and have version 1.
+type Envelope struct {
+	// Kind is the type of the object, e.g. KindTodo
+	Kind string `json:"kind"`
+	// Version is the schema version of the object
+	Version int `json:"version"`
+	// Codec is the encoding of Data; only CodecJSON is supported
+	Codec string `json:"codec"`
+	// Data is the encoded object
+	Data json.RawMessage `json:"data"`
+}
+
+// upgrade converts the payload of a schema version in the payload of the next one
+type upgrade func(data json.RawMessage) (json.RawMessage, error)
+
+// seal encodes the given payload, with the given schema version, in a envelope
+func seal(kind string, version int, payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{
+		Kind:    kind,
+		Version: version,
+		Codec:   CodecJSON,
+		Data:    data,
+	})
+}
+
+// open decodes the envelope of a object of the given kind, then upgrades its payload to
+// the given schema version, using upgrades[N] to go from version N to N+1.
+// Objects lacking the envelope are taken as version 1.
+func open(blob []byte, kind string, version int, upgrades map[int]upgrade) (json.RawMessage, error) {
+	env, err := OpenEnvelope(blob)
+	if err != nil {
+		return nil, err
+	}
+	if env.Kind != kind {
+		return nil, fmt.Errorf("%w: %q, expected %q", ErrUnexpectedKind, env.Kind, kind)
+	}
+	if env.Version < 1 || env.Version > version {
+		return nil, fmt.Errorf("%w: %s version %d, expected at most %d", ErrUnsupportedVersion, kind, env.Version, version)
+	}
+	data := env.Data
+	for v := env.Version; v < version; v++ {
+		up, ok := upgrades[v]
+		if !ok {
+			return nil, fmt.Errorf("%w: no upgrade of %s from version %d", ErrUnsupportedVersion, kind, v)
+		}
+		data, err = up(data)
+		if err != nil {
+			return nil, fmt.Errorf("upgrading %s from version %d: %w", kind, v, err)
+		}
+	}
+	return data, nil
+}
+
+// OpenEnvelope returns the envelope of a serialized object. Legacy objects, lacking the envelope,
+// are reported as version 1 todos, with Data holding the whole blob.
+func OpenEnvelope(blob []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(blob, &env); err != nil {
+		return Envelope{}, err
+	}
+	if env.Version == 0 && env.Kind == "" {
+		// only todos were serialized before the envelope was introduced
+		return Envelope{Kind: KindTodo, Version: 1, Codec: CodecJSON, Data: blob}, nil
+	}
+	if env.Codec != CodecJSON {
+		return Envelope{}, fmt.Errorf("%w: %q", ErrUnsupportedCodec, env.Codec)
+	}
+	return env, nil
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+// HistoryEntry records a state-changing operation performed on a Todo.
+// IDs are the plain string form of the ledger IDs.
+type HistoryEntry struct {
+	// Time is when the operation was performed
+	Time time.Time
+	// Actor identifies who performed the operation, empty if unknown
+	Actor string
+	// Operation is what was performed
+	Operation apiv1.Operation
+	// Changes lists the fields changed by the operation
+	Changes []apiv1.Change
+	// MergedFrom links the histories of the todos merged into this one
+	MergedFrom []string
+	// MergedInto links the history of the todo this one was merged into
+	MergedInto string
+}
+
+// NewHistoryEntry creates a new entry for the given operation, recording the fields
+// which differ between the before and after versions of the todo.
+func NewHistoryEntry(actor string, op apiv1.Operation, before, after Todo) HistoryEntry {
+	return HistoryEntry{
+		Time:      time.Now(),
+		Actor:     actor,
+		Operation: op,
+		Changes:   Diff(before, after),
+	}
+}
+
+// ToAPIv1 converts the object into the corresponding API layer object
+func (he HistoryEntry) ToAPIv1() apiv1.HistoryEntry {
+	apiEntry := apiv1.HistoryEntry{
+		Time:       he.Time,
+		Actor:      he.Actor,
+		Operation:  he.Operation,
+		Changes:    he.Changes,
+		MergedInto: apiv1.ID(he.MergedInto),
+	}
+	for _, id := range he.MergedFrom {
+		apiEntry.MergedFrom = append(apiEntry.MergedFrom, apiv1.ID(id))
+	}
+	return apiEntry
+}
+
+// History is the sequence of operations performed on a Todo, oldest first
+type History []HistoryEntry
+
+// ToAPIv1 converts the object into the corresponding API layer object
+func (h History) ToAPIv1() []apiv1.HistoryEntry {
+	apiEntries := make([]apiv1.HistoryEntry, 0, len(h))
+	for _, he := range h {
+		apiEntries = append(apiEntries, he.ToAPIv1())
+	}
+	return apiEntries
+}
+
+// Serialize encodes the object in its canonical bytestream representation.
+// If succesfull, returns the representation; otherwise the representation
+// must be ignored, and the error will describe the failure.
+func (h History) Serialize() ([]byte, error) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(h)
+	return buf.Bytes(), err
+}
+
+// DeserializeHistory decodes the object from its canonical bytestream representation.
+// If succesfull, returns the decode object; otherwise returns a nil object,
+// and the error will describe the failure.
+func DeserializeHistory(data []byte) (History, error) {
+	var h History
+	err := json.NewDecoder(bytes.NewBuffer(data)).Decode(&h)
+	return h, err
+}
+
+// Diff returns the user-visible fields which differ between two versions of a todo.
+// Bookkeeping fields, like the revision or the last update time, are ignored.
+func Diff(before, after Todo) []apiv1.Change {
+	var changes []apiv1.Change
+	add := func(field, b, a string) {
+		if b != a {
+			changes = append(changes, apiv1.Change{Field: field, Before: b, After: a})
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("assignee", before.Assignee, after.Assignee)
+	add("description", before.Description, after.Description)
+	add("status", string(before.Status), string(after.Status))
+	add("priority", priorityValue(before), priorityValue(after))
+	add("due", dueValue(before), dueValue(after))
+	add("labels", strings.Join(before.Labels, ","), strings.Join(after.Labels, ","))
+	return changes
+}
+
+// priorityValue returns the effective priority, or nothing for the zero Todo,
+// which stands for a todo not existing yet (or anymore)
+func priorityValue(td Todo) string {
+	if td.Status == "" {
+		return ""
+	}
+	return string(td.GetPriority())
+}
+
+func dueValue(td Todo) string {
+	if td.DueTime == nil {
+		return ""
+	}
+	return td.DueTime.Format(time.RFC3339)
+}
@@ -0,0 +1,113 @@
+package model_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+func TestDiff(t *testing.T) {
+	due := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	created := model.New("foo")
+
+	assigned := created
+	if err := assigned.Assign("alice"); err != nil {
+		t.Fatal(err)
+	}
+	described := created
+	if err := described.Describe("details"); err != nil {
+		t.Fatal(err)
+	}
+	scheduled := created
+	if err := scheduled.Schedule(due); err != nil {
+		t.Fatal(err)
+	}
+	labeled := created
+	if err := labeled.AddLabels("infra", "docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		before   model.Todo
+		after    model.Todo
+		expected []apiv1.Change
+	}{
+		{
+			name:   "create",
+			before: model.Todo{},
+			after:  created,
+			expected: []apiv1.Change{
+				{Field: "title", After: "foo"},
+				{Field: "status", After: "pending"},
+				{Field: "priority", After: "normal"},
+			},
+		},
+		{
+			name:   "assign",
+			before: created,
+			after:  assigned,
+			expected: []apiv1.Change{
+				{Field: "assignee", After: "alice"},
+				{Field: "status", Before: "pending", After: "assigned"},
+			},
+		},
+		{
+			name:     "describe",
+			before:   created,
+			after:    described,
+			expected: []apiv1.Change{{Field: "description", After: "details"}},
+		},
+		{
+			name:     "schedule",
+			before:   created,
+			after:    scheduled,
+			expected: []apiv1.Change{{Field: "due", After: "2030-01-02T03:04:05Z"}},
+		},
+		{
+			name:     "label",
+			before:   created,
+			after:    labeled,
+			expected: []apiv1.Change{{Field: "labels", After: "docs,infra"}},
+		},
+		{
+			name:   "no changes",
+			before: created,
+			after:  created,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := model.Diff(tc.before, tc.after)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %+v got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHistorySerialization(t *testing.T) {
+	history := model.History{
+		model.NewHistoryEntry("alice", apiv1.OpCreate, model.Todo{}, model.New("foo")),
+		{
+			Time:       time.Now().UTC().Truncate(time.Second),
+			Operation:  apiv1.OpMerge,
+			MergedFrom: []string{"1", "2"},
+		},
+	}
+	blob, err := history.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := model.DeserializeHistory(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Actor != "alice" || !reflect.DeepEqual(got[1].MergedFrom, []string{"1", "2"}) {
+		t.Fatalf("unexpected history: %+v", got)
+	}
+}
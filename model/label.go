@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidLabel = errors.New("invalid label")
+)
+
+// labels are short lowercase tags, like "frontend" or "infra"
+var labelRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// IsValidLabel returns true if the given string can be used as label
+func IsValidLabel(label string) bool {
+	return labelRegexp.MatchString(label)
+}
+
+// HasLabels returns true if the todo has all the given labels
+func (td Todo) HasLabels(labels ...string) bool {
+	for _, label := range labels {
+		idx := sort.SearchStrings(td.Labels, label)
+		if idx == len(td.Labels) || td.Labels[idx] != label {
+			return false
+		}
+	}
+	return true
+}
+
+// AddLabels adds the given labels to the todo. Labels already set are ignored.
+// This method is idempotent: the labels can be changed any number of time
+// while the object is processable. Returns error if any label is invalid
+// or if the update fails; in this case the todo is left untouched.
+func (td *Todo) AddLabels(labels ...string) error {
+	if !td.IsOngoing() {
+		return ErrFinalized
+	}
+	for _, label := range labels {
+		if !IsValidLabel(label) {
+			return ErrInvalidLabel
+		}
+	}
+	td.Labels = mergeLabels(td.Labels, labels)
+	td.LastUpdateTime = time.Now()
+	return nil
+}
+
+// RemoveLabels removes the given labels from the todo. Labels not set are ignored.
+// This method is idempotent: the labels can be changed any number of time
+// while the object is processable. Returns error if the update fails.
+func (td *Todo) RemoveLabels(labels ...string) error {
+	if !td.IsOngoing() {
+		return ErrFinalized
+	}
+	removed := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		removed[label] = true
+	}
+	var res []string
+	for _, label := range td.Labels {
+		if !removed[label] {
+			res = append(res, label)
+		}
+	}
+	td.Labels = res
+	td.LastUpdateTime = time.Now()
+	return nil
+}
+
+// mergeLabels returns the sorted union of the given label sets, or nil if empty
+func mergeLabels(labels1, labels2 []string) []string {
+	set := make(map[string]bool, len(labels1)+len(labels2))
+	for _, label := range labels1 {
+		set[label] = true
+	}
+	for _, label := range labels2 {
+		set[label] = true
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(set))
+	for label := range set {
+		res = append(res, label)
+	}
+	sort.Strings(res)
+	return res
+}
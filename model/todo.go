@@ -15,6 +15,11 @@ var (
 	ErrAlreadyAssigned = errors.New("todo already assigned")
 	ErrNotAssigned     = errors.New("todo not assigned")
 	ErrFinalized       = errors.New("todo finalized")
+	ErrInvalidPriority = errors.New("invalid priority")
+	ErrDueInPast       = errors.New("due time in the past")
+	ErrNotCompleted    = errors.New("todo not completed")
+	ErrInvalidAssignee = errors.New("invalid assignee")
+	ErrInvalidStatus   = errors.New("invalid status")
 )
 
 // Todo represent a todo item managed by the system.
@@ -25,12 +30,22 @@ type Todo struct {
 	Title string
 	// Assignee is the identifier of the agent working on the Todo
 	Assignee string
+	// PreviousAssignees lists the agents which worked on the Todo before the current one, oldest first
+	PreviousAssignees []string
 	// Description is a longer description of the todo
 	Description string
 	// Status is the current processing status of the todo
 	Status apiv1.Status
+	// Priority is the urgency of the todo
+	Priority apiv1.Priority
+	// DueTime is the optional deadline of the todo
+	DueTime *time.Time
+	// Labels is the set of tags attached to the todo, kept sorted
+	Labels []string
 	// LastUpdateTime records the last time a todo was modified in any way in the system
 	LastUpdateTime time.Time
+	// Revision is increased by the ledger every time the todo is stored
+	Revision uint64
 }
 
 func (td Todo) String() string {
@@ -44,40 +59,109 @@ func (td Todo) String() string {
 // ToAPIv1 converts the object into the corresponding API layer object
 func (td Todo) ToAPIv1() apiv1.Todo {
 	return apiv1.Todo{
-		Title:          td.Title,
-		Assignee:       td.Assignee,
-		Description:    td.Description,
-		Status:         td.Status,
-		LastUpdateTime: td.LastUpdateTime,
+		Title:             td.Title,
+		Assignee:          td.Assignee,
+		PreviousAssignees: td.PreviousAssignees,
+		Description:       td.Description,
+		Status:            td.Status,
+		Priority:          td.GetPriority(),
+		DueTime:           td.DueTime,
+		Labels:            td.Labels,
+		LastUpdateTime:    td.LastUpdateTime,
+		Revision:          td.Revision,
 	}
 }
 
-// Serialize encodes the object in its canonical bytestream representation.
+// Serialize encodes the object in its canonical bytestream representation:
+// a Envelope holding the current schema version, see TodoVersion.
 // If succesfull, returns the representation; otherwise the representation
 // must be ignored, and the error will describe the failure.
 func (td Todo) Serialize() ([]byte, error) {
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(td)
-	return buf.Bytes(), err
+	return seal(KindTodo, TodoVersion, encodeTodo(td))
 }
 
-// Serialize decodes the object from its canonical bytestream representation.
+// DeserializeTodo decodes the object from its canonical bytestream representation,
+// upgrading it from older schema versions, including the legacy ones lacking the envelope.
 // If succesfull, returns the decode object; otherwise returns a zero valued
 // object, and the error will describe the failure.
 func DeserializeTodo(data []byte) (Todo, error) {
-	var todo Todo
-	err := json.NewDecoder(bytes.NewBuffer(data)).Decode(&todo)
-	return todo, err
+	payload, err := open(data, KindTodo, TodoVersion, todoUpgrades)
+	if err != nil {
+		return Todo{}, err
+	}
+	var v2 todoV2
+	if err := json.Unmarshal(payload, &v2); err != nil {
+		return Todo{}, err
+	}
+	return decodeTodo(v2), nil
 }
 
-// / NewFromAPIv1 creates a new object from its corresponding API layer object
-func NewFromAPIv1(apiTodo apiv1.Todo) Todo {
-	return Todo{
+// NewFromAPIv1 creates a new object from its corresponding API layer object.
+// Returns error if the API object holds invalid values; in this case the
+// returned object must be ignored.
+func NewFromAPIv1(apiTodo apiv1.Todo) (Todo, error) {
+	todo := Todo{
 		Title:          apiTodo.Title,
 		Description:    apiTodo.Description,
 		Status:         apiv1.Pending,
 		LastUpdateTime: time.Now(),
 	}
+	if apiTodo.Priority != "" {
+		if err := todo.Prioritize(apiTodo.Priority); err != nil {
+			return Todo{}, err
+		}
+	}
+	if apiTodo.DueTime != nil {
+		if err := todo.Schedule(*apiTodo.DueTime); err != nil {
+			return Todo{}, err
+		}
+	}
+	if len(apiTodo.Labels) > 0 {
+		if err := todo.AddLabels(apiTodo.Labels...); err != nil {
+			return Todo{}, err
+		}
+	}
+	return todo, nil
+}
+
+// RestoreFromAPIv1 recreates a object, with all its fields, from its corresponding API layer object,
+// e.g. read from a backup. Unlike NewFromAPIv1, it doesn't apply any state transition.
+// The revision is ignored: it is managed by the ledger.
+// Returns error if the API object holds invalid or inconsistent values; in this case the
+// returned object must be ignored.
+func RestoreFromAPIv1(apiTodo apiv1.Todo) (Todo, error) {
+	switch apiTodo.Status {
+	case apiv1.Pending:
+		if apiTodo.Assignee != "" {
+			return Todo{}, fmt.Errorf("%w: pending todo assigned to %q", ErrInvalidAssignee, apiTodo.Assignee)
+		}
+	case apiv1.Assigned:
+		if apiTodo.Assignee == "" {
+			return Todo{}, fmt.Errorf("%w: assigned todo without assignee", ErrInvalidAssignee)
+		}
+	case apiv1.Completed, apiv1.Deleted:
+	default:
+		return Todo{}, fmt.Errorf("%w: %q", ErrInvalidStatus, apiTodo.Status)
+	}
+	if PriorityRank(apiTodo.Priority) < 0 {
+		return Todo{}, ErrInvalidPriority
+	}
+	for _, label := range apiTodo.Labels {
+		if !IsValidLabel(label) {
+			return Todo{}, fmt.Errorf("%w: %q", ErrInvalidLabel, label)
+		}
+	}
+	return Todo{
+		Title:             apiTodo.Title,
+		Assignee:          apiTodo.Assignee,
+		PreviousAssignees: apiTodo.PreviousAssignees,
+		Description:       apiTodo.Description,
+		Status:            apiTodo.Status,
+		Priority:          apiTodo.Priority,
+		DueTime:           apiTodo.DueTime,
+		Labels:            mergeLabels(nil, apiTodo.Labels),
+		LastUpdateTime:    apiTodo.LastUpdateTime,
+	}, nil
 }
 
 // New creates a new Todo with the given title and with sane defaults
@@ -97,6 +181,37 @@ func (td Todo) IsOngoing() bool {
 	return td.Status == apiv1.Pending || td.Status == apiv1.Assigned
 }
 
+// GetPriority returns the priority of the todo, defaulting to Normal if unset
+func (td Todo) GetPriority() apiv1.Priority {
+	if td.Priority == "" {
+		return apiv1.Normal
+	}
+	return td.Priority
+}
+
+// IsOverdue returns true if the todo is still processable but its due time is
+// before the given time.
+func (td Todo) IsOverdue(now time.Time) bool {
+	return td.IsOngoing() && td.DueTime != nil && td.DueTime.Before(now)
+}
+
+// PriorityRank returns the relative urgency of a priority: the higher the rank,
+// the more urgent the priority. Returns -1 for invalid priorities.
+func PriorityRank(priority apiv1.Priority) int {
+	switch priority {
+	case apiv1.Low:
+		return 0
+	case apiv1.Normal, "":
+		return 1
+	case apiv1.High:
+		return 2
+	case apiv1.Urgent:
+		return 3
+	default:
+		return -1
+	}
+}
+
 func (t Todo) HTMLRow() ([]byte, error) {
 	const tt = `<tr>
     <td>{{ .Title }}</td>
@@ -136,8 +251,40 @@ func (td *Todo) Describe(description string) error {
 	return nil
 }
 
+// Prioritize changes the priority of an object.
+// This method is idempotent: the priority can be changed any number of time
+// while the object is processable. Returns error if the priority is invalid
+// or if the update fails.
+func (td *Todo) Prioritize(priority apiv1.Priority) error {
+	if !td.IsOngoing() {
+		return ErrFinalized
+	}
+	if PriorityRank(priority) < 0 {
+		return ErrInvalidPriority
+	}
+	td.Priority = priority
+	td.LastUpdateTime = time.Now()
+	return nil
+}
+
+// Schedule sets the due time of an object, which must be in the future.
+// This method is idempotent: the due time can be changed any number of time
+// while the object is processable. Returns error if the update fails.
+func (td *Todo) Schedule(due time.Time) error {
+	if !td.IsOngoing() {
+		return ErrFinalized
+	}
+	if due.Before(time.Now()) {
+		return ErrDueInPast
+	}
+	td.DueTime = &due
+	td.LastUpdateTime = time.Now()
+	return nil
+}
+
 // Assign grants an assignee to a todo. Assignation can only be done once,
-// e.g. Todos can't be reassigned once set. Returns error if the assignation fails.
+// e.g. Todos can't be assigned again once set: use Reassign to change the assignee.
+// Returns error if the assignation fails.
 func (td *Todo) Assign(assignee string) error {
 	if !td.IsOngoing() {
 		return ErrFinalized
@@ -151,8 +298,79 @@ func (td *Todo) Assign(assignee string) error {
 	return nil
 }
 
-// Complete marks a todo as completed, which is a final state. Hence, a todo can be only completed once.
-// Returns error if the completion fails.
+// Unassign gives an assigned todo back to the common backlog. The assignee is recorded
+// among the previous assignees. Returns error if the unassignation fails.
+func (td *Todo) Unassign() error {
+	if !td.IsOngoing() {
+		return ErrFinalized
+	}
+	if td.Status != apiv1.Assigned {
+		return ErrNotAssigned
+	}
+	td.retireAssignee()
+	td.Status = apiv1.Pending
+	td.LastUpdateTime = time.Now()
+	return nil
+}
+
+// Reassign hands an assigned todo over to a different assignee. The current assignee is recorded
+// among the previous assignees. Reassigning to the current assignee is a no-op.
+// Returns error if the reassignation fails.
+func (td *Todo) Reassign(assignee string) error {
+	if !td.IsOngoing() {
+		return ErrFinalized
+	}
+	if td.Status != apiv1.Assigned {
+		return ErrNotAssigned
+	}
+	if assignee == "" {
+		return ErrInvalidAssignee
+	}
+	if assignee == td.Assignee {
+		return nil
+	}
+	td.retireAssignee()
+	td.Assignee = assignee
+	td.LastUpdateTime = time.Now()
+	return nil
+}
+
+// Reopen makes a completed todo processable again, e.g. when a bug comes back.
+// If assignee is empty, the todo goes back to the common backlog; otherwise it is
+// assigned to the given assignee, which can be the one which completed it.
+// Returns error if the reopening fails.
+func (td *Todo) Reopen(assignee string) error {
+	if td.Status == apiv1.Deleted {
+		return ErrFinalized
+	}
+	if td.Status != apiv1.Completed {
+		return ErrNotCompleted
+	}
+	if assignee == "" {
+		td.retireAssignee()
+		td.Status = apiv1.Pending
+	} else {
+		if assignee != td.Assignee {
+			td.retireAssignee()
+			td.Assignee = assignee
+		}
+		td.Status = apiv1.Assigned
+	}
+	td.LastUpdateTime = time.Now()
+	return nil
+}
+
+// retireAssignee moves the current assignee, if any, among the previous assignees
+func (td *Todo) retireAssignee() {
+	if td.Assignee == "" {
+		return
+	}
+	td.PreviousAssignees = append(td.PreviousAssignees, td.Assignee)
+	td.Assignee = ""
+}
+
+// Complete marks a todo as completed. A completed todo is no longer processable, and can only be reopened.
+// Hence, a todo can be only completed once until reopened. Returns error if the completion fails.
 func (td *Todo) Complete() error {
 	if td.Status != apiv1.Assigned {
 		return ErrNotAssigned
@@ -195,13 +413,52 @@ func Merge(td1, td2 Todo) (Todo, error) {
 	if lastUpdateTime.Before(td2.LastUpdateTime) {
 		lastUpdateTime = td2.LastUpdateTime
 	}
+	// the merged todo is as urgent as the most urgent of the two
+	priority := td1.Priority
+	if PriorityRank(td2.Priority) > PriorityRank(td1.Priority) {
+		priority = td2.Priority
+	}
+	dueTime := td1.DueTime
+	if dueTime == nil || (td2.DueTime != nil && td2.DueTime.Before(*dueTime)) {
+		dueTime = td2.DueTime
+	}
 
 	res := Todo{
-		Title:          fmt.Sprintf("%s-%s", td1.Title, td2.Title),
-		Description:    fmt.Sprintf("%s-%s", td1.Description, td2.Description),
-		Assignee:       assignee,
-		Status:         status,
-		LastUpdateTime: lastUpdateTime,
+		Title:             fmt.Sprintf("%s-%s", td1.Title, td2.Title),
+		PreviousAssignees: mergePreviousAssignees(td1.PreviousAssignees, td2.PreviousAssignees),
+		Description:       fmt.Sprintf("%s-%s", td1.Description, td2.Description),
+		Assignee:          assignee,
+		Status:            status,
+		Priority:          priority,
+		DueTime:           dueTime,
+		Labels:            mergeLabels(td1.Labels, td2.Labels),
+		LastUpdateTime:    lastUpdateTime,
 	}
 	return res, nil
 }
+
+// mergePreviousAssignees returns the concatenation of the given previous assignees, or nil if empty
+func mergePreviousAssignees(prev1, prev2 []string) []string {
+	if len(prev1)+len(prev2) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(prev1)+len(prev2))
+	res = append(res, prev1...)
+	return append(res, prev2...)
+}
+
+// Clone returns a deep copy of the todo, which shares nothing with the original
+func (td Todo) Clone() Todo {
+	res := td
+	if td.DueTime != nil {
+		due := *td.DueTime
+		res.DueTime = &due
+	}
+	if td.Labels != nil {
+		res.Labels = append([]string(nil), td.Labels...)
+	}
+	if td.PreviousAssignees != nil {
+		res.PreviousAssignees = append([]string(nil), td.PreviousAssignees...)
+	}
+	return res
+}
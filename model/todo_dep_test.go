@@ -1,6 +1,7 @@
 package model
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -20,7 +21,7 @@ func TestNewTodo(t *testing.T) {
 		LastUpdateTime: updateTime,
 	}
 
-	if newTodo != toCompare {
+	if !reflect.DeepEqual(newTodo, toCompare) {
 		t.Fatalf("expecting %v, got %v", toCompare, newTodo)
 	}
 }
@@ -0,0 +1,45 @@
+package model_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+func TestLabels(t *testing.T) {
+	todo := model.New("foo")
+
+	if err := todo.AddLabels("infra", "docs", "infra"); err != nil {
+		t.Fatalf("add labels failed: %v", err)
+	}
+	if !reflect.DeepEqual(todo.Labels, []string{"docs", "infra"}) {
+		t.Fatalf("unexpected labels %v", todo.Labels)
+	}
+	if !todo.HasLabels("infra", "docs") || todo.HasLabels("infra", "frontend") {
+		t.Fatalf("unexpected label matching on %v", todo.Labels)
+	}
+
+	if err := todo.AddLabels("frontend", "Not Valid"); !errors.Is(err, model.ErrInvalidLabel) {
+		t.Fatalf("expected %v got %v", model.ErrInvalidLabel, err)
+	}
+	if !reflect.DeepEqual(todo.Labels, []string{"docs", "infra"}) {
+		t.Fatalf("labels changed by failed update: %v", todo.Labels)
+	}
+
+	if err := todo.RemoveLabels("docs", "frontend"); err != nil {
+		t.Fatalf("remove labels failed: %v", err)
+	}
+	if !reflect.DeepEqual(todo.Labels, []string{"infra"}) {
+		t.Fatalf("unexpected labels %v", todo.Labels)
+	}
+
+	_ = todo.Delete()
+	if err := todo.AddLabels("docs"); !errors.Is(err, model.ErrFinalized) {
+		t.Fatalf("expected %v got %v", model.ErrFinalized, err)
+	}
+	if err := todo.RemoveLabels("infra"); !errors.Is(err, model.ErrFinalized) {
+		t.Fatalf("expected %v got %v", model.ErrFinalized, err)
+	}
+}
@@ -3,6 +3,7 @@ package model_test
 // exercise
 
 import (
+	"reflect"
 	"testing"
 
 	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
@@ -33,7 +34,7 @@ func TestMerge(t *testing.T) {
 		Description: "first todo-second todo",
 		Status:      apiv1.Pending,
 	}
-	if res != expected {
+	if !reflect.DeepEqual(res, expected) {
 		t.Fatal("merged failed", err)
 	}
 }
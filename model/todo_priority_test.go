@@ -0,0 +1,86 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+func TestNewFromAPIv1Validation(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+	past := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		apiTodo  apiv1.Todo
+		expected error
+	}{
+		{
+			name:    "defaults",
+			apiTodo: apiv1.Todo{Title: "foo"},
+		},
+		{
+			name:    "valid priority and due",
+			apiTodo: apiv1.Todo{Title: "foo", Priority: apiv1.Urgent, DueTime: &future},
+		},
+		{
+			name:     "invalid priority",
+			apiTodo:  apiv1.Todo{Title: "foo", Priority: "whenever"},
+			expected: model.ErrInvalidPriority,
+		},
+		{
+			name:     "due in the past",
+			apiTodo:  apiv1.Todo{Title: "foo", DueTime: &past},
+			expected: model.ErrDueInPast,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			todo, err := model.NewFromAPIv1(tc.apiTodo)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("expected error %v got %v", tc.expected, err)
+			}
+			if err != nil {
+				return
+			}
+			if todo.ToAPIv1().Priority == "" {
+				t.Fatalf("missing default priority")
+			}
+		})
+	}
+}
+
+func TestPrioritizeScheduleFinalized(t *testing.T) {
+	todo := model.New("foo")
+	_ = todo.Assign("fede")
+	_ = todo.Complete()
+
+	if err := todo.Prioritize(apiv1.High); !errors.Is(err, model.ErrFinalized) {
+		t.Fatalf("expected %v got %v", model.ErrFinalized, err)
+	}
+	if err := todo.Schedule(time.Now().Add(time.Hour)); !errors.Is(err, model.ErrFinalized) {
+		t.Fatalf("expected %v got %v", model.ErrFinalized, err)
+	}
+}
+
+func TestIsOverdue(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	todo := model.New("foo")
+	if todo.IsOverdue(now) {
+		t.Fatalf("todo without due time can't be overdue")
+	}
+	todo.DueTime = &past
+	if !todo.IsOverdue(now) {
+		t.Fatalf("todo should be overdue")
+	}
+	_ = todo.Delete()
+	if todo.IsOverdue(now) {
+		t.Fatalf("finalized todo can't be overdue")
+	}
+}
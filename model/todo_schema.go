@@ -0,0 +1,86 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+// TodoVersion is the current schema version of the serialized todos.
+// To change the schema, add a todoVn+1 type, bump TodoVersion and register
+// in todoUpgrades the conversion from the previous version.
+const TodoVersion = 2
+
+// todoUpgrades converts the serialized todos from a schema version to the next one
+var todoUpgrades = map[int]upgrade{
+	1: upgradeTodoV1,
+}
+
+// todoV2 is the serialized todo, version 2: the field names are explicit,
+// so renaming the fields of Todo doesn't affect the stored todos.
+type todoV2 struct {
+	Title             string         `json:"title"`
+	Assignee          string         `json:"assignee,omitempty"`
+	PreviousAssignees []string       `json:"previousAssignees"`
+	Description       string         `json:"description,omitempty"`
+	Status            apiv1.Status   `json:"status"`
+	Priority          apiv1.Priority `json:"priority,omitempty"`
+	DueTime           *time.Time     `json:"dueTime,omitempty"`
+	Labels            []string       `json:"labels"`
+	LastUpdateTime    time.Time      `json:"lastUpdateTime"`
+	Revision          uint64         `json:"revision"`
+}
+
+// todoV1 is the serialized todo, version 1: the bare JSON of Todo, as it was before
+// the envelope was introduced. Do not change, not even the field names.
+type todoV1 struct {
+	Title             string
+	Assignee          string
+	PreviousAssignees []string
+	Description       string
+	Status            apiv1.Status
+	Priority          apiv1.Priority
+	DueTime           *time.Time
+	Labels            []string
+	LastUpdateTime    time.Time
+	Revision          uint64
+}
+
+func upgradeTodoV1(data json.RawMessage) (json.RawMessage, error) {
+	var v1 todoV1
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return nil, err
+	}
+	return json.Marshal(todoV2(v1))
+}
+
+func encodeTodo(td Todo) todoV2 {
+	return todoV2{
+		Title:             td.Title,
+		Assignee:          td.Assignee,
+		PreviousAssignees: td.PreviousAssignees,
+		Description:       td.Description,
+		Status:            td.Status,
+		Priority:          td.Priority,
+		DueTime:           td.DueTime,
+		Labels:            td.Labels,
+		LastUpdateTime:    td.LastUpdateTime,
+		Revision:          td.Revision,
+	}
+}
+
+func decodeTodo(v2 todoV2) Todo {
+	return Todo{
+		Title:             v2.Title,
+		Assignee:          v2.Assignee,
+		PreviousAssignees: v2.PreviousAssignees,
+		Description:       v2.Description,
+		Status:            v2.Status,
+		Priority:          v2.Priority,
+		DueTime:           v2.DueTime,
+		Labels:            v2.Labels,
+		LastUpdateTime:    v2.LastUpdateTime,
+		Revision:          v2.Revision,
+	}
+}
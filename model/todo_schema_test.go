@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+func TestSerializeEnvelope(t *testing.T) {
+	due := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	todo := model.Todo{
+		Title:             "deploy",
+		Assignee:          "ana",
+		PreviousAssignees: []string{"bob"},
+		Description:       "the frontend",
+		Status:            apiv1.Assigned,
+		Priority:          apiv1.High,
+		DueTime:           &due,
+		Labels:            []string{"ops"},
+		LastUpdateTime:    time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		Revision:          3,
+	}
+	blob, err := todo.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := model.OpenEnvelope(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Kind != model.KindTodo || env.Version != model.TodoVersion || env.Codec != model.CodecJSON {
+		t.Fatalf("unexpected envelope: %+v", env)
+	}
+	got, err := model.DeserializeTodo(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, todo) {
+		t.Fatalf("expected %#v got %#v", todo, got)
+	}
+}
+
+func TestDeserializeLegacyTodo(t *testing.T) {
+	// as serialized before the envelope was introduced
+	legacy := `{"Title":"deploy","Assignee":"ana","PreviousAssignees":["bob"],"Description":"the frontend",` +
+		`"Status":"assigned","Priority":"high","DueTime":"2026-04-01T09:00:00Z","Labels":["ops"],` +
+		`"LastUpdateTime":"2026-03-10T12:00:00Z","Revision":3}` + "\n"
+	got, err := model.DeserializeTodo([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	expected := model.Todo{
+		Title:             "deploy",
+		Assignee:          "ana",
+		PreviousAssignees: []string{"bob"},
+		Description:       "the frontend",
+		Status:            apiv1.Assigned,
+		Priority:          apiv1.High,
+		DueTime:           &due,
+		Labels:            []string{"ops"},
+		LastUpdateTime:    time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		Revision:          3,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %#v got %#v", expected, got)
+	}
+
+	// the oldest todos lack most of the fields
+	got, err = model.DeserializeTodo([]byte(`{"Title":"old","Status":"pending","LastUpdateTime":"2014-02-04T00:00:00Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "old" || got.Status != apiv1.Pending || got.Labels != nil || got.DueTime != nil {
+		t.Fatalf("unexpected todo: %#v", got)
+	}
+}
+
+func TestDeserializeInvalidEnvelope(t *testing.T) {
+	envelope := func(kind string, version int, codec string) []byte {
+		blob, err := json.Marshal(model.Envelope{Kind: kind, Version: version, Codec: codec, Data: json.RawMessage(`{"title":"foo"}`)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return blob
+	}
+	tests := []struct {
+		name     string
+		blob     []byte
+		expected error
+	}{
+		{"future version", envelope(model.KindTodo, model.TodoVersion+1, model.CodecJSON), model.ErrUnsupportedVersion},
+		{"unknown codec", envelope(model.KindTodo, model.TodoVersion, "gob"), model.ErrUnsupportedCodec},
+		{"other kind", envelope("webhook", 1, model.CodecJSON), model.ErrUnexpectedKind},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := model.DeserializeTodo(tc.blob); !errors.Is(err, tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, err)
+			}
+		})
+	}
+	if _, err := model.DeserializeTodo([]byte("not json")); err == nil {
+		t.Fatal("expected a error decoding garbage")
+	}
+}
@@ -0,0 +1,178 @@
+package model_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/model"
+)
+
+func TestTransitions(t *testing.T) {
+	assigned := func(assignee string) model.Todo {
+		todo := model.New("foo")
+		if err := todo.Assign(assignee); err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+	completed := func(assignee string) model.Todo {
+		todo := assigned(assignee)
+		if err := todo.Complete(); err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+	deleted := func() model.Todo {
+		todo := model.New("foo")
+		if err := todo.Delete(); err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+
+	tests := []struct {
+		name             string
+		todo             model.Todo
+		transition       func(todo *model.Todo) error
+		expectedErr      error
+		expectedStatus   apiv1.Status
+		expectedAssignee string
+		expectedPrevious []string
+	}{
+		{
+			name:             "unassign",
+			todo:             assigned("alice"),
+			transition:       (*model.Todo).Unassign,
+			expectedStatus:   apiv1.Pending,
+			expectedPrevious: []string{"alice"},
+		},
+		{
+			name:           "unassign pending",
+			todo:           model.New("foo"),
+			transition:     (*model.Todo).Unassign,
+			expectedErr:    model.ErrNotAssigned,
+			expectedStatus: apiv1.Pending,
+		},
+		{
+			name:             "unassign completed",
+			todo:             completed("alice"),
+			transition:       (*model.Todo).Unassign,
+			expectedErr:      model.ErrFinalized,
+			expectedStatus:   apiv1.Completed,
+			expectedAssignee: "alice",
+		},
+		{
+			name:             "reassign",
+			todo:             assigned("alice"),
+			transition:       func(todo *model.Todo) error { return todo.Reassign("bob") },
+			expectedStatus:   apiv1.Assigned,
+			expectedAssignee: "bob",
+			expectedPrevious: []string{"alice"},
+		},
+		{
+			name:             "reassign to the same assignee",
+			todo:             assigned("alice"),
+			transition:       func(todo *model.Todo) error { return todo.Reassign("alice") },
+			expectedStatus:   apiv1.Assigned,
+			expectedAssignee: "alice",
+		},
+		{
+			name:             "reassign to nobody",
+			todo:             assigned("alice"),
+			transition:       func(todo *model.Todo) error { return todo.Reassign("") },
+			expectedErr:      model.ErrInvalidAssignee,
+			expectedStatus:   apiv1.Assigned,
+			expectedAssignee: "alice",
+		},
+		{
+			name:           "reassign pending",
+			todo:           model.New("foo"),
+			transition:     func(todo *model.Todo) error { return todo.Reassign("bob") },
+			expectedErr:    model.ErrNotAssigned,
+			expectedStatus: apiv1.Pending,
+		},
+		{
+			name:             "reopen to the backlog",
+			todo:             completed("alice"),
+			transition:       func(todo *model.Todo) error { return todo.Reopen("") },
+			expectedStatus:   apiv1.Pending,
+			expectedPrevious: []string{"alice"},
+		},
+		{
+			name:             "reopen to the same assignee",
+			todo:             completed("alice"),
+			transition:       func(todo *model.Todo) error { return todo.Reopen("alice") },
+			expectedStatus:   apiv1.Assigned,
+			expectedAssignee: "alice",
+		},
+		{
+			name:             "reopen to another assignee",
+			todo:             completed("alice"),
+			transition:       func(todo *model.Todo) error { return todo.Reopen("bob") },
+			expectedStatus:   apiv1.Assigned,
+			expectedAssignee: "bob",
+			expectedPrevious: []string{"alice"},
+		},
+		{
+			name:             "reopen ongoing",
+			todo:             assigned("alice"),
+			transition:       func(todo *model.Todo) error { return todo.Reopen("") },
+			expectedErr:      model.ErrNotCompleted,
+			expectedStatus:   apiv1.Assigned,
+			expectedAssignee: "alice",
+		},
+		{
+			name:           "reopen deleted",
+			todo:           deleted(),
+			transition:     func(todo *model.Todo) error { return todo.Reopen("") },
+			expectedErr:    model.ErrFinalized,
+			expectedStatus: apiv1.Deleted,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			todo := tc.todo
+			err := tc.transition(&todo)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v got %v", tc.expectedErr, err)
+			}
+			if todo.Status != tc.expectedStatus {
+				t.Errorf("expected status %v got %v", tc.expectedStatus, todo.Status)
+			}
+			if todo.Assignee != tc.expectedAssignee {
+				t.Errorf("expected assignee %q got %q", tc.expectedAssignee, todo.Assignee)
+			}
+			if !reflect.DeepEqual(todo.PreviousAssignees, tc.expectedPrevious) {
+				t.Errorf("expected previous assignees %v got %v", tc.expectedPrevious, todo.PreviousAssignees)
+			}
+		})
+	}
+}
+
+func TestReassignHistory(t *testing.T) {
+	todo := model.New("foo")
+	steps := []func() error{
+		func() error { return todo.Assign("alice") },
+		func() error { return todo.Reassign("bob") },
+		func() error { return todo.Complete() },
+		func() error { return todo.Reopen("carol") },
+		func() error { return todo.Unassign() },
+		func() error { return todo.Assign("alice") },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d failed: %v", i, err)
+		}
+	}
+
+	expected := []string{"alice", "bob", "carol"}
+	if !reflect.DeepEqual(todo.PreviousAssignees, expected) {
+		t.Fatalf("expected previous assignees %v got %v", expected, todo.PreviousAssignees)
+	}
+	if todo.Status != apiv1.Assigned || todo.Assignee != "alice" {
+		t.Fatalf("unexpected final state: %v", todo)
+	}
+}
@@ -0,0 +1,4 @@
+// Package search implements a in-memory full-text index over the title and description
+// of the todos. The index is inverted: it maps every term to the todos including it,
+// so queries only look at the todos matching their terms.
+package search
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+const (
+	// titleWeight is how much a term in the title counts more than one in the description
+	titleWeight = 2.0
+	// prefixWeight is how much a term matched by prefix counts with respect to a exact match
+	prefixWeight = 0.5
+)
+
+// occurrences counts the occurrences of a term in a todo
+type occurrences struct {
+	title       int
+	description int
+}
+
+// Hit is a todo matching a query, with its relevance
+type Hit struct {
+	ID    store.ID
+	Score float64
+}
+
+// Index is a inverted index of the todo texts. Index is not safe for concurrent use.
+type Index struct {
+	// postings maps every term to the todos including it
+	postings map[string]map[store.ID]occurrences
+	// docs maps every todo to its terms, to remove them
+	docs map[store.ID][]string
+	// terms are all the indexed terms, sorted, for the prefix lookups
+	terms []string
+}
+
+// NewIndex creates a empty index
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[store.ID]occurrences),
+		docs:     make(map[store.ID][]string),
+	}
+}
+
+// Len returns the number of todos indexed
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+// Tokenize splits a text in terms: lowercase sequences of letters and digits
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(ch rune) bool {
+		return !unicode.IsLetter(ch) && !unicode.IsDigit(ch)
+	})
+}
+
+// Add indexes a todo, replacing its previous version if already indexed
+func (idx *Index) Add(id store.ID, todo model.Todo) {
+	idx.Remove(id)
+	counts := make(map[string]occurrences)
+	for _, term := range Tokenize(todo.Title) {
+		occ := counts[term]
+		occ.title++
+		counts[term] = occ
+	}
+	for _, term := range Tokenize(todo.Description) {
+		occ := counts[term]
+		occ.description++
+		counts[term] = occ
+	}
+	terms := make([]string, 0, len(counts))
+	for term, occ := range counts {
+		posting, ok := idx.postings[term]
+		if !ok {
+			posting = make(map[store.ID]occurrences)
+			idx.postings[term] = posting
+			idx.insertTerm(term)
+		}
+		posting[id] = occ
+		terms = append(terms, term)
+	}
+	idx.docs[id] = terms
+}
+
+// Remove drops a todo from the index. Removing a todo not indexed does nothing.
+func (idx *Index) Remove(id store.ID) {
+	terms, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range terms {
+		posting := idx.postings[term]
+		delete(posting, id)
+		if len(posting) == 0 {
+			delete(idx.postings, term)
+			idx.removeTerm(term)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+func (idx *Index) insertTerm(term string) {
+	pos := sort.SearchStrings(idx.terms, term)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[pos+1:], idx.terms[pos:])
+	idx.terms[pos] = term
+}
+
+func (idx *Index) removeTerm(term string) {
+	pos := sort.SearchStrings(idx.terms, term)
+	if pos < len(idx.terms) && idx.terms[pos] == term {
+		idx.terms = append(idx.terms[:pos], idx.terms[pos+1:]...)
+	}
+}
+
+// Search returns the todos including all the terms of the text, most relevant first.
+// Each term matches the indexed terms it is a prefix of, but exact matches count more,
+// as do matches in the title and matches of rarer terms. A text without terms matches nothing.
+func (idx *Index) Search(text string) []Hit {
+	queryTerms := Tokenize(text)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+	var scores map[store.ID]float64
+	for _, queryTerm := range queryTerms {
+		termScores := idx.searchTerm(queryTerm)
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		// all the terms must match
+		for id, score := range scores {
+			termScore, ok := termScores[id]
+			if !ok {
+				delete(scores, id)
+				continue
+			}
+			scores[id] = score + termScore
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// searchTerm scores the todos including a indexed term with the given prefix
+func (idx *Index) searchTerm(prefix string) map[store.ID]float64 {
+	scores := make(map[store.ID]float64)
+	total := float64(len(idx.docs))
+	for pos := sort.SearchStrings(idx.terms, prefix); pos < len(idx.terms); pos++ {
+		term := idx.terms[pos]
+		if !strings.HasPrefix(term, prefix) {
+			break
+		}
+		posting := idx.postings[term]
+		weight := math.Log(1 + total/float64(len(posting)))
+		if term != prefix {
+			weight *= prefixWeight
+		}
+		for id, occ := range posting {
+			scores[id] += weight * (titleWeight*float64(occ.title) + float64(occ.description))
+		}
+	}
+	return scores
+}
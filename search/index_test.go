@@ -0,0 +1,71 @@
+package search_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/model"
+	"github.com/gotestbootcamp/go-todo-app/search"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+func TestTokenize(t *testing.T) {
+	got := search.Tokenize("Deploy the front-end, v2.1 (ASAP)!")
+	expected := []string{"deploy", "the", "front", "end", "v2", "1", "asap"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v got %v", expected, got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx := search.NewIndex()
+	idx.Add("a", model.Todo{Title: "deploy backend", Description: "after the migration"})
+	idx.Add("b", model.Todo{Title: "write docs", Description: "explain how to deploy"})
+	idx.Add("c", model.Todo{Title: "deployment pipeline"})
+	idx.Add("d", model.Todo{Title: "fix login", Description: "the backend rejects valid tokens"})
+
+	tests := []struct {
+		text     string
+		expected []store.ID
+	}{
+		// title matches count more than description ones, exact matches more than prefix ones
+		{"deploy", []store.ID{"a", "c", "b"}},
+		{"DEPLOY backend", []store.ID{"a"}},
+		{"backend", []store.ID{"a", "d"}},
+		{"back", []store.ID{"a", "d"}},
+		{"deploy login", nil},
+		{"nothing", nil},
+		{"  ,; ", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			var got []store.ID
+			for _, hit := range idx.Search(tc.text) {
+				got = append(got, hit.ID)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSearchUpdates(t *testing.T) {
+	idx := search.NewIndex()
+	idx.Add("a", model.Todo{Title: "deploy backend"})
+	idx.Add("a", model.Todo{Title: "write docs"})
+	if hits := idx.Search("deploy"); len(hits) != 0 {
+		t.Fatalf("stale terms still indexed: %v", hits)
+	}
+	if hits := idx.Search("docs"); len(hits) != 1 || hits[0].ID != "a" {
+		t.Fatalf("unexpected hits: %v", hits)
+	}
+	idx.Remove("a")
+	idx.Remove("missing")
+	if hits := idx.Search("docs"); len(hits) != 0 {
+		t.Fatalf("removed todo still indexed: %v", hits)
+	}
+	if idx.Len() != 0 {
+		t.Fatalf("expected empty index, got %d todos", idx.Len())
+	}
+}
@@ -1,17 +1,28 @@
 package fake
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
 	"github.com/gotestbootcamp/go-todo-app/store"
 )
 
+// Mem is safe for concurrent use through its methods; its fields, meant to let tests
+// inspect the content and inject failures, must not be changed concurrently with them.
 type Mem struct {
+	lock  sync.Mutex
 	Blobs map[store.ID]store.Blob
 	Error error
 	// Generate returns a generated item, true when the generation ends, error to abort the generation eith error
 	Generate func() (store.Item, bool, error)
+	closed   bool
 }
 
 var _ store.Storage = &Mem{}
+var _ store.CompareAndSwapper = &Mem{}
+var _ store.Batcher = &Mem{}
 
 func NewMem() (*Mem, error) {
 	return &Mem{
@@ -23,22 +34,37 @@ func NewMem() (*Mem, error) {
 }
 
 func (mm *Mem) Close() error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	mm.closed = true
 	return mm.Error
 }
 
-func (mm *Mem) Create(objectID store.ID, data store.Blob) error {
-	if mm.Error != nil {
-		return mm.Error
+func (mm *Mem) Create(ctx context.Context, objectID store.ID, data store.Blob) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if err := mm.check(ctx); err != nil {
+		return err
+	}
+	if _, ok := mm.Blobs[objectID]; ok {
+		return store.ErrAlreadyExists{ID: objectID}
 	}
 	mm.Blobs[objectID] = data
 	return nil
 }
 
-func (mm *Mem) LoadAll() ([]store.Item, error) {
-	if mm.Error != nil {
-		return nil, mm.Error
+// LoadAll returns all the stored items, followed by all the items
+// produced by the Generate function.
+func (mm *Mem) LoadAll(ctx context.Context) ([]store.Item, error) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if err := mm.check(ctx); err != nil {
+		return nil, err
 	}
 	var items []store.Item
+	for id, blob := range mm.Blobs {
+		items = append(items, store.Item{ID: id, Blob: blob})
+	}
 	for {
 		item, done, err := mm.Generate()
 		if err != nil {
@@ -52,9 +78,11 @@ func (mm *Mem) LoadAll() ([]store.Item, error) {
 	return items, nil
 }
 
-func (mm *Mem) Load(id store.ID) (store.Blob, error) {
-	if mm.Error != nil {
-		return nil, mm.Error
+func (mm *Mem) Load(ctx context.Context, id store.ID) (store.Blob, error) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if err := mm.check(ctx); err != nil {
+		return nil, err
 	}
 	blob, ok := mm.Blobs[id]
 	if !ok {
@@ -63,9 +91,11 @@ func (mm *Mem) Load(id store.ID) (store.Blob, error) {
 	return blob, nil
 }
 
-func (mm *Mem) Save(id store.ID, blob store.Blob) error {
-	if mm.Error != nil {
-		return mm.Error
+func (mm *Mem) Save(ctx context.Context, id store.ID, blob store.Blob) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if err := mm.check(ctx); err != nil {
+		return err
 	}
 	_, ok := mm.Blobs[id]
 	if !ok {
@@ -75,9 +105,28 @@ func (mm *Mem) Save(id store.ID, blob store.Blob) error {
 	return nil
 }
 
-func (mm *Mem) Delete(id store.ID) error {
-	if mm.Error != nil {
-		return mm.Error
+func (mm *Mem) CompareAndSwap(ctx context.Context, id store.ID, old, blob store.Blob) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if err := mm.check(ctx); err != nil {
+		return err
+	}
+	cur, ok := mm.Blobs[id]
+	if !ok {
+		return store.ErrNotFound{ID: id}
+	}
+	if !bytes.Equal(cur, old) {
+		return store.ErrConflict{ID: id}
+	}
+	mm.Blobs[id] = blob
+	return nil
+}
+
+func (mm *Mem) Delete(ctx context.Context, id store.ID) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if err := mm.check(ctx); err != nil {
+		return err
 	}
 	_, ok := mm.Blobs[id]
 	if !ok {
@@ -86,3 +135,71 @@ func (mm *Mem) Delete(id store.ID) error {
 	delete(mm.Blobs, id)
 	return nil
 }
+
+// Batch checks all the operations against the current content, then applies them wholesale.
+func (mm *Mem) Batch(ctx context.Context, ops []store.Op) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	if err := mm.check(ctx); err != nil {
+		return err
+	}
+	exists := make(map[store.ID]bool, len(ops))
+	// the blobs written by the previous operations
+	written := make(map[store.ID]store.Blob, len(ops))
+	for _, op := range ops {
+		found, ok := exists[op.ID]
+		if !ok {
+			_, found = mm.Blobs[op.ID]
+		}
+		switch op.Kind {
+		case store.OpCreate:
+			if found {
+				return store.ErrAlreadyExists{ID: op.ID}
+			}
+			exists[op.ID] = true
+			written[op.ID] = op.Blob
+		case store.OpSave:
+			if !found {
+				return store.ErrNotFound{ID: op.ID}
+			}
+			if op.Old != nil {
+				cur, ok := written[op.ID]
+				if !ok {
+					cur = mm.Blobs[op.ID]
+				}
+				if !bytes.Equal(cur, op.Old) {
+					return store.ErrConflict{ID: op.ID}
+				}
+			}
+			written[op.ID] = op.Blob
+		case store.OpDelete:
+			if !found {
+				return store.ErrNotFound{ID: op.ID}
+			}
+			exists[op.ID] = false
+		default:
+			return fmt.Errorf("unknown operation kind: %v", op.Kind)
+		}
+	}
+	for _, op := range ops {
+		if op.Kind == store.OpDelete {
+			delete(mm.Blobs, op.ID)
+			continue
+		}
+		mm.Blobs[op.ID] = op.Blob
+	}
+	return nil
+}
+
+func (mm *Mem) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if mm.Error != nil {
+		return mm.Error
+	}
+	if mm.closed {
+		return store.ErrClosed
+	}
+	return nil
+}
@@ -1,18 +1,20 @@
 package fake
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/storetest"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewEmpty(t *testing.T) {
 	st, err := NewMem()
 	assert.NoError(t, err)
-	items, err := st.LoadAll()
+	items, err := st.LoadAll(context.Background())
 	assert.NoError(t, err)
 	assert.Empty(t, items)
 }
@@ -31,7 +33,7 @@ func TestLoadAllLoad(t *testing.T) {
 		num += 1
 		return item, done, nil
 	}
-	items, err := st.LoadAll()
+	items, err := st.LoadAll(context.Background())
 	assert.Equal(t, len(items), count)
 }
 
@@ -59,35 +61,37 @@ func TestCreateError(t *testing.T) {
 	st.Error = expErr
 
 	val := "foobar"
-	err = st.Create("1", store.Blob(val))
+	err = st.Create(context.Background(), "1", store.Blob(val))
 	assert.ErrorIs(t, err, expErr)
 }
 
 func TestCreateLoad(t *testing.T) {
+	ctx := context.Background()
 	st, err := NewMem()
 	assert.NoError(t, err)
 
 	val := "foobar"
-	err = st.Create("1", store.Blob(val))
+	err = st.Create(ctx, "1", store.Blob(val))
 	assert.NoError(t, err)
 
-	blob, err := st.Load("1")
+	blob, err := st.Load(ctx, "1")
 	assert.Equal(t, string(blob), val, "retrieved object different from inserted")
 }
 
 func TestLoadWithError(t *testing.T) {
+	ctx := context.Background()
 	st, err := NewMem()
 	assert.NoError(t, err)
 
 	val := "foobar"
-	err = st.Create("1", store.Blob(val))
+	err = st.Create(ctx, "1", store.Blob(val))
 	assert.NoError(t, err)
 
 	// without this error injection, Load() will succeed
 	expErr := errors.New("injected load error")
 	st.Error = expErr
 
-	_, err = st.Load("1")
+	_, err = st.Load(ctx, "1")
 	assert.ErrorIs(t, err, expErr)
 }
 
@@ -95,7 +99,7 @@ func TestLoadFromEmpty(t *testing.T) {
 	st, err := NewMem()
 	assert.NoError(t, err)
 
-	_, err = st.Load("999")
+	_, err = st.Load(context.Background(), "999")
 	assert.ErrorIs(t, err, store.ErrNotFound{ID: "999"})
 }
 
@@ -104,7 +108,7 @@ func TestSaveFromEmpty(t *testing.T) {
 	assert.NoError(t, err)
 
 	val := "foobar"
-	err = st.Save("999", store.Blob(val))
+	err = st.Save(context.Background(), "999", store.Blob(val))
 	assert.ErrorIs(t, err, store.ErrNotFound{ID: "999"})
 }
 
@@ -115,7 +119,7 @@ func TestDeleteWithError(t *testing.T) {
 	expErr := errors.New("injected delete error")
 	st.Error = expErr
 
-	err = st.Delete(store.ID("999"))
+	err = st.Delete(context.Background(), store.ID("999"))
 	assert.ErrorIs(t, err, expErr)
 }
 
@@ -123,38 +127,48 @@ func TestDeleteFromEmpty(t *testing.T) {
 	st, err := NewMem()
 	assert.NoError(t, err)
 
-	err = st.Delete("999")
+	err = st.Delete(context.Background(), "999")
 	assert.ErrorIs(t, err, store.ErrNotFound{ID: "999"})
 }
 
 func TestCreateSaveLoad(t *testing.T) {
+	ctx := context.Background()
 	st, err := NewMem()
 	assert.NoError(t, err)
 
 	val := "foobar"
-	err = st.Create("123", store.Blob(val))
+	err = st.Create(ctx, "123", store.Blob(val))
 	assert.NoError(t, err)
 
 	val2 := "fizzbuzz"
-	err = st.Save("123", store.Blob(val2))
+	err = st.Save(ctx, "123", store.Blob(val2))
 	assert.NoError(t, err)
 
-	blob, err := st.Load("123")
+	blob, err := st.Load(ctx, "123")
 	assert.Equal(t, string(blob), val2, "retrieved object different from insterted")
 }
 
 func TestCreateDeleteLoad(t *testing.T) {
+	ctx := context.Background()
 	st, err := NewMem()
 	assert.NoError(t, err)
 
 	id := store.ID("543")
 	val := "foobar"
-	err = st.Create(id, store.Blob(val))
+	err = st.Create(ctx, id, store.Blob(val))
 	assert.NoError(t, err)
 
-	err = st.Delete(id)
+	err = st.Delete(ctx, id)
 	assert.NoError(t, err)
 
-	_, err = st.Load(id)
+	_, err = st.Load(ctx, id)
 	assert.ErrorIs(t, err, store.ErrNotFound{ID: id})
 }
+
+func TestConformance(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.Storage {
+		st, err := NewMem()
+		assert.NoError(t, err)
+		return st
+	})
+}
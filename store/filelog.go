@@ -0,0 +1,486 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// DefaultCompactThreshold is the number of dead records which triggers a log compaction
+	DefaultCompactThreshold = 1024
+
+	fileLogName = "todos.log"
+
+	// record header: op (1) + id length (4) + blob length (4) + crc32 (4)
+	recordHeaderSize = 13
+	maxIDLen         = 1 << 16
+	maxBlobLen       = 1 << 30
+)
+
+type recordOp byte
+
+const (
+	opPut    recordOp = 1
+	opDelete recordOp = 2
+)
+
+var _ Storage = &FileLog{}
+var _ CompareAndSwapper = &FileLog{}
+
+// FileLog is a durable Storage backed by a append-only log file on the local filesystem.
+// Every mutation is appended to the log and synced on disk before being acknowledged.
+// Records made obsolete by later mutations are dropped by a background compaction,
+// which is triggered once their amount crosses the configured threshold.
+// FileLog is safe for concurrent use.
+type FileLog struct {
+	path      string
+	threshold int
+
+	lock       sync.Mutex
+	file       *os.File
+	size       int64
+	index      map[ID]recordPos
+	dead       int
+	compacting bool
+	closed     bool
+	wg         sync.WaitGroup
+}
+
+// recordPos is the location of a blob in the log file
+type recordPos struct {
+	offset int64
+	size   int
+}
+
+// NewFileLog opens, creating it if needed, the log stored in the given directory.
+// compactThreshold is the number of dead records which triggers a compaction;
+// if not positive, DefaultCompactThreshold is used instead.
+func NewFileLog(dir string, compactThreshold int) (*FileLog, error) {
+	if compactThreshold <= 0 {
+		compactThreshold = DefaultCompactThreshold
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fileLogName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fl := FileLog{
+		path:      path,
+		threshold: compactThreshold,
+		file:      file,
+		index:     make(map[ID]recordPos),
+	}
+	end, err := replay(file, 0, func(op recordOp, id ID, pos recordPos) error {
+		fl.dead += applyRecord(fl.index, op, id, pos)
+		return nil
+	})
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		file.Close()
+		if errors.Is(err, errCorruptedRecord) {
+			return nil, ErrCorruptedContent{Name: path}
+		}
+		return nil, err
+	}
+	if err != nil {
+		// a torn write at the end of the log: the record was never acknowledged
+		log.Printf("filelog: truncating incomplete record at offset %d", end)
+		if err := file.Truncate(end); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	fl.size = end
+	log.Printf("filelog: opened %q: %d live records, %d dead records", path, len(fl.index), fl.dead)
+	return &fl, nil
+}
+
+func (fl *FileLog) Close() error {
+	fl.lock.Lock()
+	if fl.closed {
+		fl.lock.Unlock()
+		return ErrClosed
+	}
+	fl.closed = true
+	fl.lock.Unlock()
+
+	fl.wg.Wait() // let the compaction, if any, complete
+	return fl.file.Close()
+}
+
+func (fl *FileLog) Create(ctx context.Context, objectID ID, data Blob) error {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	if err := fl.check(ctx); err != nil {
+		return err
+	}
+	if _, ok := fl.index[objectID]; ok {
+		return ErrAlreadyExists{ID: objectID}
+	}
+	return fl.appendRecord(opPut, objectID, data)
+}
+
+// LoadAll replays the log and returns all the live items found.
+func (fl *FileLog) LoadAll(ctx context.Context) ([]Item, error) {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	if err := fl.check(ctx); err != nil {
+		return nil, err
+	}
+	index := make(map[ID]recordPos, len(fl.index))
+	_, err := replay(io.NewSectionReader(fl.file, 0, fl.size), 0, func(op recordOp, id ID, pos recordPos) error {
+		applyRecord(index, op, id, pos)
+		return nil
+	})
+	if err != nil {
+		return nil, ErrCorruptedContent{Name: fl.path}
+	}
+	items := make([]Item, 0, len(index))
+	for id, pos := range index {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		blob, err := fl.readBlob(pos)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, Item{ID: id, Blob: blob})
+	}
+	return items, nil
+}
+
+func (fl *FileLog) Load(ctx context.Context, objectID ID) (Blob, error) {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	if err := fl.check(ctx); err != nil {
+		return nil, err
+	}
+	pos, ok := fl.index[objectID]
+	if !ok {
+		return nil, ErrNotFound{ID: objectID}
+	}
+	return fl.readBlob(pos)
+}
+
+func (fl *FileLog) Save(ctx context.Context, objectID ID, blob Blob) error {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	if err := fl.check(ctx); err != nil {
+		return err
+	}
+	if _, ok := fl.index[objectID]; !ok {
+		return ErrNotFound{ID: objectID}
+	}
+	return fl.appendRecord(opPut, objectID, blob)
+}
+
+func (fl *FileLog) CompareAndSwap(ctx context.Context, objectID ID, old, blob Blob) error {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	if err := fl.check(ctx); err != nil {
+		return err
+	}
+	pos, ok := fl.index[objectID]
+	if !ok {
+		return ErrNotFound{ID: objectID}
+	}
+	cur, err := fl.readBlob(pos)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(cur, old) {
+		return ErrConflict{ID: objectID}
+	}
+	return fl.appendRecord(opPut, objectID, blob)
+}
+
+func (fl *FileLog) Delete(ctx context.Context, objectID ID) error {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	if err := fl.check(ctx); err != nil {
+		return err
+	}
+	if _, ok := fl.index[objectID]; !ok {
+		return ErrNotFound{ID: objectID}
+	}
+	return fl.appendRecord(opDelete, objectID, nil)
+}
+
+// check returns the error of the context, if done, or ErrClosed. Must be called with the lock held.
+func (fl *FileLog) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if fl.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// appendRecord writes and syncs a record at the end of the log, then updates the index.
+// Must be called with the lock held.
+func (fl *FileLog) appendRecord(op recordOp, objectID ID, blob Blob) error {
+	// replay would refuse the record, and so the whole log, on the next open
+	if len(objectID) > maxIDLen || len(blob) > maxBlobLen {
+		return fmt.Errorf("%w: %d bytes id, %d bytes blob", errRecordTooLarge, len(objectID), len(blob))
+	}
+	data := encodeRecord(op, objectID, blob)
+	if _, err := fl.file.WriteAt(data, fl.size); err != nil {
+		// don't leave a partial record behind
+		_ = fl.file.Truncate(fl.size)
+		return err
+	}
+	if err := fl.file.Sync(); err != nil {
+		_ = fl.file.Truncate(fl.size)
+		return err
+	}
+	pos := recordPos{
+		offset: fl.size + int64(recordHeaderSize+len(objectID)),
+		size:   len(blob),
+	}
+	fl.size += int64(len(data))
+	fl.dead += applyRecord(fl.index, op, objectID, pos)
+
+	fl.maybeCompact()
+	return nil
+}
+
+// maybeCompact starts a background compaction if there are enough dead records.
+// Must be called with the lock held.
+func (fl *FileLog) maybeCompact() {
+	if fl.dead < fl.threshold || fl.compacting || fl.closed {
+		return
+	}
+	fl.compacting = true
+	fl.wg.Add(1)
+	go fl.compact()
+}
+
+func (fl *FileLog) readBlob(pos recordPos) (Blob, error) {
+	blob := make(Blob, pos.size)
+	if _, err := fl.file.ReadAt(blob, pos.offset); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// compact rewrites the log keeping only the live records. Most of the work is done
+// without holding the lock, against a snapshot of the index: records appended
+// meanwhile are copied over once the snapshot is written.
+func (fl *FileLog) compact() {
+	defer fl.wg.Done()
+
+	fl.lock.Lock()
+	src := fl.file
+	snapEnd := fl.size
+	snapshot := make(map[ID]recordPos, len(fl.index))
+	for id, pos := range fl.index {
+		snapshot[id] = pos
+	}
+	fl.lock.Unlock()
+
+	tmpPath := fl.path + ".compact"
+	dst, index, size, err := writeSnapshot(tmpPath, src, snapshot)
+
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	fl.compacting = false
+	if err == nil && fl.closed {
+		err = ErrClosed
+	}
+	if err == nil {
+		var dead int
+		dead, size, err = copyTail(dst, size, io.NewSectionReader(src, snapEnd, fl.size-snapEnd), index)
+		if err == nil {
+			err = swapFile(tmpPath, fl.path)
+		}
+		if err == nil {
+			log.Printf("filelog: compacted %q: %d -> %d bytes", fl.path, fl.size, size)
+			src.Close()
+			fl.file = dst
+			fl.size = size
+			fl.index = index
+			fl.dead = dead
+			// records appended meanwhile may already need another round
+			fl.maybeCompact()
+			return
+		}
+	}
+	log.Printf("filelog: compaction of %q aborted: %v", fl.path, err)
+	if dst != nil {
+		dst.Close()
+	}
+	os.Remove(tmpPath)
+}
+
+// writeSnapshot writes the given records, read from src, in a new log file.
+// Returns the open new log file, the index of its records and its size.
+func writeSnapshot(path string, src io.ReaderAt, snapshot map[ID]recordPos) (*os.File, map[ID]recordPos, int64, error) {
+	dst, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	ids := make([]ID, 0, len(snapshot))
+	for id := range snapshot {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	w := bufio.NewWriter(dst)
+	index := make(map[ID]recordPos, len(snapshot))
+	var size int64
+	for _, id := range ids {
+		pos := snapshot[id]
+		blob := make(Blob, pos.size)
+		if _, err := src.ReadAt(blob, pos.offset); err != nil {
+			return dst, nil, 0, err
+		}
+		data := encodeRecord(opPut, id, blob)
+		if _, err := w.Write(data); err != nil {
+			return dst, nil, 0, err
+		}
+		index[id] = recordPos{
+			offset: size + int64(recordHeaderSize+len(id)),
+			size:   pos.size,
+		}
+		size += int64(len(data))
+	}
+	if err := w.Flush(); err != nil {
+		return dst, nil, 0, err
+	}
+	return dst, index, size, nil
+}
+
+// copyTail appends to dst, starting at offset size, the records found in tail,
+// updating the index accordingly. Returns the dead records count and the new size of dst.
+func copyTail(dst *os.File, size int64, tail *io.SectionReader, index map[ID]recordPos) (int, int64, error) {
+	var dead int
+	_, err := replay(tail, size, func(op recordOp, id ID, pos recordPos) error {
+		dead += applyRecord(index, op, id, pos)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	written, err := io.Copy(io.NewOffsetWriter(dst, size), io.NewSectionReader(tail, 0, tail.Size()))
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := dst.Sync(); err != nil {
+		return 0, 0, err
+	}
+	return dead, size + written, nil
+}
+
+// swapFile atomically replaces the log file with the compacted one
+func swapFile(tmpPath, path string) error {
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// applyRecord updates the index with the given record, returning how many records became dead.
+func applyRecord(index map[ID]recordPos, op recordOp, id ID, pos recordPos) int {
+	_, existing := index[id]
+	switch op {
+	case opDelete:
+		delete(index, id)
+		// both the tombstone and the deleted record are dead
+		if existing {
+			return 2
+		}
+		return 1
+	default:
+		index[id] = pos
+		if existing {
+			return 1
+		}
+		return 0
+	}
+}
+
+var (
+	errCorruptedRecord = errors.New("corrupted record")
+	errRecordTooLarge  = errors.New("record too large")
+)
+
+// replay reads all the records from r, calling fn for each of them.
+// base is the offset of r in the log file, used to compute the record positions.
+// Returns the offset past the last valid record. If the last record is incomplete,
+// returns io.ErrUnexpectedEOF; if a record fails the validation, returns errCorruptedRecord.
+func replay(r io.Reader, base int64, fn func(op recordOp, id ID, pos recordPos) error) (int64, error) {
+	br := bufio.NewReader(r)
+	offset := base
+	hdr := make([]byte, recordHeaderSize)
+	for {
+		_, err := io.ReadFull(br, hdr)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		op := recordOp(hdr[0])
+		idLen := binary.BigEndian.Uint32(hdr[1:5])
+		blobLen := binary.BigEndian.Uint32(hdr[5:9])
+		checksum := binary.BigEndian.Uint32(hdr[9:13])
+		if (op != opPut && op != opDelete) || idLen > maxIDLen || blobLen > maxBlobLen {
+			return offset, errCorruptedRecord
+		}
+		payload := make([]byte, int(idLen)+int(blobLen))
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return offset, err
+		}
+		crc := crc32.NewIEEE()
+		crc.Write(hdr[:9])
+		crc.Write(payload)
+		if crc.Sum32() != checksum {
+			return offset, errCorruptedRecord
+		}
+		id := ID(payload[:idLen])
+		pos := recordPos{
+			offset: offset + int64(recordHeaderSize) + int64(idLen),
+			size:   int(blobLen),
+		}
+		if err := fn(op, id, pos); err != nil {
+			return offset, err
+		}
+		offset += int64(recordHeaderSize + len(payload))
+	}
+}
+
+func encodeRecord(op recordOp, objectID ID, blob Blob) []byte {
+	data := make([]byte, recordHeaderSize, recordHeaderSize+len(objectID)+len(blob))
+	data[0] = byte(op)
+	binary.BigEndian.PutUint32(data[1:5], uint32(len(objectID)))
+	binary.BigEndian.PutUint32(data[5:9], uint32(len(blob)))
+	data = append(data, objectID...)
+	data = append(data, blob...)
+	crc := crc32.NewIEEE()
+	crc.Write(data[:9])
+	crc.Write(data[recordHeaderSize:])
+	binary.BigEndian.PutUint32(data[9:13], crc.Sum32())
+	return data
+}
@@ -0,0 +1,201 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/storetest"
+)
+
+func TestFileLogConformance(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.Storage {
+		st, err := store.NewFileLog(t.TempDir(), 0)
+		if err != nil {
+			t.Fatal("failed to initialize the storage", err)
+		}
+		return st
+	})
+}
+
+func TestFileLogReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := store.NewFileLog(dir, 0)
+	if err != nil {
+		t.Fatal("failed to initialize the storage", err)
+	}
+	mustDo(t, st.Create(ctx, "1", store.Blob("foo")))
+	mustDo(t, st.Create(ctx, "2", store.Blob("bar")))
+	mustDo(t, st.Create(ctx, "3", store.Blob("baz")))
+	mustDo(t, st.Save(ctx, "2", store.Blob("bar2")))
+	mustDo(t, st.Delete(ctx, "3"))
+	mustDo(t, st.Close())
+
+	st, err = store.NewFileLog(dir, 0)
+	if err != nil {
+		t.Fatal("failed to reopen the storage", err)
+	}
+	defer st.Close()
+	expectContent(t, st, map[store.ID]string{"1": "foo", "2": "bar2"})
+}
+
+func TestFileLogTornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := store.NewFileLog(dir, 0)
+	if err != nil {
+		t.Fatal("failed to initialize the storage", err)
+	}
+	mustDo(t, st.Create(ctx, "1", store.Blob("foo")))
+	mustDo(t, st.Create(ctx, "2", store.Blob("bar")))
+	mustDo(t, st.Close())
+
+	// simulate a crash in the middle of the last write
+	path := filepath.Join(dir, "todos.log")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustDo(t, os.Truncate(path, info.Size()-2))
+
+	st, err = store.NewFileLog(dir, 0)
+	if err != nil {
+		t.Fatal("failed to reopen the storage", err)
+	}
+	defer st.Close()
+	expectContent(t, st, map[store.ID]string{"1": "foo"})
+	// the log must be writable again after the recovery
+	mustDo(t, st.Create(ctx, "2", store.Blob("bar")))
+	expectContent(t, st, map[store.ID]string{"1": "foo", "2": "bar"})
+}
+
+func TestFileLogCorrupted(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := store.NewFileLog(dir, 0)
+	if err != nil {
+		t.Fatal("failed to initialize the storage", err)
+	}
+	mustDo(t, st.Create(ctx, "1", store.Blob("foo")))
+	mustDo(t, st.Create(ctx, "2", store.Blob("bar")))
+	mustDo(t, st.Close())
+
+	path := filepath.Join(dir, "todos.log")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 0xff
+	mustDo(t, os.WriteFile(path, data, 0o644))
+
+	_, err = store.NewFileLog(dir, 0)
+	if _, ok := err.(store.ErrCorruptedContent); !ok {
+		t.Fatalf("expected corrupted content error, got %v", err)
+	}
+}
+
+func TestFileLogOversizedRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := store.NewFileLog(dir, 0)
+	if err != nil {
+		t.Fatal("failed to initialize the storage", err)
+	}
+	mustDo(t, st.Create(ctx, "1", store.Blob("foo")))
+	longID := store.ID(strings.Repeat("x", 1<<16+1))
+	if err := st.Create(ctx, longID, store.Blob("bar")); err == nil {
+		t.Fatal("expected the oversized id to be refused")
+	}
+	mustDo(t, st.Close())
+
+	st, err = store.NewFileLog(dir, 0)
+	if err != nil {
+		t.Fatal("failed to reopen the storage", err)
+	}
+	defer st.Close()
+	expectContent(t, st, map[store.ID]string{"1": "foo"})
+}
+
+func TestFileLogCompaction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "todos.log")
+	st, err := store.NewFileLog(dir, 16)
+	if err != nil {
+		t.Fatal("failed to initialize the storage", err)
+	}
+	expected := map[store.ID]string{}
+	for i := 0; i < 8; i++ {
+		id := store.ID(fmt.Sprintf("%d", i))
+		expected[id] = "initial"
+		mustDo(t, st.Create(ctx, id, store.Blob(expected[id])))
+	}
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 8; i++ {
+			id := store.ID(fmt.Sprintf("%d", i))
+			expected[id] = fmt.Sprintf("round#%d", round)
+			mustDo(t, st.Save(ctx, id, store.Blob(expected[id])))
+		}
+	}
+	mustDo(t, st.Delete(ctx, "7"))
+	delete(expected, "7")
+
+	// compaction happens in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() < 1024 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("log not compacted: size=%d", info.Size())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	expectContent(t, st, expected)
+	mustDo(t, st.Close())
+
+	st, err = store.NewFileLog(dir, 16)
+	if err != nil {
+		t.Fatal("failed to reopen the storage", err)
+	}
+	defer st.Close()
+	expectContent(t, st, expected)
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectContent(t *testing.T, st store.Storage, expected map[store.ID]string) {
+	ctx := context.Background()
+	t.Helper()
+	items, err := st.LoadAll(ctx)
+	if err != nil {
+		t.Fatal("load all failed", err)
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d items, got %d", len(expected), len(items))
+	}
+	for _, item := range items {
+		if string(item.Blob) != expected[item.ID] {
+			t.Fatalf("item %v: expected %q got %q", item.ID, expected[item.ID], string(item.Blob))
+		}
+		blob, err := st.Load(ctx, item.ID)
+		if err != nil || string(blob) != expected[item.ID] {
+			t.Fatalf("load %v: expected %q got %q err=%v", item.ID, expected[item.ID], string(blob), err)
+		}
+	}
+}
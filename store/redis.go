@@ -1,25 +1,44 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/redis/go-redis/v9"
 )
 
 var _ Storage = &Redis{}
+var _ CompareAndSwapper = &Redis{}
+var _ Batcher = &Redis{}
 
+// DefaultRedisPrefix is the default prefix of the keys of the objects stored in redis: none,
+// like the keys written before the prefix was introduced, which would be ignored otherwise.
+// A prefix, like "todo:", is needed only to share the database with other applications.
+const DefaultRedisPrefix = ""
+
+// redisLoadBatch is how many keys LoadAll asks for, and then fetches, at once
+const redisLoadBatch = 500
+
+// Redis stores the objects in a redis database, each one in the key made by
+// a prefix and its ID, so the database can be shared with other applications:
+// the keys not starting with the prefix are ignored.
 type Redis struct {
-	rdb *redis.Client
+	rdb    *redis.Client
+	prefix string
 }
 
-func NewRedis(url, password string, db int) (*Redis, error) {
+// NewRedis creates a Storage on the given redis database, whose keys start with the given prefix.
+func NewRedis(url, password string, db int, prefix string) (*Redis, error) {
 	return &Redis{
 		rdb: redis.NewClient(&redis.Options{
 			Addr:     url,
 			Password: password,
 			DB:       db,
 		}),
+		prefix: prefix,
 	}, nil
 }
 
@@ -27,70 +46,228 @@ func (rd *Redis) Close() error {
 	return rd.rdb.Close()
 }
 
-func (rd *Redis) Create(objectID ID, data Blob) error {
-	_, err := rd.rdb.Get(context.Background(), string(objectID)).Result()
-	if err != nil && err != redis.Nil {
-		return err
-	}
-	if err != redis.Nil { // The item is already there
-		return fmt.Errorf("item with id %v already exists", objectID)
-	}
-
-	err = rd.rdb.Set(context.Background(), string(objectID), data, 0).Err()
+func (rd *Redis) Create(ctx context.Context, objectID ID, data Blob) error {
+	// SET NX only succeeds if the key doesn't exist yet
+	ok, err := rd.rdb.SetNX(ctx, rd.key(objectID), data, 0).Result()
 	if err != nil {
-		return err
+		return redisError(err)
+	}
+	if !ok {
+		return ErrAlreadyExists{ID: objectID}
 	}
 	return nil
 }
 
-func (rd *Redis) LoadAll() ([]Item, error) {
-	ctx := context.Background()
-	iter := rd.rdb.Scan(ctx, 0, "", 0).Iterator()
+// LoadAll scans the keys having our prefix, fetching the values of each batch of keys
+// in a single round trip. Keys deleted during the scan are skipped.
+func (rd *Redis) LoadAll(ctx context.Context) ([]Item, error) {
+	match := escapeGlob(rd.prefix) + "*"
 	res := []Item{}
-	for iter.Next(ctx) {
-		key := iter.Val()
-		val, _ := rd.rdb.Get(ctx, key).Result()
-		res = append(res, Item{ID: ID(key), Blob: Blob(val)})
+	var cursor uint64
+	for {
+		keys, next, err := rd.rdb.Scan(ctx, cursor, match, redisLoadBatch).Result()
+		if err != nil {
+			return nil, redisError(err)
+		}
+		items, err := rd.loadKeys(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, items...)
+		if next == 0 {
+			return res, nil
+		}
+		cursor = next
+	}
+}
+
+func (rd *Redis) loadKeys(ctx context.Context, keys []string) ([]Item, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	cmds := make([]*redis.StringCmd, 0, len(keys))
+	_, err := rd.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			cmds = append(cmds, pipe.Get(ctx, key))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, redisError(err)
+	}
+	items := make([]Item, 0, len(keys))
+	for idx, cmd := range cmds {
+		val, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, redisError(err)
+		}
+		items = append(items, Item{ID: ID(strings.TrimPrefix(keys[idx], rd.prefix)), Blob: Blob(val)})
 	}
-	return res, nil
+	return items, nil
 }
 
-func (rd *Redis) Load(objectID ID) (Blob, error) {
-	data, err := rd.rdb.Get(context.Background(), string(objectID)).Result()
+func (rd *Redis) Load(ctx context.Context, objectID ID) (Blob, error) {
+	data, err := rd.rdb.Get(ctx, rd.key(objectID)).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("id %s does not exist", objectID)
+		return nil, ErrNotFound{ID: objectID}
 	}
 	if err != nil {
-		return nil, err
+		return nil, redisError(err)
 	}
 	return Blob(data), nil
 }
 
-func (rd *Redis) Save(objectID ID, blob Blob) error {
-	// Non thread safe!
-	data, err := rd.rdb.Get(context.Background(), string(objectID)).Result()
-	if err == redis.Nil {
-		return fmt.Errorf("id %s does not exist", objectID)
+func (rd *Redis) Save(ctx context.Context, objectID ID, blob Blob) error {
+	// SET XX only succeeds if the key already exists
+	ok, err := rd.rdb.SetXX(ctx, rd.key(objectID), blob, 0).Result()
+	if err != nil && err != redis.Nil {
+		return redisError(err)
 	}
-
-	err = rd.rdb.Set(context.Background(), string(objectID), data, 0).Err()
-	if err != nil {
-		return err
+	if !ok {
+		return ErrNotFound{ID: objectID}
 	}
 	return nil
 }
 
-func (rd *Redis) Delete(objectID ID) error {
-	// Non thread safe!
-	data, err := rd.rdb.Del(context.Background(), string(objectID)).Result()
-	if err == redis.Nil {
-		return fmt.Errorf("id %s does not exist", objectID)
+func (rd *Redis) CompareAndSwap(ctx context.Context, objectID ID, old, blob Blob) error {
+	key := rd.key(objectID)
+	// the transaction fails if the key is changed by anyone else while we inspect it
+	err := rd.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		cur, err := tx.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return ErrNotFound{ID: objectID}
+		}
+		if err != nil {
+			return err
+		}
+		if cur != string(old) {
+			return ErrConflict{ID: objectID}
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, blob, 0)
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return ErrConflict{ID: objectID}
 	}
+	return redisError(err)
+}
 
-	err = rd.rdb.Set(context.Background(), string(objectID), data, 0).Err()
-	if err != nil {
+// Batch checks the operations against the current content of the keys, then applies them
+// in a MULTI/EXEC transaction, which fails if any key is changed meanwhile.
+func (rd *Redis) Batch(ctx context.Context, ops []Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(ops))
+	for _, op := range ops {
+		keys = append(keys, rd.key(op.ID))
+	}
+	err := rd.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		exists := make(map[ID]bool, len(ops))
+		// the blobs written by the previous operations
+		written := make(map[ID]Blob, len(ops))
+		for _, op := range ops {
+			found, ok := exists[op.ID]
+			if !ok {
+				count, err := tx.Exists(ctx, rd.key(op.ID)).Result()
+				if err != nil {
+					return err
+				}
+				found = count > 0
+			}
+			switch op.Kind {
+			case OpCreate:
+				if found {
+					return ErrAlreadyExists{ID: op.ID}
+				}
+				exists[op.ID] = true
+				written[op.ID] = op.Blob
+			case OpSave:
+				if !found {
+					return ErrNotFound{ID: op.ID}
+				}
+				if op.Old != nil {
+					cur, ok := written[op.ID]
+					if !ok {
+						var err error
+						if cur, err = tx.Get(ctx, rd.key(op.ID)).Bytes(); err != nil {
+							return err
+						}
+					}
+					if !bytes.Equal(cur, op.Old) {
+						return ErrConflict{ID: op.ID}
+					}
+				}
+				written[op.ID] = op.Blob
+			case OpDelete:
+				if !found {
+					return ErrNotFound{ID: op.ID}
+				}
+				exists[op.ID] = false
+			default:
+				return fmt.Errorf("unknown operation kind: %v", op.Kind)
+			}
+		}
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, op := range ops {
+				if op.Kind == OpDelete {
+					pipe.Del(ctx, rd.key(op.ID))
+					continue
+				}
+				pipe.Set(ctx, rd.key(op.ID), op.Blob, 0)
+			}
+			return nil
+		})
 		return err
+	}, keys...)
+	if err == redis.TxFailedErr {
+		return ErrConflict{ID: ops[0].ID}
 	}
+	return redisError(err)
+}
 
+func (rd *Redis) Delete(ctx context.Context, objectID ID) error {
+	count, err := rd.rdb.Del(ctx, rd.key(objectID)).Result()
+	if err != nil {
+		return redisError(err)
+	}
+	if count == 0 {
+		return ErrNotFound{ID: objectID}
+	}
 	return nil
 }
+
+// key returns the redis key of a object
+func (rd *Redis) key(objectID ID) string {
+	return rd.prefix + string(objectID)
+}
+
+// escapeGlob escapes the characters having a special meaning in the redis glob-style patterns
+func escapeGlob(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// redisError translates the redis client errors in the store errors, if possible
+func redisError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, redis.ErrClosed) {
+		return ErrClosed
+	}
+	return err
+}
@@ -1,42 +1,171 @@
-package store
+package store_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+	"github.com/gotestbootcamp/go-todo-app/store/storetest"
 )
 
 // exercise
 
 func TestWithRedis(t *testing.T) {
+	skipWithoutDocker(t)
 	req := testcontainers.ContainerRequest{
 		Image:        "redis:latest",
 		ExposedPorts: []string{"6379/tcp"},
 		WaitingFor:   wait.ForLog("Ready to accept connections"),
 	}
-	redisC, _ := testcontainers.GenericContainer(context.Background(),
+	redisC, err := testcontainers.GenericContainer(context.Background(),
 		testcontainers.GenericContainerRequest{
 			ContainerRequest: req,
 			Started:          true,
 		})
-
-	mapped, _ := redisC.MappedPort(context.Background(), "6379/tcp")
-
+	if err != nil {
+		t.Skipf("cannot start the redis container: %v", err)
+	}
 	t.Cleanup(func() { redisC.Terminate(context.Background()) })
 
-	storage, err := NewRedis("127.0.0.1:"+mapped.Port(), "", 0)
+	mapped, err := redisC.MappedPort(context.Background(), "6379/tcp")
 	if err != nil {
-		t.Fatal("failed to initialize the storage", err)
+		t.Fatal("failed to get the redis port", err)
 	}
+	addr := "127.0.0.1:" + mapped.Port()
 
-	t.Run("test create and get", func(t *testing.T) {
-		fmt.Println("TODO add a test where we create an item and we verify it", storage)
+	storetest.RunConformance(t, func(t *testing.T) store.Storage {
+		// each case expects a empty storage
+		rdb := redis.NewClient(&redis.Options{Addr: addr})
+		defer rdb.Close()
+		if err := rdb.FlushDB(context.Background()).Err(); err != nil {
+			t.Fatal("failed to flush the storage", err)
+		}
+
+		storage, err := store.NewRedis(addr, "", 0, store.DefaultRedisPrefix)
+		if err != nil {
+			t.Fatal("failed to initialize the storage", err)
+		}
+		return storage
 	})
+}
 
-	t.Run("test duplicateid fails", func(t *testing.T) {
-		fmt.Println("TODO add a test where we create a duplicate id fails", storage)
+// skipWithoutDocker skips the test if no container runtime is available. Looking up the
+// docker host panics rather than failing when there is none, even within testcontainers'
+// own SkipIfProviderIsNotHealthy.
+func skipWithoutDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+func TestWithMiniredis(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.Storage {
+		srv := miniredis.RunT(t)
+		storage, err := store.NewRedis(srv.Addr(), "", 0, store.DefaultRedisPrefix)
+		if err != nil {
+			t.Fatal("failed to initialize the storage", err)
+		}
+		return storage
 	})
 }
+
+func TestRedisPrefix(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	// keys of other applications, including ones matching a unescaped prefix
+	srv.Set("other", "not ours")
+	srv.Set("todo*x", "not ours")
+	srv.Set("todo?:x", "not ours")
+
+	storage, err := store.NewRedis(srv.Addr(), "", 0, "todo*:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	other, err := store.NewRedis(srv.Addr(), "", 0, "other:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	// more than a scan batch
+	var expected []string
+	for i := 0; i < 1200; i++ {
+		id := store.ID(fmt.Sprintf("%04d", i))
+		if err := storage.Create(ctx, id, store.Blob("blob "+id)); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, string(id))
+	}
+	if err := other.Create(ctx, "0000", store.Blob("other")); err != nil {
+		t.Fatalf("same ID with different prefixes must not clash: %v", err)
+	}
+	if got, err := srv.Get("todo*:0001"); err != nil || got != "blob 0001" {
+		t.Fatalf("unexpected key content %q: %v", got, err)
+	}
+
+	items, err := storage.LoadAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, item := range items {
+		if string(item.Blob) != "blob "+string(item.ID) {
+			t.Fatalf("unexpected item %v: %q", item.ID, item.Blob)
+		}
+		ids = append(ids, string(item.ID))
+	}
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("expected %d items, got %d", len(expected), len(ids))
+	}
+
+	if err := storage.Delete(ctx, "0000"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Load(ctx, "0000"); !errors.Is(err, store.ErrNotFound{ID: "0000"}) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if blob, err := other.Load(ctx, "0000"); err != nil || string(blob) != "other" {
+		t.Fatalf("object of the other prefix changed: %q %v", blob, err)
+	}
+}
+
+func TestRedisDefaultPrefixLoadsUnprefixedKeys(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	// as written before the prefix was introduced
+	srv.Set("1", "blob 1")
+	srv.Set("history/1", "blob history/1")
+
+	storage, err := store.NewRedis(srv.Addr(), "", 0, store.DefaultRedisPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	items, err := storage.LoadAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items got %v", items)
+	}
+	for _, item := range items {
+		if string(item.Blob) != "blob "+string(item.ID) {
+			t.Errorf("unexpected item %v: %q", item.ID, item.Blob)
+		}
+	}
+}
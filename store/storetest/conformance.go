@@ -0,0 +1,416 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// Factory creates a new, empty and ready to use Storage.
+// The suite takes ownership of the returned Storage and closes it once done;
+// the factory should register with `t.Cleanup` the release of any other resource
+// (e.g. temporary directories, external servers) it allocated.
+type Factory func(t *testing.T) store.Storage
+
+// RunConformance runs the full conformance suite against the Storage instances
+// created by the given factory. Each case runs as a subtest on a fresh Storage.
+func RunConformance(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	t.Helper()
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T, st store.Storage)
+	}{
+		{"load all from empty", testLoadAllEmpty},
+		{"create and load", testCreateLoad},
+		{"create duplicate fails", testCreateDuplicate},
+		{"load missing", testLoadMissing},
+		{"save missing", testSaveMissing},
+		{"create save load", testCreateSaveLoad},
+		{"create delete load", testCreateDeleteLoad},
+		{"delete missing", testDeleteMissing},
+		{"delete then create again", testDeleteCreate},
+		{"load all", testLoadAll},
+		{"load all after updates", testLoadAllAfterUpdates},
+		{"canceled context", testCanceledContext},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := factory(t)
+			defer func() {
+				if err := st.Close(); err != nil {
+					t.Errorf("close failed: %v", err)
+				}
+			}()
+			tc.run(t, st)
+		})
+	}
+
+	casCases := []struct {
+		name string
+		run  func(t *testing.T, st store.Storage, cas store.CompareAndSwapper)
+	}{
+		{"compare and swap", testCompareAndSwap},
+		{"compare and swap conflict", testCompareAndSwapConflict},
+		{"compare and swap missing", testCompareAndSwapMissing},
+	}
+
+	for _, tc := range casCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := factory(t)
+			defer func() {
+				if err := st.Close(); err != nil {
+					t.Errorf("close failed: %v", err)
+				}
+			}()
+			cas, ok := st.(store.CompareAndSwapper)
+			if !ok {
+				t.Skip("optional CompareAndSwapper interface not implemented")
+			}
+			tc.run(t, st, cas)
+		})
+	}
+
+	batchCases := []struct {
+		name string
+		run  func(t *testing.T, st store.Storage, batcher store.Batcher)
+	}{
+		{"batch", testBatch},
+		{"batch delete then create", testBatchDeleteCreate},
+		{"batch failure applies nothing", testBatchFailure},
+		{"batch compare and swap", testBatchCompareAndSwap},
+	}
+
+	for _, tc := range batchCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := factory(t)
+			defer func() {
+				if err := st.Close(); err != nil {
+					t.Errorf("close failed: %v", err)
+				}
+			}()
+			batcher, ok := st.(store.Batcher)
+			if !ok {
+				t.Skip("optional Batcher interface not implemented")
+			}
+			tc.run(t, st, batcher)
+		})
+	}
+
+	t.Run("close", func(t *testing.T) {
+		st := factory(t)
+		mustCreate(t, st, "1", "foobar")
+		if err := st.Close(); err != nil {
+			t.Fatalf("close failed: %v", err)
+		}
+		if _, err := st.Load(ctx, "1"); !errors.Is(err, store.ErrClosed) {
+			t.Errorf("load after close: expected %v, got %v", store.ErrClosed, err)
+		}
+		if err := st.Create(ctx, "2", store.Blob("fizzbuzz")); !errors.Is(err, store.ErrClosed) {
+			t.Errorf("create after close: expected %v, got %v", store.ErrClosed, err)
+		}
+		if _, err := st.LoadAll(ctx); !errors.Is(err, store.ErrClosed) {
+			t.Errorf("load all after close: expected %v, got %v", store.ErrClosed, err)
+		}
+	})
+}
+
+func testCanceledContext(t *testing.T, st store.Storage) {
+	mustCreate(t, st, "1", "foobar")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := st.Create(ctx, "2", store.Blob("fizzbuzz")); !errors.Is(err, context.Canceled) {
+		t.Errorf("create: expected %v, got %v", context.Canceled, err)
+	}
+	if _, err := st.Load(ctx, "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("load: expected %v, got %v", context.Canceled, err)
+	}
+	if err := st.Save(ctx, "1", store.Blob("fizzbuzz")); !errors.Is(err, context.Canceled) {
+		t.Errorf("save: expected %v, got %v", context.Canceled, err)
+	}
+	if err := st.Delete(ctx, "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("delete: expected %v, got %v", context.Canceled, err)
+	}
+	if _, err := st.LoadAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("load all: expected %v, got %v", context.Canceled, err)
+	}
+	// nothing changed
+	expectBlob(t, st, "1", "foobar")
+	if _, err := st.Load(context.Background(), "2"); !errors.Is(err, store.ErrNotFound{ID: "2"}) {
+		t.Errorf("create with canceled context: expected %v, got %v", store.ErrNotFound{ID: "2"}, err)
+	}
+}
+
+func testLoadAllEmpty(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	items, err := st.LoadAll(ctx)
+	if err != nil {
+		t.Fatalf("load all failed: %v", err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("expected no items, got %d", len(items))
+	}
+}
+
+func testCreateLoad(t *testing.T, st store.Storage) {
+	mustCreate(t, st, "1", "foobar")
+	expectBlob(t, st, "1", "foobar")
+}
+
+func testCreateDuplicate(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foobar")
+	err := st.Create(ctx, "1", store.Blob("fizzbuzz"))
+	if !errors.Is(err, store.ErrAlreadyExists{ID: "1"}) {
+		t.Fatalf("expected %v, got %v", store.ErrAlreadyExists{ID: "1"}, err)
+	}
+	// the original content must be untouched
+	expectBlob(t, st, "1", "foobar")
+}
+
+func testLoadMissing(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	_, err := st.Load(ctx, "999")
+	if !errors.Is(err, store.ErrNotFound{ID: "999"}) {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound{ID: "999"}, err)
+	}
+}
+
+func testSaveMissing(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	err := st.Save(ctx, "999", store.Blob("foobar"))
+	if !errors.Is(err, store.ErrNotFound{ID: "999"}) {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound{ID: "999"}, err)
+	}
+	// a failed save must not create the object
+	testLoadMissing(t, st)
+}
+
+func testCreateSaveLoad(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	mustCreate(t, st, "123", "foobar")
+	if err := st.Save(ctx, "123", store.Blob("fizzbuzz")); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	expectBlob(t, st, "123", "fizzbuzz")
+}
+
+func testCreateDeleteLoad(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	mustCreate(t, st, "543", "foobar")
+	if err := st.Delete(ctx, "543"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	_, err := st.Load(ctx, "543")
+	if !errors.Is(err, store.ErrNotFound{ID: "543"}) {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound{ID: "543"}, err)
+	}
+}
+
+func testDeleteMissing(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	err := st.Delete(ctx, "999")
+	if !errors.Is(err, store.ErrNotFound{ID: "999"}) {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound{ID: "999"}, err)
+	}
+}
+
+func testDeleteCreate(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foobar")
+	if err := st.Delete(ctx, "1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	mustCreate(t, st, "1", "fizzbuzz")
+	expectBlob(t, st, "1", "fizzbuzz")
+}
+
+func testLoadAll(t *testing.T, st store.Storage) {
+	expected := map[store.ID]string{}
+	for i := 0; i < 5; i++ {
+		id := store.ID(fmt.Sprintf("%d", i))
+		expected[id] = fmt.Sprintf("data#%d", i)
+		mustCreate(t, st, id, expected[id])
+	}
+	expectItems(t, st, expected)
+}
+
+func testLoadAllAfterUpdates(t *testing.T, st store.Storage) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foo")
+	mustCreate(t, st, "2", "bar")
+	mustCreate(t, st, "3", "baz")
+	if err := st.Save(ctx, "2", store.Blob("bar2")); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := st.Delete(ctx, "3"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	expectItems(t, st, map[store.ID]string{
+		"1": "foo",
+		"2": "bar2",
+	})
+}
+
+func testCompareAndSwap(t *testing.T, st store.Storage, cas store.CompareAndSwapper) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foobar")
+	if err := cas.CompareAndSwap(ctx, "1", store.Blob("foobar"), store.Blob("fizzbuzz")); err != nil {
+		t.Fatalf("compare and swap failed: %v", err)
+	}
+	expectBlob(t, st, "1", "fizzbuzz")
+}
+
+func testCompareAndSwapConflict(t *testing.T, st store.Storage, cas store.CompareAndSwapper) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foobar")
+	err := cas.CompareAndSwap(ctx, "1", store.Blob("stale"), store.Blob("fizzbuzz"))
+	if !errors.Is(err, store.ErrConflict{ID: "1"}) {
+		t.Fatalf("expected %v, got %v", store.ErrConflict{ID: "1"}, err)
+	}
+	expectBlob(t, st, "1", "foobar")
+}
+
+func testCompareAndSwapMissing(t *testing.T, st store.Storage, cas store.CompareAndSwapper) {
+	ctx := context.Background()
+	err := cas.CompareAndSwap(ctx, "999", store.Blob("foobar"), store.Blob("fizzbuzz"))
+	if !errors.Is(err, store.ErrNotFound{ID: "999"}) {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound{ID: "999"}, err)
+	}
+	testLoadMissing(t, st)
+}
+
+func testBatch(t *testing.T, st store.Storage, batcher store.Batcher) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foo")
+	mustCreate(t, st, "2", "bar")
+	err := batcher.Batch(ctx, []store.Op{
+		{Kind: store.OpSave, ID: "1", Blob: store.Blob("fizz")},
+		{Kind: store.OpDelete, ID: "2"},
+		{Kind: store.OpCreate, ID: "3", Blob: store.Blob("buzz")},
+	})
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	expectItems(t, st, map[store.ID]string{
+		"1": "fizz",
+		"3": "buzz",
+	})
+}
+
+func testBatchDeleteCreate(t *testing.T, st store.Storage, batcher store.Batcher) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foo")
+	err := batcher.Batch(ctx, []store.Op{
+		{Kind: store.OpDelete, ID: "1"},
+		{Kind: store.OpCreate, ID: "1", Blob: store.Blob("bar")},
+	})
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	expectBlob(t, st, "1", "bar")
+}
+
+func testBatchFailure(t *testing.T, st store.Storage, batcher store.Batcher) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foo")
+	mustCreate(t, st, "2", "bar")
+	err := batcher.Batch(ctx, []store.Op{
+		{Kind: store.OpDelete, ID: "1"},
+		{Kind: store.OpSave, ID: "2", Blob: store.Blob("fizz")},
+		{Kind: store.OpCreate, ID: "2", Blob: store.Blob("buzz")},
+	})
+	if !errors.Is(err, store.ErrAlreadyExists{ID: "2"}) {
+		t.Fatalf("expected %v, got %v", store.ErrAlreadyExists{ID: "2"}, err)
+	}
+	expectItems(t, st, map[store.ID]string{
+		"1": "foo",
+		"2": "bar",
+	})
+
+	err = batcher.Batch(ctx, []store.Op{
+		{Kind: store.OpCreate, ID: "3", Blob: store.Blob("buzz")},
+		{Kind: store.OpDelete, ID: "999"},
+	})
+	if !errors.Is(err, store.ErrNotFound{ID: "999"}) {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound{ID: "999"}, err)
+	}
+	testLoadMissing(t, st)
+	if _, err := st.Load(ctx, "3"); !errors.Is(err, store.ErrNotFound{ID: "3"}) {
+		t.Fatalf("expected %v, got %v", store.ErrNotFound{ID: "3"}, err)
+	}
+}
+
+func mustCreate(t *testing.T, st store.Storage, id store.ID, val string) {
+	ctx := context.Background()
+	t.Helper()
+	if err := st.Create(ctx, id, store.Blob(val)); err != nil {
+		t.Fatalf("create %v failed: %v", id, err)
+	}
+}
+
+func expectBlob(t *testing.T, st store.Storage, id store.ID, val string) {
+	ctx := context.Background()
+	t.Helper()
+	blob, err := st.Load(ctx, id)
+	if err != nil {
+		t.Fatalf("load %v failed: %v", id, err)
+	}
+	if string(blob) != val {
+		t.Fatalf("load %v: expected %q, got %q", id, val, string(blob))
+	}
+}
+
+func expectItems(t *testing.T, st store.Storage, expected map[store.ID]string) {
+	ctx := context.Background()
+	t.Helper()
+	items, err := st.LoadAll(ctx)
+	if err != nil {
+		t.Fatalf("load all failed: %v", err)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d items, got %d: %v", len(expected), len(items), items)
+	}
+	for _, item := range items {
+		val, ok := expected[item.ID]
+		if !ok {
+			t.Fatalf("unexpected item %v", item.ID)
+		}
+		if string(item.Blob) != val {
+			t.Fatalf("item %v: expected %q, got %q", item.ID, val, string(item.Blob))
+		}
+	}
+}
+
+func testBatchCompareAndSwap(t *testing.T, st store.Storage, batcher store.Batcher) {
+	ctx := context.Background()
+	mustCreate(t, st, "1", "foo")
+	err := batcher.Batch(ctx, []store.Op{
+		{Kind: store.OpSave, ID: "1", Blob: store.Blob("bar"), Old: store.Blob("foo")},
+		{Kind: store.OpSave, ID: "1", Blob: store.Blob("baz"), Old: store.Blob("bar")},
+		{Kind: store.OpCreate, ID: "2", Blob: store.Blob("fizz")},
+	})
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+
+	err = batcher.Batch(ctx, []store.Op{
+		{Kind: store.OpCreate, ID: "3", Blob: store.Blob("buzz")},
+		{Kind: store.OpSave, ID: "1", Blob: store.Blob("qux"), Old: store.Blob("foo")},
+	})
+	if !errors.Is(err, store.ErrConflict{ID: "1"}) {
+		t.Fatalf("expected %v, got %v", store.ErrConflict{ID: "1"}, err)
+	}
+	expectItems(t, st, map[store.ID]string{
+		"1": "baz",
+		"2": "fizz",
+	})
+}
@@ -0,0 +1,4 @@
+// Package storetest provides a reusable conformance suite for store.Storage
+// implementations. Every backend, in-tree or not, is expected to pass it:
+// the suite encodes the contract the Ledger relies on.
+package storetest
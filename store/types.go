@@ -1,6 +1,11 @@
 package store
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // ID is an opaque value which uniquely identifies a Todo. Can only be compared for equality
 // Note: this incidentally is 1:1 with API objects, but this is an implementation
@@ -15,15 +20,72 @@ func (b Blob) MarshalBinary() ([]byte, error) {
 const (
 	// NullID represents a invalid ID
 	NullID ID = ""
+	// NamespaceSeparator separates the namespace from the key in namespaced IDs
+	NamespaceSeparator = "/"
 )
 
+// Namespaced returns the ID of key within the given namespace. Namespaces let other
+// kind of records, like the todo histories, share the same Storage with the todos.
+func Namespaced(namespace, key string) ID {
+	return ID(namespace + NamespaceSeparator + key)
+}
+
+// Namespace returns the namespace of the ID, which is empty for the todos.
+func (id ID) Namespace() string {
+	ns, _, found := strings.Cut(string(id), NamespaceSeparator)
+	if !found {
+		return ""
+	}
+	return ns
+}
+
+// Storage is a durable key-value store. Every operation but Close honours the
+// cancellation and the deadline of its context, returning the context error.
 type Storage interface {
 	Close() error
-	Create(ID, Blob) error
-	LoadAll() ([]Item, error)
-	Load(ID) (Blob, error)
-	Save(ID, Blob) error
-	Delete(ID) error
+	Create(context.Context, ID, Blob) error
+	LoadAll(context.Context) ([]Item, error)
+	Load(context.Context, ID) (Blob, error)
+	Save(context.Context, ID, Blob) error
+	Delete(context.Context, ID) error
+}
+
+// CompareAndSwapper is implemented by the Storage which can atomically replace
+// a blob only if it still holds the expected content, e.g. because other processes
+// share the same backend. The Ledger uses it to enforce the optimistic concurrency control.
+type CompareAndSwapper interface {
+	// CompareAndSwap replaces the blob identified by ID with blob only if its current
+	// content is equal to old. Returns ErrConflict if the content differs,
+	// ErrNotFound if the ID is unknown.
+	CompareAndSwap(ctx context.Context, id ID, old, blob Blob) error
+}
+
+// OpKind is the kind of a write operation
+type OpKind int
+
+const (
+	OpCreate OpKind = iota + 1
+	OpSave
+	OpDelete
+)
+
+// Op is a write operation, with the same semantics of the corresponding Storage method.
+// Blob is ignored by OpDelete.
+type Op struct {
+	Kind OpKind
+	ID   ID
+	Blob Blob
+	// Old, if not nil, makes OpSave behave like CompareAndSwap: the blob is replaced only
+	// if its current content, including the changes of the previous operations, is equal to Old.
+	Old Blob
+}
+
+// Batcher is implemented by the Storage which can apply several write operations atomically.
+// The Ledger uses it to commit its transactions.
+type Batcher interface {
+	// Batch applies the given operations in order, all or nothing: if any operation fails,
+	// Batch returns its error and none of the operations is applied.
+	Batch(ctx context.Context, ops []Op) error
 }
 
 // Item binds a Todo with its ID identifier
@@ -34,6 +96,11 @@ type Item struct {
 	Blob Blob
 }
 
+var (
+	// ErrClosed is returned by any operation attempted on a closed Storage
+	ErrClosed = errors.New("storage closed")
+)
+
 type ErrNotFound struct {
 	ID ID
 }
@@ -42,6 +109,22 @@ func (e ErrNotFound) Error() string {
 	return fmt.Sprintf("unknown id: %v", e.ID)
 }
 
+type ErrAlreadyExists struct {
+	ID ID
+}
+
+func (e ErrAlreadyExists) Error() string {
+	return fmt.Sprintf("duplicate id: %v", e.ID)
+}
+
+type ErrConflict struct {
+	ID ID
+}
+
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("conflicting update: %v", e.ID)
+}
+
 type ErrCorruptedContent struct {
 	Name string
 }
@@ -0,0 +1,4 @@
+// Package uuid provides the generators of the unique identifiers assigned to new objects.
+// Local generators (random UUIDv4, time-ordered UUIDv7 and ULID) need no external dependency;
+// a deterministic sequence generator is provided to be used in testing.
+package uuid
@@ -1,22 +1,232 @@
 package uuid
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type UUIDGenerator struct {
+// UUIDGenerator creates new unique identifiers.
+// All the implementations are safe for concurrent use.
+type UUIDGenerator interface {
+	NewUUID() (string, error)
+}
+
+const (
+	// KindUUIDv4 generates random UUIDs (RFC 9562 version 4)
+	KindUUIDv4 = "uuidv4"
+	// KindUUIDv7 generates time-ordered UUIDs (RFC 9562 version 7)
+	KindUUIDv7 = "uuidv7"
+	// KindULID generates time-ordered ULIDs (https://github.com/ulid/spec)
+	KindULID = "ulid"
+	// KindRemote fetches UUIDs from a remote service
+	KindRemote = "remote"
+)
+
+// FromKind creates a generator given its kind, as in the `Kind*` constants
+func FromKind(kind string) (UUIDGenerator, error) {
+	switch kind {
+	case KindUUIDv4:
+		return NewV4(), nil
+	case KindUUIDv7:
+		return NewV7(), nil
+	case KindULID:
+		return NewULID(), nil
+	case KindRemote:
+		return NewRemote(uuidURL), nil
+	default:
+		return nil, fmt.Errorf("unknown generator kind %q", kind)
+	}
+}
+
+// Kinds returns all the supported generator kinds
+func Kinds() []string {
+	return []string{KindUUIDv4, KindUUIDv7, KindULID, KindRemote}
+}
+
+// V4 generates random UUIDs
+type V4 struct{}
+
+var _ UUIDGenerator = V4{}
+
+func NewV4() V4 {
+	return V4{}
+}
+
+func (g V4) NewUUID() (string, error) {
+	var data [16]byte
+	if _, err := rand.Read(data[:]); err != nil {
+		return "", err
+	}
+	data[6] = (data[6] & 0x0f) | 0x40 // version 4
+	data[8] = (data[8] & 0x3f) | 0x80 // variant RFC 9562
+	return format(data), nil
+}
+
+// V7 generates time-ordered UUIDs. UUIDs generated by the same V7 instance
+// are strictly increasing, even within the same millisecond.
+type V7 struct {
+	lock   sync.Mutex
+	now    func() time.Time
+	lastMs int64
+	seq    uint16
+}
+
+var _ UUIDGenerator = &V7{}
+
+func NewV7() *V7 {
+	return &V7{now: time.Now}
+}
+
+func (g *V7) NewUUID() (string, error) {
+	var data [16]byte
+	if _, err := rand.Read(data[6:]); err != nil {
+		return "", err
+	}
+
+	g.lock.Lock()
+	ms := g.now().UnixMilli()
+	if ms <= g.lastMs {
+		// same (or earlier, if the clock went back) millisecond: keep increasing
+		ms = g.lastMs
+		g.seq++
+		if g.seq > 0x0fff {
+			ms++
+			g.seq = 0
+		}
+	} else {
+		// start from a random point, leaving room to increase
+		g.seq = uint16(data[6]&0x07)<<8 | uint16(data[7])
+	}
+	g.lastMs = ms
+	seq := g.seq
+	g.lock.Unlock()
+
+	data[0] = byte(ms >> 40)
+	data[1] = byte(ms >> 32)
+	data[2] = byte(ms >> 24)
+	data[3] = byte(ms >> 16)
+	data[4] = byte(ms >> 8)
+	data[5] = byte(ms)
+	data[6] = 0x70 | byte(seq>>8) // version 7
+	data[7] = byte(seq)
+	data[8] = (data[8] & 0x3f) | 0x80 // variant RFC 9562
+	return format(data), nil
+}
+
+// ULID generates time-ordered ULIDs. ULIDs generated by the same instance
+// are strictly increasing, even within the same millisecond.
+type ULID struct {
+	lock    sync.Mutex
+	now     func() time.Time
+	lastMs  int64
+	entropy [10]byte
+}
+
+var _ UUIDGenerator = &ULID{}
+
+func NewULID() *ULID {
+	return &ULID{now: time.Now}
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (g *ULID) NewUUID() (string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	ms := g.now().UnixMilli()
+	if ms <= g.lastMs {
+		// same (or earlier, if the clock went back) millisecond: increment the entropy
+		ms = g.lastMs
+		if !increment(g.entropy[:]) {
+			return "", fmt.Errorf("ulid: entropy exhausted in millisecond %d", ms)
+		}
+	} else if _, err := rand.Read(g.entropy[:]); err != nil {
+		return "", err
+	}
+	g.lastMs = ms
+
+	var data [16]byte
+	data[0] = byte(ms >> 40)
+	data[1] = byte(ms >> 32)
+	data[2] = byte(ms >> 24)
+	data[3] = byte(ms >> 16)
+	data[4] = byte(ms >> 8)
+	data[5] = byte(ms)
+	copy(data[6:], g.entropy[:])
+
+	// 128 bits in 26 base32 characters: the first character only holds 3 bits
+	var out [26]byte
+	var acc uint64
+	var bits uint
+	pos := len(out) - 1
+	for i := len(data) - 1; i >= 0; i-- {
+		acc |= uint64(data[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[pos] = crockford[acc&0x1f]
+			pos--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	out[0] = crockford[acc&0x1f]
+	return string(out[:]), nil
+}
+
+// increment adds one to the big endian number in data. Returns false on overflow.
+func increment(data []byte) bool {
+	for i := len(data) - 1; i >= 0; i-- {
+		data[i]++
+		if data[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Sequence generates deterministic IDs which are increasing decimal numbers.
+// Meant to be used in testing.
+type Sequence struct {
+	next atomic.Uint64
+}
+
+var _ UUIDGenerator = &Sequence{}
+
+// NewSequence creates a Sequence whose first ID is the given start value
+func NewSequence(start uint64) *Sequence {
+	seq := Sequence{}
+	seq.next.Store(start)
+	return &seq
+}
+
+func (g *Sequence) NewUUID() (string, error) {
+	return strconv.FormatUint(g.next.Add(1)-1, 10), nil
+}
+
+// Remote fetches UUIDs from a remote service
+type Remote struct {
 	url string
 }
 
+var _ UUIDGenerator = Remote{}
+
 const uuidURL = "https://www.uuidtools.com/api/generate/v1/"
 
-func New() UUIDGenerator {
-	return UUIDGenerator{url: uuidURL}
+// NewRemote creates a generator which fetches UUIDs from the service at the given URL
+func NewRemote(url string) Remote {
+	return Remote{url: url}
 }
 
-func (g UUIDGenerator) NewUUID() (string, error) {
+func (g Remote) NewUUID() (string, error) {
 	resp, err := http.Get(g.url)
 	if err != nil {
 		return "", err
@@ -31,5 +241,22 @@ func (g UUIDGenerator) NewUUID() (string, error) {
 	if err := json.Unmarshal(body, &uuids); err != nil {
 		return "", err
 	}
+	if len(uuids) == 0 {
+		return "", fmt.Errorf("no uuid received from %s", g.url)
+	}
 	return uuids[0], nil
 }
+
+func format(data [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], data[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], data[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], data[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], data[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], data[10:])
+	return string(buf[:])
+}
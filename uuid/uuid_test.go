@@ -4,19 +4,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
 
 	"github.com/gotestbootcamp/go-todo-app/uuid"
 )
 
 func TestUUID(t *testing.T) {
-	// TODO: change the code so it leverages this mock
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode([]string{"myuuid"})
 	}))
 
 	t.Cleanup(svr.Close)
-	generator := uuid.New()
+	generator := uuid.NewRemote(svr.URL)
 	uuid, err := generator.NewUUID()
 	if err != nil {
 		t.Fatalf("did not expect an error, got %v", err)
@@ -25,3 +25,78 @@ func TestUUID(t *testing.T) {
 		t.Fatalf("expecting myuuid, got %s", uuid)
 	}
 }
+
+func TestLocalGenerators(t *testing.T) {
+	tests := []struct {
+		name    string
+		gen     uuid.UUIDGenerator
+		format  *regexp.Regexp
+		ordered bool
+	}{
+		{
+			name:   "uuidv4",
+			gen:    uuid.NewV4(),
+			format: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		},
+		{
+			name:    "uuidv7",
+			gen:     uuid.NewV7(),
+			format:  regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+			ordered: true,
+		},
+		{
+			name:    "ulid",
+			gen:     uuid.NewULID(),
+			format:  regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`),
+			ordered: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			prev := ""
+			for i := 0; i < 5000; i++ {
+				id, err := tc.gen.NewUUID()
+				if err != nil {
+					t.Fatalf("generation failed: %v", err)
+				}
+				if !tc.format.MatchString(id) {
+					t.Fatalf("malformed id %q", id)
+				}
+				if seen[id] {
+					t.Fatalf("duplicate id %q", id)
+				}
+				seen[id] = true
+				if tc.ordered && id <= prev {
+					t.Fatalf("id %q not greater than the previous %q", id, prev)
+				}
+				prev = id
+			}
+		})
+	}
+}
+
+func TestSequence(t *testing.T) {
+	gen := uuid.NewSequence(1234)
+	for _, expected := range []string{"1234", "1235", "1236"} {
+		id, err := gen.NewUUID()
+		if err != nil {
+			t.Fatalf("generation failed: %v", err)
+		}
+		if id != expected {
+			t.Fatalf("expecting %s, got %s", expected, id)
+		}
+	}
+}
+
+func TestFromKind(t *testing.T) {
+	for _, kind := range uuid.Kinds() {
+		if _, err := uuid.FromKind(kind); err != nil {
+			t.Errorf("kind %q: unexpected error %v", kind, err)
+		}
+	}
+	if _, err := uuid.FromKind("foobar"); err == nil {
+		t.Errorf("unknown kind accepted")
+	}
+}
@@ -0,0 +1,369 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store"
+)
+
+// namespace is the store namespace holding the subscriptions
+const namespace = "webhook"
+
+// maxRedirects is how many redirects a delivery follows, like the default http client
+const maxRedirects = 10
+
+// Options tunes the deliveries
+type Options struct {
+	// MaxAttempts is how many times a delivery is tried before giving up
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; each retry doubles it
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+	// Timeout bounds each delivery attempt
+	Timeout time.Duration
+	// MaxDeadLetters is how many abandoned deliveries are kept; the oldest are discarded first
+	MaxDeadLetters int
+	// QueueSize is how many events can wait for the delivery to a subscription;
+	// beyond it, the events are abandoned straight away
+	QueueSize int
+	// AllowedHosts, if not empty, are the only hosts the subscriptions, and their redirects, can target
+	AllowedHosts []string
+}
+
+// DefaultOptions returns the options suitable for most cases
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts:    6,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Timeout:        10 * time.Second,
+		MaxDeadLetters: 1024,
+		QueueSize:      256,
+	}
+}
+
+var (
+	ErrHostNotAllowed = errors.New("webhook host not allowed")
+	errQueueFull      = errors.New("delivery queue full")
+)
+
+// worker delivers the events to a subscription, one at a time, in order
+type worker struct {
+	queue  chan apiv1.Event
+	cancel context.CancelFunc
+}
+
+// Dispatcher manages the subscriptions and delivers the events to them.
+// Dispatcher is safe for concurrent use.
+type Dispatcher struct {
+	lock        sync.RWMutex
+	storer      store.Storage
+	opts        Options
+	client      *http.Client
+	subs        map[string]Subscription
+	workers     map[string]*worker
+	deadLetters []apiv1.DeadLetter
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// NewDispatcher creates a new Dispatcher, loading the subscriptions persisted in the given store,
+// which can be shared with other users, like the Ledger.
+// Returns error if the initialization fails; in this case, the returned dispatcher must be ignored.
+func NewDispatcher(ctx context.Context, storer store.Storage, opts Options) (*Dispatcher, error) {
+	items, err := storer.LoadAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the deliveries outlive the initialization, so they don't inherit its context
+	deliveryCtx, cancel := context.WithCancel(context.Background())
+	d := Dispatcher{
+		storer:  storer,
+		opts:    opts,
+		subs:    make(map[string]Subscription),
+		workers: make(map[string]*worker),
+		ctx:     deliveryCtx,
+		cancel:  cancel,
+	}
+	d.client = &http.Client{Timeout: opts.Timeout, CheckRedirect: d.checkRedirect}
+	prefix := string(store.Namespaced(namespace, ""))
+	for _, item := range items {
+		if item.ID.Namespace() != namespace {
+			continue
+		}
+		sub, err := DeserializeSubscription(item.Blob)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		id := string(item.ID)[len(prefix):]
+		d.subs[id] = sub
+		d.start(id, sub)
+	}
+	log.Printf("webhook: loaded %d subscriptions", len(d.subs))
+	return &d, nil
+}
+
+// Close stops the deliveries in progress, abandoning their pending retries and the queued events,
+// and waits for them.
+// The store is not closed.
+func (d *Dispatcher) Close() error {
+	// once canceled under lock, Notify can't start new deliveries
+	d.lock.Lock()
+	d.cancel()
+	d.lock.Unlock()
+	d.wg.Wait()
+	return nil
+}
+
+// Subscribe persists and activates a new subscription with the given id.
+// Returns ErrHostNotAllowed if the subscription targets a host not in Options.AllowedHosts.
+func (d *Dispatcher) Subscribe(ctx context.Context, id string, sub Subscription) error {
+	if !d.allowed(sub) {
+		return ErrHostNotAllowed
+	}
+	blob, err := sub.Serialize()
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if err := d.storer.Create(ctx, store.Namespaced(namespace, id), blob); err != nil {
+		return err
+	}
+	d.subs[id] = sub
+	d.start(id, sub)
+	return nil
+}
+
+// Unsubscribe removes a subscription, abandoning its pending deliveries.
+// Returns store.ErrNotFound if the id is unknown.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, ok := d.subs[id]; !ok {
+		return store.ErrNotFound{ID: store.ID(id)}
+	}
+	if err := d.storer.Delete(ctx, store.Namespaced(namespace, id)); err != nil {
+		return err
+	}
+	delete(d.subs, id)
+	if w, ok := d.workers[id]; ok {
+		w.cancel()
+		delete(d.workers, id)
+	}
+	return nil
+}
+
+// Get returns a subscription from its id. Returns store.ErrNotFound if the id is unknown.
+func (d *Dispatcher) Get(id string) (Subscription, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	sub, ok := d.subs[id]
+	if !ok {
+		return Subscription{}, store.ErrNotFound{ID: store.ID(id)}
+	}
+	return sub, nil
+}
+
+// List returns the ids of all the subscriptions, sorted
+func (d *Dispatcher) List() []string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	ids := make([]string, 0, len(d.subs))
+	for id := range d.subs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DeadLetters returns the abandoned deliveries, oldest first
+func (d *Dispatcher) DeadLetters() []apiv1.DeadLetter {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return append([]apiv1.DeadLetter(nil), d.deadLetters...)
+}
+
+// Notify queues a event for the delivery to all the interested subscriptions. Doesn't block:
+// if the queue of a subscription is full, its delivery is abandoned.
+func (d *Dispatcher) Notify(ev apiv1.Event) {
+	var overflows []string
+	d.lock.RLock()
+	if d.ctx.Err() != nil {
+		d.lock.RUnlock()
+		return
+	}
+	for id, sub := range d.subs {
+		w, ok := d.workers[id]
+		if !ok || !sub.Wants(ev) {
+			continue
+		}
+		select {
+		case w.queue <- ev:
+		default:
+			overflows = append(overflows, id)
+		}
+	}
+	d.lock.RUnlock()
+
+	for _, id := range overflows {
+		log.Printf("webhook: abandoned event %d to %v: %v", ev.Seq, id, errQueueFull)
+		d.deadLetter(apiv1.WebhookPayload{Webhook: apiv1.ID(id), Event: ev}, 0, errQueueFull)
+	}
+}
+
+// start runs the worker delivering the events to a subscription, unless its host is not allowed.
+// Must be called with the lock held.
+func (d *Dispatcher) start(id string, sub Subscription) {
+	if !d.allowed(sub) {
+		log.Printf("webhook: not delivering to %v: %v", id, ErrHostNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithCancel(d.ctx)
+	w := &worker{
+		queue:  make(chan apiv1.Event, d.opts.QueueSize),
+		cancel: cancel,
+	}
+	d.workers[id] = w
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-w.queue:
+				d.deliver(ctx, id, sub, ev)
+			}
+		}
+	}()
+}
+
+// allowed tells if the subscription targets one of the allowed hosts, see allowedHost
+func (d *Dispatcher) allowed(sub Subscription) bool {
+	u, err := url.Parse(sub.URL)
+	if err != nil {
+		return false
+	}
+	return d.allowedHost(u)
+}
+
+// allowedHost tells if the URL targets one of the allowed hosts; all of them are, if none is configured
+func (d *Dispatcher) allowedHost(u *url.URL) bool {
+	if len(d.opts.AllowedHosts) == 0 {
+		return true
+	}
+	for _, host := range d.opts.AllowedHosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRedirect follows the redirects only to the allowed hosts, else a receiver
+// could bounce the deliveries anywhere.
+func (d *Dispatcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if !d.allowedHost(req.URL) {
+		return fmt.Errorf("redirect to %s: %w", req.URL.Hostname(), ErrHostNotAllowed)
+	}
+	return nil
+}
+
+// deliver tries to deliver a event until it succeeds, the attempts are exhausted or the context is done
+func (d *Dispatcher) deliver(ctx context.Context, id string, sub Subscription, ev apiv1.Event) {
+	payload := apiv1.WebhookPayload{
+		Webhook: apiv1.ID(id),
+		Event:   ev,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.deadLetter(payload, 0, err)
+		return
+	}
+
+	backoff := d.opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := d.post(ctx, sub, ev, body)
+		if err == nil {
+			log.Printf("webhook: delivered event %d to %v at attempt %d", ev.Seq, id, attempt)
+			return
+		}
+		log.Printf("webhook: failed to deliver event %d to %v at attempt %d: %v", ev.Seq, id, attempt, err)
+		if attempt >= d.opts.MaxAttempts {
+			d.deadLetter(payload, attempt, err)
+			return
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("webhook: abandoned event %d to %v: unsubscribed or shutting down", ev.Seq, id)
+			return
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > d.opts.MaxBackoff {
+			backoff = d.opts.MaxBackoff
+		}
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, sub Subscription, ev apiv1.Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set(apiv1.SignatureHeader, Sign(sub.Secret, body))
+	req.Header.Set(apiv1.EventHeader, string(ev.Operation))
+	req.Header.Set(apiv1.DeliveryHeader, strconv.FormatUint(ev.Seq, 10))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// let the client reuse the connection
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1048576))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deadLetter(payload apiv1.WebhookPayload, attempts int, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(d.deadLetters) > 0 && len(d.deadLetters) >= d.opts.MaxDeadLetters {
+		d.deadLetters = d.deadLetters[1:]
+	}
+	d.deadLetters = append(d.deadLetters, apiv1.DeadLetter{
+		Payload:   payload,
+		Attempts:  attempts,
+		LastError: err.Error(),
+		Time:      time.Now(),
+	})
+}
@@ -0,0 +1,371 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+	"github.com/gotestbootcamp/go-todo-app/store/fake"
+	"github.com/gotestbootcamp/go-todo-app/webhook"
+)
+
+// receiver records the deliveries, failing the first ones as requested
+type receiver struct {
+	lock     sync.Mutex
+	failures int
+	attempts int
+	payloads []apiv1.WebhookPayload
+	err      error
+	received chan struct{}
+	secret   string
+}
+
+func newReceiver(secret string, failures int) *receiver {
+	return &receiver{
+		secret:   secret,
+		failures: failures,
+		received: make(chan struct{}, 64),
+	}
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	rc.attempts++
+	if rc.attempts <= rc.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.err = err
+		return
+	}
+	if !webhook.Verify(rc.secret, body, r.Header.Get(apiv1.SignatureHeader)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var payload apiv1.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		rc.err = err
+		return
+	}
+	if r.Header.Get(apiv1.EventHeader) != string(payload.Event.Operation) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.payloads = append(rc.payloads, payload)
+	rc.received <- struct{}{}
+}
+
+func (rc *receiver) wait(t *testing.T, count int) []apiv1.WebhookPayload {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		select {
+		case <-rc.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %d deliveries, got %d", count, i)
+		}
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if rc.err != nil {
+		t.Fatal(rc.err)
+	}
+	return rc.payloads
+}
+
+func testOptions() webhook.Options {
+	opts := webhook.DefaultOptions()
+	opts.InitialBackoff = 5 * time.Millisecond
+	opts.MaxBackoff = 20 * time.Millisecond
+	opts.MaxAttempts = 3
+	return opts
+}
+
+func event(seq uint64, op apiv1.Operation, assignee string) apiv1.Event {
+	return apiv1.Event{
+		Seq:       seq,
+		Operation: op,
+		Item: apiv1.Item{
+			ID:   "todo-1",
+			Todo: &apiv1.Todo{Title: "foo", Assignee: assignee},
+		},
+	}
+}
+
+func newDispatcher(t *testing.T) (*webhook.Dispatcher, *fake.Mem) {
+	t.Helper()
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := webhook.NewDispatcher(context.Background(), st, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d, st
+}
+
+func subscribe(t *testing.T, d *webhook.Dispatcher, id string, apiWebhook apiv1.Webhook) webhook.Subscription {
+	t.Helper()
+	sub, err := webhook.NewFromAPIv1(apiWebhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Subscribe(context.Background(), id, sub); err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestDeliveryFiltering(t *testing.T) {
+	d, _ := newDispatcher(t)
+	rc := newReceiver("s3cr3t", 0)
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	subscribe(t, d, "ci", apiv1.Webhook{
+		URL:      srv.URL,
+		Secret:   "s3cr3t",
+		Events:   []apiv1.Operation{apiv1.OpAssign, apiv1.OpComplete},
+		Assignee: "ana",
+	})
+
+	d.Notify(event(1, apiv1.OpCreate, ""))
+	d.Notify(event(2, apiv1.OpAssign, "bob"))
+	d.Notify(event(3, apiv1.OpAssign, "ana"))
+	payloads := rc.wait(t, 1)
+	d.Notify(event(4, apiv1.OpComplete, "ana"))
+	payloads = rc.wait(t, 1)
+
+	if len(payloads) != 2 || payloads[0].Event.Seq != 3 || payloads[1].Event.Seq != 4 {
+		t.Fatalf("unexpected deliveries: %+v", payloads)
+	}
+	if payloads[0].Webhook != "ci" {
+		t.Fatalf("unexpected webhook id %q", payloads[0].Webhook)
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	d, _ := newDispatcher(t)
+	rc := newReceiver("s3cr3t", 2)
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	subscribe(t, d, "bot", apiv1.Webhook{URL: srv.URL, Secret: "s3cr3t"})
+	d.Notify(event(1, apiv1.OpCreate, ""))
+	rc.wait(t, 1)
+	if len(d.DeadLetters()) != 0 {
+		t.Fatalf("unexpected dead letters: %v", d.DeadLetters())
+	}
+}
+
+func TestDeliveryDeadLetters(t *testing.T) {
+	d, _ := newDispatcher(t)
+	// the receiver can't verify the payloads signed with another secret
+	rc := newReceiver("s3cr3t", 0)
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	subscribe(t, d, "bot", apiv1.Webhook{URL: srv.URL, Secret: "wrong"})
+	d.Notify(event(1, apiv1.OpCreate, ""))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.DeadLetters()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	letters := d.DeadLetters()
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter got %v", letters)
+	}
+	if letters[0].Attempts != 3 || letters[0].Payload.Event.Seq != 1 || letters[0].Payload.Webhook != "bot" {
+		t.Fatalf("unexpected dead letter: %+v", letters[0])
+	}
+}
+
+func TestSubscriptionsPersistence(t *testing.T) {
+	ctx := context.Background()
+	d, st := newDispatcher(t)
+	subscribe(t, d, "a", apiv1.Webhook{URL: "http://localhost/a"})
+	sub := subscribe(t, d, "b", apiv1.Webhook{URL: "http://localhost/b", Events: []apiv1.Operation{apiv1.OpMerge}})
+	if err := d.Unsubscribe(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	d2, err := webhook.NewDispatcher(ctx, st, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d2.Close()
+	if ids := d2.List(); len(ids) != 1 || ids[0] != "b" {
+		t.Fatalf("unexpected subscriptions: %v", ids)
+	}
+	got, err := d2.Get("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.URL != sub.URL || got.Secret != sub.Secret || len(got.Secret) == 0 {
+		t.Fatalf("unexpected subscription: %+v", got)
+	}
+}
+
+func TestNewFromAPIv1Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiWebhook apiv1.Webhook
+		expected   error
+	}{
+		{"valid", apiv1.Webhook{URL: "https://example.com/hook"}, nil},
+		{"relative url", apiv1.Webhook{URL: "/hook"}, webhook.ErrInvalidURL},
+		{"unsupported scheme", apiv1.Webhook{URL: "ftp://example.com/hook"}, webhook.ErrInvalidURL},
+		{"unknown event", apiv1.Webhook{URL: "https://example.com/hook", Events: []apiv1.Operation{"explode"}}, webhook.ErrInvalidEvent},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := webhook.NewFromAPIv1(tc.apiWebhook); err != tc.expected {
+				t.Fatalf("expected %v got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestDeliveryQueueFull(t *testing.T) {
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions()
+	opts.QueueSize = 2
+	d, err := webhook.NewDispatcher(context.Background(), st, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// the receiver is stuck until released
+	release := make(chan struct{})
+	rc := newReceiver("s3cr3t", 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		rc.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	subscribe(t, d, "slow", apiv1.Webhook{URL: srv.URL, Secret: "s3cr3t"})
+
+	const numEvents = 10
+	for seq := uint64(1); seq <= numEvents; seq++ {
+		d.Notify(event(seq, apiv1.OpCreate, ""))
+	}
+	// at most one in flight and two queued
+	letters := d.DeadLetters()
+	if len(letters) < numEvents-3 {
+		t.Fatalf("expected at least %d dead letters got %d", numEvents-3, len(letters))
+	}
+	for _, letter := range letters {
+		if letter.Attempts != 0 || letter.LastError != "delivery queue full" {
+			t.Fatalf("unexpected dead letter: %+v", letter)
+		}
+	}
+	close(release)
+
+	// the events not abandoned are delivered in order
+	payloads := rc.wait(t, numEvents-len(letters))
+	for idx := 1; idx < len(payloads); idx++ {
+		if payloads[idx].Event.Seq <= payloads[idx-1].Event.Seq {
+			t.Fatalf("deliveries out of order: %+v", payloads)
+		}
+	}
+}
+
+func TestAllowedHosts(t *testing.T) {
+	ctx := context.Background()
+	rc := newReceiver("s3cr3t", 0)
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	// subscribed before the hosts were restricted
+	d, st := newDispatcher(t)
+	subscribe(t, d, "local", apiv1.Webhook{URL: srv.URL, Secret: "s3cr3t"})
+	d.Close()
+
+	opts := testOptions()
+	opts.AllowedHosts = []string{"hooks.example.com"}
+	d, err := webhook.NewDispatcher(ctx, st, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	for url, expected := range map[string]error{
+		"https://hooks.example.com/ci":      nil,
+		"https://HOOKS.example.com:8443/ci": nil,
+		"http://127.0.0.1:6379/":            webhook.ErrHostNotAllowed,
+		"http://169.254.169.254/latest":     webhook.ErrHostNotAllowed,
+	} {
+		sub, err := webhook.NewFromAPIv1(apiv1.Webhook{URL: url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Subscribe(ctx, url, sub); err != expected {
+			t.Errorf("%s: expected %v got %v", url, expected, err)
+		}
+	}
+
+	d.Notify(event(1, apiv1.OpCreate, ""))
+	select {
+	case <-rc.received:
+		t.Fatal("delivered to a host not allowed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAllowedHostsRedirect(t *testing.T) {
+	rc := newReceiver("s3cr3t", 0)
+	target := httptest.NewServer(rc)
+	defer target.Close()
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	st, err := fake.NewMem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions()
+	opts.AllowedHosts = []string{"localhost"}
+	d, err := webhook.NewDispatcher(context.Background(), st, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// the redirector is allowed, the target it bounces the deliveries to is not
+	u, err := url.Parse(redirector.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribe(t, d, "bot", apiv1.Webhook{URL: "http://localhost:" + u.Port(), Secret: "s3cr3t"})
+	d.Notify(event(1, apiv1.OpCreate, ""))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.DeadLetters()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if letters := d.DeadLetters(); len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter got %v", letters)
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if rc.attempts != 0 {
+		t.Fatalf("redirected to a host not allowed %d times", rc.attempts)
+	}
+}
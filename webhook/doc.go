@@ -0,0 +1,6 @@
+// Package webhook delivers the changes of the todos to external systems, like chat bots
+// or CI systems, which subscribe to them. Subscriptions are persisted in the store.
+// Deliveries are signed JSON POSTs, sent asynchronously, in order, by a worker per subscription,
+// and retried with exponential backoff; the ones which keep failing, or which overflow the bounded
+// queue of a slow subscriber, end up in a bounded dead-letter list.
+package webhook
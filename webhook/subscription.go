@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	apiv1 "github.com/gotestbootcamp/go-todo-app/api/v1"
+)
+
+var (
+	ErrInvalidURL   = errors.New("invalid webhook url")
+	ErrInvalidEvent = errors.New("invalid webhook event")
+)
+
+// Subscription describes which events are delivered where
+type Subscription struct {
+	// URL is where the events are POSTed
+	URL string
+	// Secret is the key used to sign the payloads
+	Secret string
+	// Events restricts the deliveries to the given operations. Empty means all the operations.
+	Events []apiv1.Operation
+	// Assignee restricts the deliveries to the todos with the given assignee
+	Assignee string
+}
+
+// NewFromAPIv1 creates a new subscription from its corresponding API layer object,
+// generating a random secret if none is given. Returns error if the API object
+// holds invalid values; in this case the returned object must be ignored.
+func NewFromAPIv1(apiWebhook apiv1.Webhook) (Subscription, error) {
+	u, err := url.Parse(apiWebhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, ErrInvalidURL
+	}
+	for _, op := range apiWebhook.Events {
+		if !isValidOperation(op) {
+			return Subscription{}, ErrInvalidEvent
+		}
+	}
+	secret := apiWebhook.Secret
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return Subscription{}, err
+		}
+		secret = hex.EncodeToString(key)
+	}
+	return Subscription{
+		URL:      apiWebhook.URL,
+		Secret:   secret,
+		Events:   apiWebhook.Events,
+		Assignee: apiWebhook.Assignee,
+	}, nil
+}
+
+// ToAPIv1 converts the object into the corresponding API layer object.
+// The secret is included only if requested.
+func (sub Subscription) ToAPIv1(id string, withSecret bool) apiv1.Webhook {
+	apiWebhook := apiv1.Webhook{
+		ID:       apiv1.ID(id),
+		URL:      sub.URL,
+		Events:   sub.Events,
+		Assignee: sub.Assignee,
+	}
+	if withSecret {
+		apiWebhook.Secret = sub.Secret
+	}
+	return apiWebhook
+}
+
+// Wants returns true if the event should be delivered to this subscription
+func (sub Subscription) Wants(ev apiv1.Event) bool {
+	if sub.Assignee != "" && (ev.Item.Todo == nil || ev.Item.Todo.Assignee != sub.Assignee) {
+		return false
+	}
+	if len(sub.Events) == 0 {
+		return true
+	}
+	for _, op := range sub.Events {
+		if op == ev.Operation {
+			return true
+		}
+	}
+	return false
+}
+
+// Serialize encodes the object in its canonical bytestream representation.
+// If succesfull, returns the representation; otherwise the representation
+// must be ignored, and the error will describe the failure.
+func (sub Subscription) Serialize() ([]byte, error) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(sub)
+	return buf.Bytes(), err
+}
+
+// DeserializeSubscription decodes the object from its canonical bytestream representation.
+// If succesfull, returns the decode object; otherwise returns a zero valued
+// object, and the error will describe the failure.
+func DeserializeSubscription(data []byte) (Subscription, error) {
+	var sub Subscription
+	err := json.NewDecoder(bytes.NewBuffer(data)).Decode(&sub)
+	return sub, err
+}
+
+// Sign returns the signature of a payload, in the format of the apiv1.SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if the signature, in the format of the apiv1.SignatureHeader,
+// matches the payload. Meant to be used by the receivers.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func isValidOperation(op apiv1.Operation) bool {
+	switch op {
+	case apiv1.OpCreate, apiv1.OpDescribe, apiv1.OpAssign, apiv1.OpUnassign, apiv1.OpReassign,
+		apiv1.OpReopen, apiv1.OpLabel, apiv1.OpUnlabel, apiv1.OpComplete, apiv1.OpDelete, apiv1.OpMerge,
+		apiv1.OpImport:
+		return true
+	default:
+		return false
+	}
+}